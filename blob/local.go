@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type local struct {
+	directory string
+}
+
+func (s *local) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	// written to a temporary file first, so readers never see partial blobs
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err = io.Copy(file, content); err != nil {
+		file.Close()
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+
+	if err = os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("rename file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, domain.ErrBlobNotFound
+		}
+
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+
+	return file, nil
+}
+
+func (s *local) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove file: %w", err)
+	}
+
+	return nil
+}
+
+// Keys are relative paths and can't escape the directory
+func (s *local) path(key string) (string, error) {
+	if !filepath.IsLocal(key) || strings.Contains(key, `\`) {
+		return "", fmt.Errorf("invalid blob key: %s", key)
+	}
+
+	return filepath.Join(s.directory, filepath.FromSlash(key)), nil
+}
+
+func NewLocal(directory string) *local {
+	return &local{
+		directory: directory,
+	}
+}
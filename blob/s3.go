@@ -0,0 +1,96 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const noSuchKeyErrorCode = "NoSuchKey"
+
+// Works with any S3 compatible storage, like MinIO
+type s3 struct {
+	client *minio.Client
+	bucket string
+}
+
+func (s *s3) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, content, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+
+	// GetObject is lazy, the request is only sent on the first read
+	if _, err = object.Stat(); err != nil {
+		object.Close()
+
+		if minio.ToErrorResponse(err).Code == noSuchKeyErrorCode {
+			return nil, domain.ErrBlobNotFound
+		}
+
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	return object, nil
+}
+
+func (s *s3) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("remove object: %w", err)
+	}
+
+	return nil
+}
+
+// Creates the bucket if it doesn't exist
+func NewS3(
+	ctx context.Context,
+	endpoint,
+	region,
+	bucket,
+	accessKeyID,
+	secretAccessKey string,
+	useSSL bool,
+) (*s3, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket: %w", err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: region})
+		if err != nil {
+			return nil, fmt.Errorf("create bucket: %w", err)
+		}
+	}
+
+	return &s3{
+		client: client,
+		bucket: bucket,
+	}, nil
+}
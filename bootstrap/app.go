@@ -1,46 +1,95 @@
 package bootstrap
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/gocql/gocql"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/lam0glia/chat-system/domain"
 	"github.com/redis/go-redis/v9"
 	"github.com/sony/sonyflake"
 )
 
 type App struct {
-	Env                *Env
-	CassandraSession   *gocql.Session
-	RabbitMQConnection *amqp.Connection
+	Env *Env
+	// Only one of them is open, depending on CHAT_STORAGE,
+	// and none of them with IN_MEMORY
+	CassandraSession       *gocql.Session
+	SQLDatabase            *sql.DB
+	ChatRepository         domain.ChatRepository
+	BlockRepository        domain.BlockRepository
+	ConversationRepository domain.ConversationRepository
+	UserRepository         domain.UserRepository
+	// Messages flagged by the moderator
+	ReviewRepository domain.ReviewRepository
+	// Messages reported by the users
+	ReportRepository   domain.ReportRepository
 	RedisClient        *redis.Client
-	SonyFlake          *sonyflake.Sonyflake
+	PresenceRepository domain.PresenceRepository
+	// Websockets of every node
+	ConnectionCountRepository domain.ConnectionCountRepository
+	// Chosen by BROKER
+	Broker         domain.Broker
+	SonyFlake      *sonyflake.Sonyflake
+	BlobStore      domain.BlobStore
+	ThumbnailQueue domain.ThumbnailQueue
+	// Rules file and webhook, in this order
+	Moderator domain.Moderator
+	// Shared by the nodes through redis
+	RateLimiter     domain.RateLimiter
+	RateLimitPolicy *domain.RateLimitPolicy
+	// Messages the consumers of the broker rejected
+	DeadLetterQueue domain.DeadLetterQueue
+	// Each node keeps its own embedded index, so it is only
+	// opened by the http server
+	SearchIndex domain.SearchIndex
+	// Only opened by the http server, the other processes
+	// hold no connections to act on
+	ControlPlane domain.ControlPlane
 }
 
-func NewApp() (*App, error) {
+type appOptions struct {
+	schemaCheck bool
+}
+
+type AppOption func(*appOptions)
+
+// Refuses to start when the database schema differs
+// from the migrations embedded in the binary
+func WithSchemaCheck() AppOption {
+	return func(o *appOptions) {
+		o.schemaCheck = true
+	}
+}
+
+func NewApp(opts ...AppOption) (*App, error) {
 	var (
-		err error
-		app App
+		err     error
+		app     App
+		options appOptions
 	)
 
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	app.Env, err = newEnv()
 	if err != nil {
 		return nil, fmt.Errorf("load env: %w", err)
 	}
 
-	app.CassandraSession, err = newCassandra(app.Env.CassandraHosts...)
+	app.RateLimitPolicy, err = app.Env.RateLimitPolicy()
 	if err != nil {
-		return nil, fmt.Errorf("create cassandra session: %w", err)
+		return nil, fmt.Errorf("load env: %w", err)
 	}
 
-	app.RabbitMQConnection, err = amqp.Dial(app.Env.RabbitMQURL)
-	if err != nil {
-		return nil, fmt.Errorf("create rabbitmq connection: %w", err)
+	if app.Env.InMemory {
+		err = app.openMemoryInfrastructure()
+	} else {
+		err = app.openInfrastructure(options)
 	}
-
-	app.RedisClient, err = newRedis(app.Env.RedisURL)
 	if err != nil {
-		return nil, fmt.Errorf("create redis connection: %w", err)
+		return nil, err
 	}
 
 	app.SonyFlake, err = newUIDGenerator(
@@ -51,5 +100,47 @@ func NewApp() (*App, error) {
 		return nil, fmt.Errorf("new uid generator: %w", err)
 	}
 
+	app.BlobStore, err = newBlobStore(app.Env)
+	if err != nil {
+		return nil, fmt.Errorf("create blob store: %w", err)
+	}
+
+	app.Moderator, err = newModerator(app.Env)
+	if err != nil {
+		return nil, fmt.Errorf("create moderator: %w", err)
+	}
+
+	return &app, nil
+}
+
+// Only the database selected by CHAT_STORAGE and its repositories,
+// for the tools that must not depend on the broker, redis or
+// the blob store being up
+func OpenStorage(opts ...AppOption) (*App, error) {
+	var (
+		err     error
+		app     App
+		options appOptions
+	)
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	app.Env, err = newStorageEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load env: %w", err)
+	}
+
+	if err = app.openChatStorage(); err != nil {
+		return nil, fmt.Errorf("open chat storage: %w", err)
+	}
+
+	if options.schemaCheck {
+		if err = app.checkSchema(); err != nil {
+			return nil, fmt.Errorf("check schema: %w", err)
+		}
+	}
+
 	return &app, nil
 }
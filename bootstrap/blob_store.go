@@ -0,0 +1,24 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/lam0glia/chat-system/blob"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+func newBlobStore(env *Env) (domain.BlobStore, error) {
+	if env.BlobStore == S3BlobStoreName {
+		return blob.NewS3(
+			context.Background(),
+			env.S3Endpoint,
+			env.S3Region,
+			env.S3Bucket,
+			env.S3AccessKeyID,
+			env.S3SecretAccessKey,
+			env.S3UseSSL,
+		)
+	}
+
+	return blob.NewLocal(env.BlobStoreDirectory), nil
+}
@@ -2,9 +2,16 @@ package bootstrap
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/gocql/gocql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lam0glia/chat-system/migration"
+	"github.com/lam0glia/chat-system/repository"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -18,12 +25,121 @@ func newCassandra(hosts ...string) (*gocql.Session, error) {
 	return cluster.CreateSession()
 }
 
-func newRedis(url string) (*redis.Client, error) {
+func newPostgres(url string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return db, nil
+}
+
+func newSQLite(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	db, err := sql.Open(
+		"sqlite3",
+		fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL", path),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	// a single writer avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// Opens the database selected by CHAT_STORAGE and
+// the repositories backed by it
+func (app *App) openChatStorage() error {
+	var err error
+
+	switch app.Env.ChatStorage {
+	case PostgresStorageName:
+		app.SQLDatabase, err = newPostgres(app.Env.PostgresURL)
+		if err != nil {
+			return fmt.Errorf("create postgres connection: %w", err)
+		}
+
+		app.ChatRepository = repository.NewSQLChat(app.SQLDatabase)
+		app.BlockRepository = repository.NewSQLBlock(app.SQLDatabase)
+		app.ConversationRepository = repository.NewSQLConversation(app.SQLDatabase)
+		app.UserRepository = repository.NewSQLUser(app.SQLDatabase)
+		app.ReviewRepository = repository.NewSQLReview(app.SQLDatabase)
+		app.ReportRepository = repository.NewSQLReport(app.SQLDatabase)
+	case SQLiteStorageName:
+		app.SQLDatabase, err = newSQLite(app.Env.SQLitePath)
+		if err != nil {
+			return fmt.Errorf("open sqlite database: %w", err)
+		}
+
+		app.ChatRepository = repository.NewSQLChat(app.SQLDatabase)
+		app.BlockRepository = repository.NewSQLBlock(app.SQLDatabase)
+		app.ConversationRepository = repository.NewSQLConversation(app.SQLDatabase)
+		app.UserRepository = repository.NewSQLUser(app.SQLDatabase)
+		app.ReviewRepository = repository.NewSQLReview(app.SQLDatabase)
+		app.ReportRepository = repository.NewSQLReport(app.SQLDatabase)
+	default:
+		app.CassandraSession, err = newCassandra(app.Env.CassandraHosts...)
+		if err != nil {
+			return fmt.Errorf("create cassandra session: %w", err)
+		}
+
+		app.ChatRepository = repository.NewChat(app.CassandraSession)
+		app.BlockRepository = repository.NewBlock(app.CassandraSession)
+		app.ConversationRepository = repository.NewConversation(app.CassandraSession)
+		app.UserRepository = repository.NewUser(app.CassandraSession)
+		app.ReviewRepository = repository.NewReview(app.CassandraSession)
+		app.ReportRepository = repository.NewReport(app.CassandraSession)
+	}
+
+	return nil
+}
+
+// Migrations of the database selected by CHAT_STORAGE
+func (app *App) SchemaMigrator() (migration.Migrator, error) {
+	if app.Env.InMemory {
+		return nil, fmt.Errorf("the in-memory storage has no schema")
+	}
+
+	switch app.Env.ChatStorage {
+	case PostgresStorageName:
+		return migration.NewSQLMigrator(app.SQLDatabase, migration.PostgresDialect)
+	case SQLiteStorageName:
+		return migration.NewSQLMigrator(app.SQLDatabase, migration.SQLiteDialect)
+	default:
+		return migration.NewCassandraMigrator(app.CassandraSession)
+	}
+}
+
+func (app *App) checkSchema() error {
+	migrator, err := app.SchemaMigrator()
+	if err != nil {
+		return fmt.Errorf("new migrator: %w", err)
+	}
+
+	return migrator.Check(context.Background())
+}
+
+// A poolSize of zero keeps the default of the client
+func newRedis(url string, poolSize int) (*redis.Client, error) {
 	opts, err := redis.ParseURL(url)
 	if err != nil {
 		return nil, fmt.Errorf("parse url: %w", err)
 	}
 
+	if poolSize > 0 {
+		opts.PoolSize = poolSize
+	}
+
 	client := redis.NewClient(opts)
 
 	if err = client.Ping(context.Background()).Err(); err != nil {
@@ -0,0 +1,146 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/event"
+	"github.com/lam0glia/chat-system/internal/memqueue"
+	"github.com/lam0glia/chat-system/ratelimit"
+	"github.com/lam0glia/chat-system/repository"
+	"github.com/lam0glia/chat-system/stream"
+	"github.com/nats-io/nats.go"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func (app *App) openInfrastructure(options appOptions) error {
+	var err error
+
+	if err = app.openChatStorage(); err != nil {
+		return fmt.Errorf("open chat storage: %w", err)
+	}
+
+	if options.schemaCheck {
+		if err = app.checkSchema(); err != nil {
+			return fmt.Errorf("check schema: %w", err)
+		}
+	}
+
+	app.RedisClient, err = newRedis(app.Env.RedisURL, 0)
+	if err != nil {
+		return fmt.Errorf("create redis connection: %w", err)
+	}
+
+	app.PresenceRepository = repository.NewPresence(app.RedisClient)
+	app.ConnectionCountRepository = repository.NewConnectionCount(app.RedisClient)
+	app.RateLimiter = ratelimit.NewRedis(app.RedisClient)
+
+	app.Broker, err = app.openBroker()
+	if err != nil {
+		return fmt.Errorf("open %s broker: %w", app.Env.Broker, err)
+	}
+
+	app.ThumbnailQueue, err = app.Broker.NewThumbnailQueue()
+	if err != nil {
+		return fmt.Errorf("create thumbnail queue: %w", err)
+	}
+
+	app.DeadLetterQueue, err = app.Broker.NewDeadLetterQueue()
+	if err != nil {
+		return fmt.Errorf("create dead letter queue: %w", err)
+	}
+
+	return nil
+}
+
+func (app *App) openBroker() (domain.Broker, error) {
+	switch app.Env.Broker {
+	case NATSBrokerName:
+		conn, err := nats.Connect(app.Env.NATSURL)
+		if err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+
+		broker, err := event.NewNATS(conn, app.Env.UserQueuePolicy())
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return broker, nil
+	case RedisBrokerName:
+		// kept apart from the presence client, as blocking
+		// reads would starve its pool
+		client, err := newRedis(app.Env.RedisURL, app.Env.RedisBrokerPoolSize)
+		if err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+
+		return event.NewRedisStreams(client, app.Env.UserQueuePolicy()), nil
+	default:
+		conn, err := amqp.Dial(app.Env.RabbitMQURL)
+		if err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+
+		if err = app.applyUserQueuePolicy(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("apply user queue policy: %w", err)
+		}
+
+		return event.NewRabbitMQ(conn), nil
+	}
+}
+
+// Without the management api the policy is left to the
+// operators, e.g. in the definitions of the broker
+func (app *App) applyUserQueuePolicy() error {
+	if app.Env.RabbitMQManagementURL == "" {
+		log.Printf("User queues are limited by the %s policy of the broker", stream.UserQueuePolicyName)
+		return nil
+	}
+
+	uri, err := amqp.ParseURI(app.Env.RabbitMQURL)
+	if err != nil {
+		return fmt.Errorf("parse RABBITMQ_URL: %w", err)
+	}
+
+	return stream.ApplyUserQueuePolicy(
+		context.Background(),
+		app.Env.RabbitMQManagementURL,
+		uri.Vhost,
+		app.Env.UserQueuePolicy(),
+	)
+}
+
+// Replaces every external service with an in-process adapter,
+// state is lost on restart and nothing is shared between
+// processes, so the thumbnail worker runs inside the server
+func (app *App) openMemoryInfrastructure() error {
+	var err error
+
+	app.ChatRepository = repository.NewMemoryChat()
+	app.BlockRepository = repository.NewMemoryBlock()
+	app.ConversationRepository = repository.NewMemoryConversation()
+	app.UserRepository = repository.NewMemoryUser()
+	app.ReviewRepository = repository.NewMemoryReview()
+	app.ReportRepository = repository.NewMemoryReport()
+	app.PresenceRepository = repository.NewMemoryPresence()
+	app.ConnectionCountRepository = repository.NewMemoryConnectionCount()
+	app.RateLimiter = ratelimit.NewMemory()
+	app.Broker = event.NewMemory(memqueue.NewBroker())
+
+	app.ThumbnailQueue, err = app.Broker.NewThumbnailQueue()
+	if err != nil {
+		return fmt.Errorf("create thumbnail queue: %w", err)
+	}
+
+	app.DeadLetterQueue, err = app.Broker.NewDeadLetterQueue()
+	if err != nil {
+		return fmt.Errorf("create dead letter queue: %w", err)
+	}
+
+	return nil
+}
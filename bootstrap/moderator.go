@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/moderation"
+)
+
+// Allows everything when neither the rules nor the webhook are set
+func newModerator(env *Env) (domain.Moderator, error) {
+	var moderators []domain.Moderator
+
+	if env.ModerationRulesFile != "" {
+		rules, err := moderation.NewRulesFromFile(env.ModerationRulesFile)
+		if err != nil {
+			return nil, err
+		}
+
+		moderators = append(moderators, rules)
+	}
+
+	if env.ModerationWebhookURL != "" {
+		moderators = append(moderators, moderation.NewWebhook(
+			env.ModerationWebhookURL,
+			env.ModerationWebhookTimeout,
+		))
+	}
+
+	return moderation.NewChain(moderators...), nil
+}
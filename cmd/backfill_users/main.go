@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/repository"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	app, err := bootstrap.OpenStorage(bootstrap.WithSchemaCheck())
+	if err != nil {
+		log.Panicf("Failed to open storage: %s", err)
+	}
+
+	// the SQL migrations already register them
+	if app.Env.ChatStorage != bootstrap.CassandraStorageName {
+		log.Fatalf("err: only the %s storage needs the users backfilled", bootstrap.CassandraStorageName)
+	}
+
+	log.Println("Registering the participants of the existing messages...")
+
+	count, err := repository.NewUserBackfill(app.CassandraSession).Run(ctx)
+	if err != nil {
+		log.Fatalf("err: backfill users (%d registered): %s", count, err)
+	}
+
+	log.Printf("Registered %d users", count)
+}
@@ -13,6 +13,9 @@ import (
 
 	"github.com/lam0glia/chat-system/bootstrap"
 	"github.com/lam0glia/chat-system/http/route"
+	"github.com/lam0glia/chat-system/search"
+	"github.com/lam0glia/chat-system/service"
+	"github.com/lam0glia/chat-system/use_case"
 )
 
 var app *bootstrap.App
@@ -20,10 +23,20 @@ var app *bootstrap.App
 func init() {
 	var err error
 
-	app, err = bootstrap.NewApp()
+	app, err = bootstrap.NewApp(bootstrap.WithSchemaCheck())
 	if err != nil {
 		log.Panicf("Failed to bootstrap app: %s", err)
 	}
+
+	app.SearchIndex, err = search.NewEmbedded(app.Env.SearchIndexDirectory)
+	if err != nil {
+		log.Panicf("Failed to open search index: %s", err)
+	}
+
+	app.ControlPlane, err = app.Broker.NewControlPlane()
+	if err != nil {
+		log.Panicf("Failed to open control plane: %s", err)
+	}
 }
 
 func main() {
@@ -32,7 +45,10 @@ func main() {
 
 	defer cancel()
 
-	handler := route.Setup(app)
+	// websockets open on this node
+	connections := service.NewConnectionRegistry()
+
+	handler := route.Setup(app, connections)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", app.Env.HTTPPortNumber),
@@ -53,6 +69,115 @@ func main() {
 		}
 	}()
 
+	controlPlaneListener := service.NewControlPlaneListener(app.ControlPlane, connections)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := controlPlaneListener.Run(ctx); err != nil {
+			log.Printf("err: run control plane listener: %s", err)
+		}
+	}()
+
+	connectionReporter := service.NewConnectionReporter(
+		app.ConnectionCountRepository,
+		connections,
+		app.Env.MachineID,
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := connectionReporter.Run(ctx); err != nil {
+			log.Printf("err: run connection reporter: %s", err)
+		}
+	}()
+
+	presenceReaper := service.NewPresenceReaper(
+		app.PresenceRepository,
+		app.Broker,
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := presenceReaper.Run(ctx); err != nil {
+			log.Printf("err: run presence reaper: %s", err)
+		}
+	}()
+
+	deadLetterMonitor := service.NewDeadLetterMonitor(
+		app.DeadLetterQueue,
+		app.Env.DeadLetterCheckInterval,
+		app.Env.DeadLetterAlertThreshold,
+		app.Env.DeadLetterAlertWebhookURL,
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := deadLetterMonitor.Run(ctx); err != nil {
+			log.Printf("err: run dead letter monitor: %s", err)
+		}
+	}()
+
+	// every node consumes the whole message stream to keep its
+	// own index up to date, filled from the history when empty
+	messageEventQueue, err := app.Broker.NewMessageEventQueue(
+		fmt.Sprintf("search.index.%d", app.Env.MachineID),
+	)
+	if err != nil {
+		log.Fatalf("err: create message event queue: %s", err)
+	}
+
+	defer messageEventQueue.Close()
+
+	searchIndexer := service.NewSearchIndexer(
+		messageEventQueue,
+		app.SearchIndex,
+		app.ChatRepository,
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := searchIndexer.Run(ctx); err != nil {
+			log.Printf("err: run search indexer: %s", err)
+		}
+	}()
+
+	// there is no other process to share the queue with
+	if app.Env.InMemory {
+		chatStream, err := app.Broker.NewChatDispatcher()
+		if err != nil {
+			log.Fatalf("err: open chat stream: %s", err)
+		}
+
+		defer chatStream.Close()
+
+		generateThumbnails := use_case.NewGenerateThumbnails(
+			chatStream,
+			app.ChatRepository,
+			app.BlobStore,
+			app.Env.AttachmentMaxPixels,
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := app.ThumbnailQueue.Consume(ctx, generateThumbnails.Execute)
+			if err != nil {
+				log.Printf("err: consume thumbnail jobs: %s", err)
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -65,4 +190,10 @@ func main() {
 	}()
 
 	wg.Wait()
+
+	app.ControlPlane.Close()
+
+	if err = app.SearchIndex.Close(); err != nil {
+		log.Printf("err: close search index: %s", err)
+	}
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/repository/conformance"
+)
+
+// Checks the storage selected by CHAT_STORAGE. It writes
+// test conversations, so never point it at production
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	app, err := bootstrap.NewApp(bootstrap.WithSchemaCheck())
+	if err != nil {
+		log.Panicf("Failed to bootstrap app: %s", err)
+	}
+
+	if app.Env.EnvironmentName == bootstrap.ProductionEnvironmentName {
+		log.Fatal("err: the conformance suite can't run in production")
+	}
+
+	log.Printf("Checking the %s storage...", app.Env.ChatStorage)
+
+	if err = conformance.Run(
+		ctx,
+		app.ChatRepository,
+		app.BlockRepository,
+		app.ConversationRepository,
+		app.UserRepository,
+		app.ReviewRepository,
+		app.ReportRepository,
+		app.SonyFlake,
+	); err != nil {
+		log.Fatalf("err: conformance:\n%s", err)
+	}
+
+	log.Println("All checks passed")
+}
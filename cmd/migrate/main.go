@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lam0glia/chat-system/bootstrap"
+)
+
+const usage = "usage: migrate up|status"
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal(usage)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	app, err := bootstrap.OpenStorage()
+	if err != nil {
+		log.Panicf("Failed to open storage: %s", err)
+	}
+
+	migrator, err := app.SchemaMigrator()
+	if err != nil {
+		log.Fatalf("err: new migrator: %s", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("err: migrate up: %s", err)
+		}
+
+		log.Printf("Applied %d migrations", len(applied))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("err: migration status: %s", err)
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+
+			name := status.Name
+			if name == "" {
+				name = "(unknown)"
+			}
+
+			fmt.Printf("%04d %-40s %s\n", status.Version, name, state)
+		}
+	default:
+		log.Fatal(usage)
+	}
+}
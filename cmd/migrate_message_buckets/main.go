@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/repository"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	app, err := bootstrap.OpenStorage()
+	if err != nil {
+		log.Panicf("Failed to open storage: %s", err)
+	}
+
+	if app.Env.ChatStorage != bootstrap.CassandraStorageName {
+		log.Fatalf("err: only the %s storage has time buckets", bootstrap.CassandraStorageName)
+	}
+
+	log.Println("Moving messages to time buckets...")
+
+	count, err := repository.NewMessageBucketMigration(app.CassandraSession).Run(ctx)
+	if err != nil {
+		log.Fatalf("err: migrate messages (%d moved): %s", count, err)
+	}
+
+	log.Printf("Moved %d messages. The legacy messages table can be dropped now", count)
+}
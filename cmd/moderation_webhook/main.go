@@ -0,0 +1,53 @@
+// Stand-in of an external moderation service, answers the
+// webhook moderator with the verdicts of a rules file
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/moderation"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	rulesFile := flag.String("rules", "moderation-rules.json", "JSON array of rules")
+
+	flag.Parse()
+
+	moderator, err := moderation.NewRulesFromFile(*rulesFile)
+	if err != nil {
+		log.Fatalf("err: load rules: %s", err)
+	}
+
+	http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		var request moderation.WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		verdict, err := moderator.Moderate(r.Context(), &domain.Message{
+			ID:      request.ID,
+			FromID:  request.From,
+			ToID:    request.To,
+			Content: request.Content,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("message %d: %s %s", request.ID, verdict.Action, verdict.Reason)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(verdict)
+	})
+
+	log.Printf("Listening on %s", *addr)
+
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+var app *bootstrap.App
+
+func init() {
+	var err error
+
+	app, err = bootstrap.NewApp(bootstrap.WithSchemaCheck())
+	if err != nil {
+		log.Panicf("Failed to bootstrap app: %s", err)
+	}
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	if app.Env.InMemory {
+		log.Fatal("err: with IN_MEMORY the thumbnails are generated by the http server")
+	}
+
+	chatStream, err := app.Broker.NewChatDispatcher()
+	if err != nil {
+		log.Fatalf("err: open chat stream: %s", err)
+	}
+
+	defer chatStream.Close()
+
+	generateThumbnails := use_case.NewGenerateThumbnails(
+		chatStream,
+		app.ChatRepository,
+		app.BlobStore,
+		app.Env.AttachmentMaxPixels,
+	)
+
+	log.Println("Consuming thumbnail jobs...")
+
+	err = app.ThumbnailQueue.Consume(ctx, generateThumbnails.Execute)
+	if err != nil {
+		log.Fatalf("err: consume thumbnail jobs: %s", err)
+	}
+}
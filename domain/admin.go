@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Roles of the /admin routes, each one can also
+// do everything the ones before it can
+const (
+	// Reads users and conversations to investigate tickets
+	AdminRoleSupport = "support"
+	// Acts on users and content
+	AdminRoleModerator = "moderator"
+	// Operates the service
+	AdminRoleAdmin = "admin"
+)
+
+var adminRoleLevels = map[string]int{
+	AdminRoleSupport:   1,
+	AdminRoleModerator: 2,
+	AdminRoleAdmin:     3,
+}
+
+func IsAdminRole(role string) bool {
+	_, ok := adminRoleLevels[role]
+	return ok
+}
+
+// Whether role can do what required can
+func AdminRoleAllows(role, required string) bool {
+	return IsAdminRole(role) && adminRoleLevels[role] >= adminRoleLevels[required]
+}
+
+// What support sees of a user
+type UserStatus struct {
+	UserID uint64 `json:"userId"`
+	Exists bool   `json:"exists"`
+	Online bool   `json:"online"`
+	// Websockets of the user on every node
+	Connections int         `json:"connections"`
+	Suspension  *Suspension `json:"suspension,omitempty"`
+	// Of the rate limits
+	Tier string `json:"tier,omitempty"`
+}
+
+type SuspendUserRequest struct {
+	UserID uint64
+	Reason string `json:"reason" binding:"required"`
+	// e.g. "72h", the suspension doesn't expire when empty
+	Duration string `json:"duration"`
+}
+
+type SuspendUserUseCase interface {
+	Execute(ctx context.Context, request *SuspendUserRequest) (*Suspension, error)
+}
+
+type CheckSuspensionUseCase interface {
+	// ErrUserSuspended while the suspension of the user is active
+	Execute(ctx context.Context, userID uint64) error
+}
+
+// Websocket close codes of the private range, sent
+// when the server ends the connection
+const (
+	CloseCodeDisconnected = 4000
+	CloseCodeSuspended    = 4001
+)
+
+const ChannelExchangeControl = "control"
+
+const (
+	ControlCommandDisconnect = "disconnect"
+	// Writes the event to every connection of the user
+	ControlCommandEvent = "event"
+)
+
+// Broadcast to every node, for what has to reach the
+// connections of a user wherever they are open
+type ControlCommand struct {
+	Type   string `json:"type"`
+	UserID uint64 `json:"userId"`
+	// Close code and reason of ControlCommandDisconnect
+	Code   int    `json:"code,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Encoded Event of ControlCommandEvent, kept as is so
+	// the payload isn't decoded into generic values
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// Commands are not persisted, nodes that are down miss
+// them and those that start later don't receive them
+type ControlPlane interface {
+	Publish(ctx context.Context, command *ControlCommand) error
+	// Blocks handling the commands until ctx is canceled
+	Subscribe(ctx context.Context, handle func(*ControlCommand)) error
+	Close()
+}
+
+type DisconnectUserUseCase interface {
+	// Closes the connections of the user on every node
+	Execute(ctx context.Context, userID uint64, code int, reason string) error
+}
+
+// Open websocket of a user
+type Connection interface {
+	// Sends the close frame and closes the connection
+	Disconnect(code int, reason string)
+	// Writes the encoded event
+	Send(event json.RawMessage)
+}
+
+type ConnectionCount struct {
+	Connections int `json:"connections"`
+	Users       int `json:"users"`
+	// That reported their connections
+	Nodes int `json:"nodes"`
+}
+
+// Websockets open on this node
+type ConnectionRegistry interface {
+	// Returns the function that removes it
+	Add(userID uint64, conn Connection) (remove func())
+	// Returns how many connections were closed
+	Disconnect(userID uint64, code int, reason string) int
+	// Returns how many connections it was written to
+	Send(userID uint64, event json.RawMessage) int
+	// Connections by user
+	Snapshot() map[uint64]int
+}
+
+// Connections of the whole cluster, each node reports the
+// ones open on it
+type ConnectionCountRepository interface {
+	// Replaces the counts of the node, they expire if the
+	// node stops reporting
+	ReportNode(ctx context.Context, nodeID uint16, counts map[uint64]int) error
+	RemoveNode(ctx context.Context, nodeID uint16) error
+	CountUser(ctx context.Context, userID uint64) (int, error)
+	Count(ctx context.Context) (ConnectionCount, error)
+}
+
+// Of the suspensions, zero and negative durations are invalid
+func SuspensionEnd(duration string, from time.Time) (*time.Time, error) {
+	if duration == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return nil, ErrInvalidDuration
+	}
+
+	end := from.Add(d)
+
+	return &end, nil
+}
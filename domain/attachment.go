@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	AttachmentURLFormat = "/v1/chat/attachments/%d"
+	ThumbnailURLFormat  = "/v1/chat/attachments/%d/thumbnails/%d"
+)
+
+const MaxAttachmentsPerMessage = 10
+
+type Attachment struct {
+	ID         uint64 `json:"id"`
+	UploaderID uint64 `json:"uploaderId"`
+	// The other participant of the conversation it was uploaded to
+	PeerID uint64 `json:"-"`
+	// Set once it is sent, an attachment belongs to a single message
+	MessageID *uint64 `json:"-"`
+	Name      string  `json:"name"`
+	MIMEType  string  `json:"mimeType"`
+	Size      int64   `json:"size"`
+	// Hex encoded SHA-256 of the content
+	Checksum string `json:"checksum"`
+	// Only filled for images
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Authorized download path, only the participants can fetch it
+	URL       string    `json:"url"`
+	BlobKey   string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Generated asynchronously for images, empty until ready
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+	// BlurHash of the image, rendered while the thumbnail is downloaded
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
+type Thumbnail struct {
+	// Bound of the longest side
+	Size     int    `json:"size" cql:"size"`
+	Width    int    `json:"width" cql:"width"`
+	Height   int    `json:"height" cql:"height"`
+	MIMEType string `json:"mimeType" cql:"mime_type"`
+	URL      string `json:"url" cql:"-"`
+	BlobKey  string `json:"-" cql:"blob_key"`
+}
+
+// Fills the download paths, which are not stored
+func (a *Attachment) SetURLs() {
+	a.URL = fmt.Sprintf(AttachmentURLFormat, a.ID)
+
+	for i := range a.Thumbnails {
+		a.Thumbnails[i].URL = fmt.Sprintf(ThumbnailURLFormat, a.ID, a.Thumbnails[i].Size)
+	}
+}
+
+// Returns nil if there is no thumbnail with the size
+func (a *Attachment) Thumbnail(size int) *Thumbnail {
+	for i := range a.Thumbnails {
+		if a.Thumbnails[i].Size == size {
+			return &a.Thumbnails[i]
+		}
+	}
+
+	return nil
+}
+
+func (a *Attachment) IsParticipant(userID uint64) bool {
+	return a.UploaderID == userID || a.PeerID == userID
+}
+
+type UploadAttachmentRequest struct {
+	From    uint64
+	To      uint64
+	Name    string
+	Size    int64
+	Content io.Reader
+}
+
+type UploadAttachmentUseCase interface {
+	Execute(ctx context.Context, request *UploadAttachmentRequest) (*Attachment, error)
+}
+
+type ThumbnailJob struct {
+	AttachmentID uint64 `json:"attachmentId"`
+}
+
+type ThumbnailQueue interface {
+	Enqueue(job *ThumbnailJob) error
+	// Blocks handling jobs until ctx is canceled. Jobs that fail
+	// are retried once
+	Consume(ctx context.Context, handle func(context.Context, *ThumbnailJob) error) error
+	Close()
+}
+
+type GenerateThumbnailsUseCase interface {
+	Execute(ctx context.Context, job *ThumbnailJob) error
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Returns ErrBlobNotFound if there is no blob with the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
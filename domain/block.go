@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type Block struct {
+	UserID    uint64    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BlockRequest struct {
+	UserID uint64 `json:"userId" binding:"required"`
+}
+
+// Blocks are stored with the chat, so every node enforces
+// the same ones
+type BlockRepository interface {
+	// Blocking a user twice is not an error
+	Block(ctx context.Context, userID, blockedID uint64) error
+	Unblock(ctx context.Context, userID, blockedID uint64) error
+	// Users blocked by userID, most recent first
+	ListBlocked(ctx context.Context, userID uint64) ([]Block, error)
+	// Whether any of them blocked the other
+	IsBlocked(ctx context.Context, userID, peerID uint64) (bool, error)
+	// Users blocked by userID and the ones that blocked userID
+	ListBlockedPeers(ctx context.Context, userID uint64) ([]uint64, error)
+}
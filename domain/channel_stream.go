@@ -1,7 +1,26 @@
 package domain
 
+import "time"
+
 const ChannelExchangePresence = "presence"
 
+const (
+	// Discards the oldest messages of a full queue
+	UserQueueOverflowDropHead = "drop-head"
+	// Discards the messages published to a full queue
+	UserQueueOverflowRejectPublish = "reject-publish"
+)
+
+// Limits of the queue of each user, zero is unlimited. What they
+// discard is still in the history, which clients sync on reconnect
+type UserQueuePolicy struct {
+	MessageTTL time.Duration
+	MaxLength  int
+	Overflow   string
+	// Deleted after being unused for this long
+	Expiry time.Duration
+}
+
 type ChannelFactory interface {
 	NewChannel() (StreamChannel, error)
 }
@@ -11,3 +30,27 @@ type StreamChannel interface {
 	Subscribe(buff WebsocketWriteBuffer) error
 	Close()
 }
+
+// Opens the streams of the chat on the configured broker
+type ChatStreamFactory interface {
+	// Stream that consumes the queue of userID
+	NewChatStream(userID uint64) (ChatStream, error)
+	// Stream that only dispatches, for processes that
+	// don't consume the queue of any user
+	NewChatDispatcher() (ChatStream, error)
+	// Durable subscription to every published MessageEvent
+	NewMessageEventQueue(queueName string) (MessageEventQueue, error)
+}
+
+// Message broker the chat runs on: per-user delivery, presence
+// and control broadcasts and the background job queues
+type Broker interface {
+	ChannelFactory
+	ChatStreamFactory
+	NewThumbnailQueue() (ThumbnailQueue, error)
+	// Messages the consumers rejected as undecodable
+	NewDeadLetterQueue() (DeadLetterQueue, error)
+	// Subscribes this process to the commands of every node
+	NewControlPlane() (ControlPlane, error)
+	Close()
+}
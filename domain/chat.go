@@ -6,11 +6,71 @@ import (
 )
 
 type Message struct {
+	ID        uint64     `json:"id"`
+	FromID    uint64     `json:"from"`
+	ToID      uint64     `json:"to"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"createdAt"`
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	// Messages deleted for everyone are kept as tombstones
+	// without content, so synced clients also remove them
+	DeletedAt *time.Time      `json:"deletedAt,omitempty"`
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+	// Id of the thread root, replies to a reply belong
+	// to the same thread
+	ReplyToID   *uint64      `json:"replyTo,omitempty"`
+	ReplyCount  int          `json:"replyCount,omitempty"`
+	LastReplyAt *time.Time   `json:"lastReplyAt,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Filled by the repositories, clients receive Attachments
+	AttachmentIDs []uint64 `json:"-"`
+	// Deleted only for the user listing the messages, they
+	// only see it in the compliance reads
+	Hidden bool `json:"hidden,omitempty"`
+	// Set on the delivery to a recipient that muted the conversation
+	Muted bool `json:"muted,omitempty"`
+}
+
+// Removes the hidden messages keeping the order
+func VisibleMessages(messages []Message) []Message {
+	visible := messages[:0]
+
+	for _, message := range messages {
+		if !message.Hidden {
+			visible = append(visible, message)
+		}
+	}
+
+	return visible
+}
+
+// Delivered to the participants when a reply is sent
+type ThreadUpdate struct {
+	RootID      uint64    `json:"rootId"`
+	ReplyID     uint64    `json:"replyId"`
+	ReplyCount  int       `json:"replyCount"`
+	LastReplyAt time.Time `json:"lastReplyAt"`
+}
+
+const (
+	DeleteScopeMe       = "me"
+	DeleteScopeEveryone = "everyone"
+)
+
+type MessageDeletion struct {
 	ID        uint64    `json:"id"`
 	FromID    uint64    `json:"from"`
 	ToID      uint64    `json:"to"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	Scope     string    `json:"scope"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// A prior version of an edited message
+type MessageEdit struct {
+	MessageID uint64 `json:"messageId"`
+	Content   string `json:"content"`
+	// When this version was replaced
+	EditedAt time.Time `json:"editedAt"`
 }
 
 func NewMessage(id, fromID, toID uint64, content string) *Message {
@@ -25,38 +85,158 @@ func NewMessage(id, fromID, toID uint64, content string) *Message {
 
 type SendMessageRequest struct {
 	From    uint64
-	To      uint64 `json:"to"`
-	Content string `json:"content"`
+	To      uint64  `json:"to"`
+	Content string  `json:"content"`
+	ReplyTo *uint64 `json:"replyTo"`
+	// Ids returned by the upload endpoint
+	AttachmentIDs []uint64 `json:"attachmentIds"`
+	// Rate limited when set
+	Client *Client `json:"-"`
 }
 
 type MessageReceivedResponse struct {
-	ID        uint64    `json:"id"`
-	FromID    uint64    `json:"from"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID          uint64       `json:"id"`
+	FromID      uint64       `json:"from"`
+	Content     string       `json:"content"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	ReplyToID   *uint64      `json:"replyTo,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Muted       bool         `json:"muted,omitempty"`
+}
+
+type EditMessageRequest struct {
+	From    uint64
+	ID      uint64 `json:"id"`
+	To      uint64 `json:"to" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+type DeleteMessageRequest struct {
+	From  uint64
+	ID    uint64 `json:"id"`
+	To    uint64 `json:"to" form:"to" binding:"required"`
+	Scope string `json:"scope" form:"scope" binding:"required,oneof=me everyone"`
 }
 
+type ListMessageEditsRequest struct {
+	To uint64 `form:"to" binding:"required"`
+}
+
+type ListThreadRequest struct {
+	AfterID *uint64 `form:"afterId"`
+	To      uint64  `form:"to" binding:"required"`
+}
+
+const (
+	DefaultMessagesLimit = 20
+	MaxMessagesLimit     = 100
+)
+
+// At most one of the cursors can be set, without any
+// the latest messages are listed
 type ListMessageRequest struct {
 	BeforeID *uint64 `form:"beforeId"`
+	AfterID  *uint64 `form:"afterId"`
+	// Lists the messages surrounding it, including it
+	AroundID *uint64 `form:"aroundId"`
+	Limit    int     `form:"limit" binding:"min=0"`
 	To       uint64  `form:"to" binding:"required"`
+	// Keeps the messages hidden by the user, flagged
+	IncludeHidden bool `form:"-"`
+}
+
+// Messages are ordered from the oldest to the newest
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	// Send as afterId to get the newer messages, empty
+	// when there are none yet
+	NextCursor *uint64 `json:"nextCursor,omitempty"`
+	// Send as beforeId to get the older messages, empty
+	// when there are none
+	PrevCursor *uint64 `json:"prevCursor,omitempty"`
+}
+
+// Exclusive bounds of the messages listed by the repositories
+type MessageRange struct {
+	BeforeID   *uint64
+	AfterID    *uint64
+	Descending bool
+	Limit      int
 }
 
 type ChatRepository interface {
+	// Returns ErrAttachmentNotFound if one of the attachments
+	// was sent with another message meanwhile
 	InsertMessage(ctx context.Context, message *Message) error
+	// Messages are returned in the order of the range. The ones hidden
+	// by fromID are flagged instead of removed, so ranges can be paged
 	ListMessages(
 		ctx context.Context,
 		fromID,
 		toID uint64,
-		beforeID *uint64,
+		messageRange *MessageRange,
+	) ([]Message, error)
+	// Replies of the thread rooted at rootID, oldest first
+	ListThread(
+		ctx context.Context,
+		fromID,
+		toID,
+		rootID uint64,
+		afterID *uint64,
 		limit int,
 	) ([]Message, error)
+	CountReplies(ctx context.Context, fromID, toID, rootID uint64) (int, error)
+	InsertAttachment(ctx context.Context, attachment *Attachment) error
+	// Returns ErrAttachmentNotFound if there is no attachment with the id
+	GetAttachment(ctx context.Context, id uint64) (*Attachment, error)
+	// Returns ErrAttachmentNotFound if the attachment was deleted
+	UpdateAttachmentThumbnails(ctx context.Context, attachment *Attachment) error
+	// Returns ErrMessageNotFound if there is no message with
+	// the id in the conversation
+	GetMessage(ctx context.Context, fromID, toID, id uint64) (*Message, error)
+	// Calls handle with every message, in no particular order and without
+	// attachments or aggregated data, stopping at the first error
+	ScanMessages(ctx context.Context, handle func(*Message) error) error
+	// Saves the message content and keeps the previous one
+	// in its edit history
+	UpdateMessageContent(ctx context.Context, message *Message, previousContent string) error
+	ListMessageEdits(ctx context.Context, fromID, toID, messageID uint64) ([]MessageEdit, error)
+	// Replaces the message with a tombstone and erases its edit
+	// history and attachments. A reply is no longer counted in
+	// its thread
+	DeleteMessage(ctx context.Context, message *Message) error
+	// Hides the message only from the messages listed by userID
+	HideMessage(ctx context.Context, userID, peerID, messageID uint64) error
+	// Ids between firstID and lastID hidden by userID
+	ListHiddenMessageIDs(ctx context.Context, userID, peerID, firstID, lastID uint64) (map[uint64]struct{}, error)
+	AddReaction(ctx context.Context, peerID uint64, reaction *Reaction) error
+	RemoveReaction(ctx context.Context, peerID uint64, reaction *Reaction) error
+}
+
+type ListMessagesUseCase interface {
+	Execute(ctx context.Context, userID uint64, request *ListMessageRequest) (*MessagePage, error)
 }
 
 type SendMessageUseCase interface {
 	Execute(ctx context.Context, message *SendMessageRequest) error
 }
 
+type EditMessageUseCase interface {
+	Execute(ctx context.Context, request *EditMessageRequest) (*Message, error)
+}
+
+type DeleteMessageUseCase interface {
+	Execute(ctx context.Context, request *DeleteMessageRequest) error
+}
+
 type ChatStream interface {
 	DispatchMessage(*Message) error
-	ConsumeMessages(WebsocketConnection) error
+	DispatchEvent(toID uint64, event *Event) error
+	PublishMessageEvent(event *MessageEvent) error
+	// Ephemeral events are never persisted and are discarded
+	// by the broker if not consumed within ttl
+	DispatchEphemeralEvent(toID uint64, event *Event, ttl time.Duration) error
+	// Blocks writing the queued messages to the buffer until Close
+	ConsumeMessages(WebsocketWriteBuffer) error
+	Close()
 }
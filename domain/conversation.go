@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Settings of a conversation that only apply to one of
+// its participants
+type ConversationSettings struct {
+	// Messages are still delivered, flagged so
+	// clients don't notify them
+	Muted bool `json:"muted"`
+	// Empty mutes until unmuted
+	MutedUntil *time.Time `json:"mutedUntil,omitempty"`
+	// Hidden from the inbox until a new message arrives
+	Archived bool `json:"archived"`
+	// Pinned conversations are listed first
+	PinnedAt *time.Time `json:"pinnedAt,omitempty"`
+}
+
+func (s *ConversationSettings) IsMuted(now time.Time) bool {
+	return s.Muted && (s.MutedUntil == nil || now.Before(*s.MutedUntil))
+}
+
+// Conversation of a user with peerID, as listed in their inbox
+type Conversation struct {
+	PeerID        uint64     `json:"peerId"`
+	LastMessageID uint64     `json:"lastMessageId,omitempty"`
+	LastMessageAt *time.Time `json:"lastMessageAt,omitempty"`
+	ConversationSettings
+}
+
+type ListConversationsRequest struct {
+	// Lists only the archived conversations
+	Archived bool `form:"archived"`
+}
+
+// Only the fields sent are changed
+type UpdateConversationRequest struct {
+	From   uint64
+	PeerID uint64
+	Muted  *bool `json:"muted"`
+	// Only sent with muted
+	MutedUntil *time.Time `json:"mutedUntil"`
+	Archived   *bool      `json:"archived"`
+	Pinned     *bool      `json:"pinned"`
+}
+
+type ConversationRepository interface {
+	// Moves the conversation of both participants to the
+	// message, unarchiving it
+	UpdateLastMessage(ctx context.Context, message *Message) error
+	// Conversation of userID with peerID, with the default settings
+	// when they never talked nor changed them
+	GetConversation(ctx context.Context, userID, peerID uint64) (*Conversation, error)
+	UpdateSettings(ctx context.Context, userID, peerID uint64, settings *ConversationSettings) error
+	// Every conversation of userID, in no particular order
+	ListConversations(ctx context.Context, userID uint64) ([]Conversation, error)
+}
+
+type ListConversationsUseCase interface {
+	Execute(ctx context.Context, userID uint64, request *ListConversationsRequest) ([]Conversation, error)
+}
+
+type UpdateConversationUseCase interface {
+	Execute(ctx context.Context, request *UpdateConversationRequest) (*Conversation, error)
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultDeadLetterLimit = 50
+	MaxDeadLetterLimit     = 500
+)
+
+// Message a consumer couldn't handle, kept for inspection
+// instead of being thrown away
+type DeadLetter struct {
+	// Broker specific, only meaningful to the broker
+	ID string `json:"id"`
+	// Queue it was consumed from and replayed to, presence
+	// broadcasts have ChannelExchangePresence
+	Source     string     `json:"source"`
+	Reason     string     `json:"reason"`
+	Body       string     `json:"body"`
+	RejectedAt *time.Time `json:"rejectedAt,omitempty"`
+}
+
+type DeadLetterQueue interface {
+	// Oldest first, without removing them
+	List(ctx context.Context, limit int) ([]DeadLetter, error)
+	// Publishes the oldest back to their sources and
+	// removes them, returning how many were replayed
+	Replay(ctx context.Context, limit int) (int, error)
+	Count(ctx context.Context) (int, error)
+}
+
+type DeadLetterRequest struct {
+	Limit int `form:"limit" json:"limit"`
+}
+
+type DeadLetterResponse struct {
+	Count       int          `json:"count"`
+	DeadLetters []DeadLetter `json:"deadLetters"`
+}
+
+type DeadLetterReplayResponse struct {
+	Replayed int `json:"replayed"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "errors"
+
+var (
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrNotMessageSender   = errors.New("only the sender can change the message")
+	ErrEditWindowExpired  = errors.New("message can no longer be edited")
+	ErrInvalidDeleteScope = errors.New("invalid delete scope")
+	ErrInvalidReaction    = errors.New("invalid reaction")
+	ErrInvalidCursor      = errors.New("only one cursor can be set")
+	ErrInvalidMuteExpiry  = errors.New("mute expiry must be in the future and sent with muted")
+
+	ErrEmptyMessage      = errors.New("message has no content")
+	ErrMessageTooLong    = errors.New("message is too long")
+	ErrInvalidEncoding   = errors.New("message is not valid UTF-8")
+	ErrInvalidRecipient  = errors.New("invalid recipient")
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrMessageRejected   = errors.New("message violates the content policy")
+	ErrReviewNotFound    = errors.New("review not found")
+	ErrReviewDecided     = errors.New("review was already decided")
+	ErrReportNotFound    = errors.New("report not found")
+	ErrAlreadyReported   = errors.New("message was already reported")
+	ErrCannotReportOwn   = errors.New("can't report your own message")
+	ErrReportResolved    = errors.New("report was already resolved")
+	ErrInvalidDuration   = errors.New("invalid duration")
+	ErrUserSuspended     = errors.New("user is suspended")
+
+	ErrAttachmentNotFound       = errors.New("attachment not found")
+	ErrAttachmentTooLarge       = errors.New("attachment is too large")
+	ErrImageTooLarge            = errors.New("image has too many pixels")
+	ErrAttachmentTypeNotAllowed = errors.New("attachment type is not allowed")
+	ErrTooManyAttachments       = errors.New("too many attachments")
+	ErrBlobNotFound             = errors.New("blob not found")
+
+	// Has the message of any other failure, so the sender
+	// can't tell they were blocked
+	ErrBlocked         = errors.New("message could not be sent")
+	ErrCannotBlockSelf = errors.New("users can't block themselves")
+
+	ErrRateLimited = errors.New("rate limited")
+	ErrUnknownTier = errors.New("tier is not configured")
+)
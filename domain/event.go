@@ -0,0 +1,81 @@
+package domain
+
+import "context"
+
+// Client events received through the chat websocket.
+// Frames without a type are handled as EventTypeMessageSend
+const (
+	EventTypeMessageSend    = "message.send"
+	EventTypeMessageEdit    = "message.edit"
+	EventTypeMessageDelete  = "message.delete"
+	EventTypeReactionAdd    = "reaction.add"
+	EventTypeReactionRemove = "reaction.remove"
+	EventTypeTypingStart    = "typing.start"
+	EventTypeTypingStop     = "typing.stop"
+)
+
+// Events delivered to clients, the message ones are
+// also published on the message stream
+const (
+	EventTypeMessageCreated  = "message.created"
+	EventTypeMessageEdited   = "message.edited"
+	EventTypeMessageUpdated  = "message.updated"
+	EventTypeMessageDeleted  = "message.deleted"
+	EventTypeReactionAdded   = "reaction.added"
+	EventTypeReactionRemoved = "reaction.removed"
+	EventTypeThreadUpdated   = "thread.updated"
+	// Sent to the devices of the user that changed the settings
+	EventTypeConversationUpdated = "conversation.updated"
+	// Sent to the connection whose client event failed
+	EventTypeError = "error"
+)
+
+const (
+	ErrorCodeRateLimited    = "rate_limited"
+	ErrorCodeInvalidMessage = "invalid_message"
+	ErrorCodeRejected       = "message_rejected"
+	ErrorCodeSuspended      = "user_suspended"
+	// Any other failure, blocks included so they can't be told apart
+	ErrorCodeFailed = "failed"
+)
+
+// Payload of EventTypeError
+type ErrorFrame struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Type of the client event that failed
+	Event        string `json:"event"`
+	RetryAfterMs int64  `json:"retryAfterMs,omitempty"`
+}
+
+type ClientEvent struct {
+	Type string `json:"type"`
+}
+
+// Envelope of the events delivered to clients, plain messages
+// are still delivered without it
+type Event struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+}
+
+// Published on the message stream, consumed by background
+// processes like the search indexer
+type MessageEvent struct {
+	Type     string           `json:"type"`
+	Message  *Message         `json:"message,omitempty"`
+	Deletion *MessageDeletion `json:"deletion,omitempty"`
+}
+
+type MessageEventQueue interface {
+	// Blocks handling events until ctx is canceled
+	Consume(ctx context.Context, handle func(context.Context, *MessageEvent) error) error
+	Close()
+}
+
+func NewEvent(eventType string, payload any) *Event {
+	return &Event{
+		Type:    eventType,
+		Payload: payload,
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Actions of a moderation verdict, from the least to the most severe
+const (
+	ModerationActionAllow = "allow"
+	// Delivers the message with the offending parts replaced
+	ModerationActionRedact = "redact"
+	// Delivers the message and queues it for review
+	ModerationActionFlag = "flag"
+	// Doesn't send the message
+	ModerationActionReject = "reject"
+)
+
+var moderationSeverity = map[string]int{
+	ModerationActionAllow:  0,
+	ModerationActionRedact: 1,
+	ModerationActionFlag:   2,
+	ModerationActionReject: 3,
+}
+
+// Whether action is more severe than other, unknown
+// actions are the same as allow
+func ModerationActionExceeds(action, other string) bool {
+	return moderationSeverity[action] > moderationSeverity[other]
+}
+
+type ModerationVerdict struct {
+	Action string `json:"action"`
+	// Content to deliver, redacted or not
+	Content string `json:"content"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type Moderator interface {
+	// Judges the content of a message about to be sent
+	Moderate(ctx context.Context, message *Message) (*ModerationVerdict, error)
+}
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	// The message was deleted for everyone
+	ReviewStatusRemoved = "removed"
+)
+
+const (
+	DefaultReviewLimit = 50
+	MaxReviewLimit     = 500
+)
+
+// Flagged message waiting for, or after, a moderator's decision
+type Review struct {
+	MessageID uint64 `json:"messageId"`
+	FromID    uint64 `json:"from"`
+	ToID      uint64 `json:"to"`
+	// As delivered
+	Content    string     `json:"content"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	FlaggedAt  time.Time  `json:"flaggedAt"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+}
+
+type ReviewRepository interface {
+	AddReview(ctx context.Context, review *Review) error
+	// Oldest flagged first
+	ListReviews(ctx context.Context, status string, limit int) ([]Review, error)
+	// Returns ErrReviewNotFound if the message was never flagged
+	GetReview(ctx context.Context, messageID uint64) (*Review, error)
+	// Saves the status and when it was reviewed
+	UpdateReview(ctx context.Context, review *Review) error
+}
+
+type ListReviewsRequest struct {
+	// ReviewStatusPending when empty
+	Status string `form:"status"`
+	Limit  int    `form:"limit"`
+}
+
+type ReviewDecisionRequest struct {
+	MessageID uint64
+	// ReviewStatusApproved or ReviewStatusRemoved
+	Decision string `json:"decision" binding:"required,oneof=approved removed"`
+}
+
+type ReviewUseCase interface {
+	Decide(ctx context.Context, request *ReviewDecisionRequest) (*Review, error)
+}
@@ -21,7 +21,9 @@ type PresenceService interface {
 	SetChannel(channel StreamChannel)
 	SetUserOnline(ctx context.Context, userID uint64) error
 	RefreshUserPresence(ctx context.Context, userID uint64) error
-	SubscribeUserPresenceUpdate(buff WebsocketWriteBuffer) error
+	// Updates of the users blocked by or blocking userID
+	// are not written to buff
+	SubscribeUserPresenceUpdate(userID uint64, buff WebsocketWriteBuffer) error
 	SetUserOffline(ctx context.Context, userID uint64) error
 }
 
@@ -29,4 +31,17 @@ type PresenceRepository interface {
 	UpdateUserStatus(ctx context.Context, userID uint64, status string) error
 	DeleteUserStatus(ctx context.Context, userID uint64) error
 	SetKeyExpiration(ctx context.Context, userID uint64) error
+	IsOnline(ctx context.Context, userID uint64) (bool, error)
+	// Blocks sending the id of every user whose presence expired
+	// until ctx is canceled
+	WatchExpiredUsers(ctx context.Context, expired chan<- uint64) error
+	// Returns true only for the first caller, so a single node
+	// publishes the offline event of an expired user
+	ClaimExpiredUser(ctx context.Context, userID uint64) (bool, error)
+}
+
+// Publishes the offline status of users whose presence expired
+// without "SetUserOffline" being called, e.g. when a node crashes
+type PresenceReaper interface {
+	Run(ctx context.Context) error
 }
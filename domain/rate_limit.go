@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Actions limited per user and per IP
+const (
+	RateLimitActionMessage = "message"
+	// First message to a user never talked to
+	RateLimitActionConversation = "conversation"
+	RateLimitActionConnect      = "connect"
+	// Typing, reactions, edits and deletions
+	RateLimitActionEvent = "event"
+	// Reports of messages to the moderators
+	RateLimitActionReport = "report"
+)
+
+// Token bucket holding Requests tokens, refilled evenly over
+// Period. The zero value doesn't limit
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Scales the requests of the limit, keeping at least one
+func (l RateLimit) Scale(factor float64) RateLimit {
+	l.Requests = max(1, int(float64(l.Requests)*factor))
+	return l
+}
+
+type RateLimitPolicy struct {
+	// By action
+	UserLimits map[string]RateLimit
+	IPLimits   map[string]RateLimit
+	// Factor of the user limits by tier, users without
+	// a known tier have the limits as they are
+	Tiers map[string]float64
+}
+
+// Who is making the requests, the limits of both
+// the user and the IP apply
+type Client struct {
+	UserID uint64
+	IP     string
+}
+
+// Empty Tier removes the one of the user
+type SetUserTierRequest struct {
+	UserID uint64
+	Tier   string `json:"tier"`
+}
+
+type SetUserTierUseCase interface {
+	Execute(ctx context.Context, request *SetUserTierRequest) error
+}
+
+// Wraps ErrRateLimited with when the request can be retried
+type RateLimitError struct {
+	Action     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s, retry after %s", ErrRateLimited, e.Action, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+type RateLimiter interface {
+	// Takes a token of the bucket of key, returning how long until
+	// one is available instead when it's empty
+	Take(ctx context.Context, key string, limit RateLimit) (retryAfter time.Duration, err error)
+}
+
+type RateLimitService interface {
+	// Returns a *RateLimitError when the client exceeded the limits
+	Allow(ctx context.Context, client *Client, action string) error
+}
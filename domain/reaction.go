@@ -0,0 +1,32 @@
+package domain
+
+import "context"
+
+// Max length in runes, enough for emojis joined by ZWJ
+const MaxReactionLength = 16
+
+type Reaction struct {
+	MessageID uint64 `json:"messageId"`
+	UserID    uint64 `json:"userId"`
+	Emoji     string `json:"emoji"`
+}
+
+// Aggregated reactions of a message returned by ListMessages
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// Whether the user listing the messages reacted with the emoji
+	Reacted bool `json:"reacted"`
+}
+
+type ReactionRequest struct {
+	From      uint64
+	MessageID uint64 `json:"messageId"`
+	To        uint64 `json:"to" form:"to" binding:"required"`
+	Emoji     string `json:"emoji" form:"emoji" binding:"required"`
+}
+
+type ReactionUseCase interface {
+	Add(ctx context.Context, request *ReactionRequest) error
+	Remove(ctx context.Context, request *ReactionRequest) error
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonScam          = "scam"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+)
+
+const (
+	ReportStatusOpen = "open"
+	// Seen by a moderator, waiting for an action
+	ReportStatusTriaged   = "triaged"
+	ReportStatusDismissed = "dismissed"
+	// An action was taken
+	ReportStatusResolved = "resolved"
+)
+
+const (
+	// Deletes the message for everyone
+	ReportActionDeleteMessage = "delete_message"
+	// Suspends the sender of the message
+	ReportActionSuspendUser = "suspend_user"
+)
+
+const (
+	// Messages around the reported one kept with the report
+	ReportContextSize = 20
+	// In runes
+	ReportDetailsMaxLength = 1000
+	DefaultReportLimit     = 50
+	MaxReportLimit         = 500
+)
+
+type Report struct {
+	ID         uint64 `json:"id"`
+	MessageID  uint64 `json:"messageId"`
+	ReporterID uint64 `json:"reporterId"`
+	// Sender of the message
+	ReportedID uint64 `json:"reportedId"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details,omitempty"`
+	// The conversation around the message as the reporter saw it,
+	// it is kept even if the messages are edited or deleted later
+	Context    []Message  `json:"context"`
+	Status     string     `json:"status"`
+	Actions    []string   `json:"actions,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+type ReportRepository interface {
+	// Returns ErrAlreadyReported if the reporter already
+	// reported the message
+	AddReport(ctx context.Context, report *Report) error
+	// Returns ErrReportNotFound if there is no report with the id
+	GetReport(ctx context.Context, id uint64) (*Report, error)
+	// Oldest first
+	ListReports(ctx context.Context, status string, limit int) ([]Report, error)
+	// Saves the status, actions, note and when it was resolved
+	UpdateReport(ctx context.Context, report *Report) error
+}
+
+type ReportMessageRequest struct {
+	From      uint64
+	MessageID uint64
+	To        uint64 `json:"to" binding:"required"`
+	Reason    string `json:"reason" binding:"required,oneof=spam harassment scam inappropriate other"`
+	Details   string `json:"details"`
+}
+
+// The context is only shown to the moderators
+type ReportMessageResponse struct {
+	ID     uint64 `json:"id"`
+	Status string `json:"status"`
+}
+
+type ListReportsRequest struct {
+	// ReportStatusOpen when empty
+	Status string `form:"status"`
+	Limit  int    `form:"limit"`
+}
+
+type TriageReportRequest struct {
+	ID     uint64
+	Status string `json:"status" binding:"required,oneof=open triaged dismissed"`
+	Note   string `json:"note"`
+}
+
+type ReportActionRequest struct {
+	ID     uint64
+	Action string `json:"action" binding:"required,oneof=delete_message suspend_user"`
+	// Of the suspension, it doesn't expire when empty
+	Duration string `json:"duration"`
+	Note     string `json:"note"`
+}
+
+type ReportMessageUseCase interface {
+	Execute(ctx context.Context, request *ReportMessageRequest) (*Report, error)
+}
+
+type ModerateReportUseCase interface {
+	Triage(ctx context.Context, request *TriageReportRequest) (*Report, error)
+	Act(ctx context.Context, request *ReportActionRequest) (*Report, error)
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+)
+
+type SearchDocument struct {
+	MessageID uint64
+	FromID    uint64
+	ToID      uint64
+	Content   string
+	CreatedAt time.Time
+}
+
+type SearchHit struct {
+	MessageID uint64    `json:"id"`
+	FromID    uint64    `json:"from"`
+	ToID      uint64    `json:"to"`
+	CreatedAt time.Time `json:"createdAt"`
+	// HTML escaped content with the matched terms inside <mark> tags
+	Highlight string `json:"highlight"`
+}
+
+type SearchRequest struct {
+	Query  string `form:"q" binding:"required"`
+	Offset int    `form:"offset" binding:"min=0"`
+	Limit  int    `form:"limit" binding:"min=0"`
+}
+
+type SearchResponse struct {
+	Hits       []SearchHit `json:"hits"`
+	NextOffset *int        `json:"nextOffset,omitempty"`
+}
+
+type SearchIndex interface {
+	Index(ctx context.Context, document *SearchDocument) error
+	Remove(ctx context.Context, messageID uint64) error
+	// Matches documents containing every term of the query, only from
+	// conversations of userID, newest first
+	Search(ctx context.Context, userID uint64, query string, offset, limit int) ([]SearchHit, error)
+	// Number of indexed documents
+	Count(ctx context.Context) (int, error)
+	Close() error
+}
+
+type SearchMessagesUseCase interface {
+	Execute(ctx context.Context, userID uint64, request *SearchRequest) (*SearchResponse, error)
+}
+
+// Keeps the index up to date with the message stream
+type SearchIndexer interface {
+	Run(ctx context.Context) error
+}
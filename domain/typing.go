@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type TypingRequest struct {
+	To uint64 `json:"to"`
+}
+
+type Typing struct {
+	FromID uint64 `json:"from"`
+	// Clients should consider the indicator stopped after it
+	// even if no "typing.stop" arrives
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type TypingUseCase interface {
+	Start(ctx context.Context, request *TypingRequest) error
+	Stop(ctx context.Context, request *TypingRequest) error
+	// Stops every indicator still active, call it when the connection ends
+	Close()
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Suspended users can't send messages
+type Suspension struct {
+	UserID      uint64    `json:"userId"`
+	Reason      string    `json:"reason"`
+	SuspendedAt time.Time `json:"suspendedAt"`
+	// Doesn't expire when nil
+	Until *time.Time `json:"until,omitempty"`
+}
+
+func (s *Suspension) Active(now time.Time) bool {
+	return s.Until == nil || s.Until.After(now)
+}
+
+// Users aren't managed by the chat, they are known
+// once they connect for the first time
+type UserRepository interface {
+	// Keeps the first time the user was seen
+	Register(ctx context.Context, userID uint64) error
+	Exists(ctx context.Context, userID uint64) (bool, error)
+	// Replaces the current suspension of the user
+	Suspend(ctx context.Context, suspension *Suspension) error
+	Unsuspend(ctx context.Context, userID uint64) error
+	// Returns nil if the user was never suspended, expired
+	// suspensions are returned until they are lifted
+	GetSuspension(ctx context.Context, userID uint64) (*Suspension, error)
+	// An empty tier removes the one of the user
+	SetTier(ctx context.Context, userID uint64, tier string) error
+	// Returns an empty tier if the user has none
+	GetTier(ctx context.Context, userID uint64) (string, error)
+}
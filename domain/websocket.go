@@ -5,7 +5,6 @@ type WebsocketConnection interface {
 }
 
 type WebsocketWriteBuffer interface {
-	Setup(WebsocketConnection)
 	DeliveryToClient()
 	Write(body any)
 }
@@ -0,0 +1,135 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Each process binds its own exclusive queue to the
+// fanout exchange, deleted when the process is gone
+type controlPlane struct {
+	channel   *amqp.Channel
+	queueName string
+	mu        sync.Mutex
+}
+
+func (p *controlPlane) Publish(ctx context.Context, command *domain.ControlCommand) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("json encode command: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err = p.channel.PublishWithContext(
+		ctx,
+		domain.ChannelExchangeControl, // exchange
+		"",                            // routing key (ignored by fanout)
+		false,                         // mandatory
+		false,                         // immediate
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		})
+	if err != nil {
+		return fmt.Errorf("publish command: %w", err)
+	}
+
+	return nil
+}
+
+// Undecodable commands are dropped instead of dead lettered,
+// replaying them later would close the connections opened since
+func (p *controlPlane) Subscribe(ctx context.Context, handle func(*domain.ControlCommand)) error {
+	deliveries, err := p.channel.ConsumeWithContext(
+		ctx,
+		p.queueName, // queue
+		"",          // consumer
+		true,        // auto-ack
+		true,        // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	defer internal.LogGoroutineClosed("ControlPlane.Subscribe")
+
+	for d := range deliveries {
+		var command domain.ControlCommand
+
+		if err = json.Unmarshal(d.Body, &command); err != nil {
+			log.Printf("err: json decode: %s", err)
+			continue
+		}
+
+		handle(&command)
+	}
+
+	return nil
+}
+
+func (p *controlPlane) Close() {
+	p.channel.Close()
+}
+
+func NewControlPlane(conn *amqp.Connection) (*controlPlane, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	err = ch.ExchangeDeclare(
+		domain.ChannelExchangeControl, // name
+		"fanout",                      // type
+		true,                          // durable
+		false,                         // auto-delete
+		false,                         // internal
+		false,                         // no-wait
+		nil,                           // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup exchange: %w", err)
+	}
+
+	q, err := ch.QueueDeclare(
+		"",    // name, generated by the server
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup queue: %w", err)
+	}
+
+	err = ch.QueueBind(
+		q.Name,                        // queue
+		"",                            // routing key (ignored by fanout)
+		domain.ChannelExchangeControl, // exchange
+		false,                         // no-wait
+		nil,                           // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("bind queue: %w", err)
+	}
+
+	return &controlPlane{
+		channel:   ch,
+		queueName: q.Name,
+	}, nil
+}
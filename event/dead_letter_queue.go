@@ -0,0 +1,169 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/stream"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type deadLetterQueue struct {
+	conn *amqp.Connection
+}
+
+// Reads the messages without acknowledging them, they
+// are requeued in order when the channel is closed
+func (q *deadLetterQueue) List(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	defer ch.Close()
+
+	var deadLetters []domain.DeadLetter
+
+	for len(deadLetters) < limit {
+		d, ok, err := ch.Get(stream.DeadLetterQueue, false)
+		if err != nil {
+			return nil, fmt.Errorf("get: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		deadLetters = append(deadLetters, deadLetterFromDelivery(&d))
+	}
+
+	return deadLetters, nil
+}
+
+func (q *deadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("open channel: %w", err)
+	}
+
+	defer ch.Close()
+
+	replayed := 0
+
+	for replayed < limit {
+		d, ok, err := ch.Get(stream.DeadLetterQueue, false)
+		if err != nil {
+			return replayed, fmt.Errorf("get: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		deadLetter := deadLetterFromDelivery(&d)
+
+		// presence is broadcast, the queues are reached by name
+		exchange, key := "", deadLetter.Source
+		if deadLetter.Source == domain.ChannelExchangePresence {
+			exchange, key = domain.ChannelExchangePresence, ""
+		}
+
+		err = ch.PublishWithContext(
+			ctx,
+			exchange, // exchange
+			key,      // routing key
+			false,    // mandatory
+			false,    // immediate
+			amqp.Publishing{
+				ContentType:  d.ContentType,
+				DeliveryMode: amqp.Persistent,
+				Body:         d.Body,
+			})
+		if err != nil {
+			d.Nack(false, true)
+			return replayed, fmt.Errorf("publish: %w", err)
+		}
+
+		if err = d.Ack(false); err != nil {
+			return replayed, fmt.Errorf("ack: %w", err)
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (q *deadLetterQueue) Count(ctx context.Context) (int, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("open channel: %w", err)
+	}
+
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(
+		stream.DeadLetterQueue, // name
+		true,                   // durable
+		false,                  // delete when unused
+		false,                  // exclusive
+		false,                  // no-wait
+		nil,                    // arguments
+	)
+	if err != nil {
+		return 0, fmt.Errorf("inspect queue: %w", err)
+	}
+
+	return queue.Messages, nil
+}
+
+// Rejections moved by the consumers carry their own headers, the
+// ones dead lettered by the queues only have the x-death ones
+func deadLetterFromDelivery(d *amqp.Delivery) domain.DeadLetter {
+	deadLetter := domain.DeadLetter{
+		Body: string(d.Body),
+	}
+
+	if source, ok := d.Headers[stream.SourceHeader].(string); ok {
+		deadLetter.Source = source
+		deadLetter.Reason, _ = d.Headers[stream.RejectionReasonHeader].(string)
+
+		if rejectedAt, ok := d.Headers[stream.RejectedAtHeader].(time.Time); ok {
+			deadLetter.RejectedAt = &rejectedAt
+		}
+
+		return deadLetter
+	}
+
+	deadLetter.Source, _ = d.Headers["x-first-death-queue"].(string)
+	deadLetter.Reason, _ = d.Headers["x-first-death-reason"].(string)
+
+	if deaths, ok := d.Headers["x-death"].([]any); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			if rejectedAt, ok := death["time"].(time.Time); ok {
+				deadLetter.RejectedAt = &rejectedAt
+			}
+		}
+	}
+
+	return deadLetter
+}
+
+func NewDeadLetterQueue(conn *amqp.Connection) (*deadLetterQueue, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	defer ch.Close()
+
+	if err = stream.DeclareDeadLetters(ch); err != nil {
+		return nil, fmt.Errorf("setup dead letters: %w", err)
+	}
+
+	return &deadLetterQueue{
+		conn: conn,
+	}, nil
+}
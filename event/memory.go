@@ -0,0 +1,301 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/internal/memqueue"
+	"github.com/lam0glia/chat-system/stream"
+)
+
+// In-process replacement of RabbitMQ, only reaches
+// the connections of the same process
+type memory struct {
+	broker *memqueue.Broker
+}
+
+type memoryChannel struct {
+	broker *memqueue.Broker
+	queue  *memqueue.Queue
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (m *memory) NewChannel() (domain.StreamChannel, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	channel := &memoryChannel{
+		broker: m.broker,
+		queue:  m.broker.AnonymousQueue(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	m.broker.Bind(domain.ChannelExchangePresence, channel.queue)
+
+	return channel, nil
+}
+
+func (m *memory) NewChatStream(userID uint64) (domain.ChatStream, error) {
+	return stream.NewMemoryChat(m.broker, userID), nil
+}
+
+func (m *memory) NewChatDispatcher() (domain.ChatStream, error) {
+	return stream.NewMemoryChatDispatcher(m.broker), nil
+}
+
+func (m *memory) NewMessageEventQueue(queueName string) (domain.MessageEventQueue, error) {
+	queue := m.broker.Queue(queueName)
+
+	m.broker.Bind(stream.MessageEventsExchange, queue)
+
+	return &memoryMessageEventQueue{
+		broker: m.broker,
+		name:   queueName,
+		queue:  queue,
+	}, nil
+}
+
+func (m *memory) NewThumbnailQueue() (domain.ThumbnailQueue, error) {
+	return &memoryThumbnailQueue{
+		broker: m.broker,
+		queue:  m.broker.Queue(thumbnailQueueName),
+	}, nil
+}
+
+func (m *memory) NewDeadLetterQueue() (domain.DeadLetterQueue, error) {
+	return &memoryDeadLetterQueue{
+		broker: m.broker,
+	}, nil
+}
+
+func (m *memory) NewControlPlane() (domain.ControlPlane, error) {
+	queue := m.broker.AnonymousQueue()
+
+	m.broker.Bind(domain.ChannelExchangeControl, queue)
+
+	return &memoryControlPlane{
+		broker: m.broker,
+		queue:  queue,
+	}, nil
+}
+
+func (m *memory) Close() {}
+
+func (c *memoryChannel) Publish(exchange, key string, body any) error {
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	c.broker.Publish(exchange, key, encodedBody, 0)
+
+	return nil
+}
+
+func (c *memoryChannel) Subscribe(buff domain.WebsocketWriteBuffer) error {
+	defer internal.LogGoroutineClosed("MemoryChannel.Subscribe")
+
+	for {
+		data, ok := c.queue.Get(c.ctx)
+		if !ok {
+			return nil
+		}
+
+		var msg domain.Presence
+
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			c.broker.DeadLetters().Add(
+				domain.ChannelExchangePresence,
+				data,
+				fmt.Errorf("json decode: %w", err),
+			)
+
+			continue
+		}
+
+		buff.Write(&msg)
+	}
+}
+
+func (c *memoryChannel) Close() {
+	c.broker.Unbind(domain.ChannelExchangePresence, c.queue)
+	c.cancel()
+}
+
+type memoryMessageEventQueue struct {
+	broker *memqueue.Broker
+	name   string
+	queue  *memqueue.Queue
+}
+
+func (q *memoryMessageEventQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.MessageEvent) error,
+) error {
+	defer internal.LogGoroutineClosed("MemoryMessageEventQueue.Consume")
+
+	for {
+		data, ok := q.queue.Get(ctx)
+		if !ok {
+			return nil
+		}
+
+		var event domain.MessageEvent
+
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			q.broker.DeadLetters().Add(q.name, data, fmt.Errorf("json decode: %w", err))
+
+			continue
+		}
+
+		// not retried, there is no redelivery flag to stop a loop
+		if err := handle(ctx, &event); err != nil {
+			log.Printf("err: handle %s event: %s", event.Type, err)
+		}
+	}
+}
+
+func (q *memoryMessageEventQueue) Close() {}
+
+type memoryThumbnailQueue struct {
+	broker *memqueue.Broker
+	queue  *memqueue.Queue
+}
+
+func (q *memoryThumbnailQueue) Enqueue(job *domain.ThumbnailJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json encode job: %w", err)
+	}
+
+	q.queue.Requeue(body)
+
+	return nil
+}
+
+func (q *memoryThumbnailQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.ThumbnailJob) error,
+) error {
+	defer internal.LogGoroutineClosed("MemoryThumbnailQueue.Consume")
+
+	for {
+		data, ok := q.queue.Get(ctx)
+		if !ok {
+			return nil
+		}
+
+		var job domain.ThumbnailJob
+
+		if err := json.Unmarshal(data, &job); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			q.broker.DeadLetters().Add(thumbnailQueueName, data, fmt.Errorf("json decode: %w", err))
+
+			continue
+		}
+
+		if err := handle(ctx, &job); err != nil {
+			log.Printf("err: handle thumbnail job %d: %s", job.AttachmentID, err)
+		}
+	}
+}
+
+func (q *memoryThumbnailQueue) Close() {}
+
+type memoryControlPlane struct {
+	broker *memqueue.Broker
+	queue  *memqueue.Queue
+}
+
+func (p *memoryControlPlane) Publish(ctx context.Context, command *domain.ControlCommand) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("json encode command: %w", err)
+	}
+
+	p.broker.Publish(domain.ChannelExchangeControl, "", body, 0)
+
+	return nil
+}
+
+func (p *memoryControlPlane) Subscribe(ctx context.Context, handle func(*domain.ControlCommand)) error {
+	defer internal.LogGoroutineClosed("MemoryControlPlane.Subscribe")
+
+	for {
+		data, ok := p.queue.Get(ctx)
+		if !ok {
+			return nil
+		}
+
+		var command domain.ControlCommand
+
+		if err := json.Unmarshal(data, &command); err != nil {
+			log.Printf("err: json decode: %s", err)
+			continue
+		}
+
+		handle(&command)
+	}
+}
+
+func (p *memoryControlPlane) Close() {
+	p.broker.Unbind(domain.ChannelExchangeControl, p.queue)
+}
+
+type memoryDeadLetterQueue struct {
+	broker *memqueue.Broker
+}
+
+func (q *memoryDeadLetterQueue) List(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	var deadLetters []domain.DeadLetter
+
+	for _, letter := range q.broker.DeadLetters().List(limit) {
+		rejectedAt := letter.RejectedAt
+
+		deadLetters = append(deadLetters, domain.DeadLetter{
+			ID:         strconv.FormatUint(letter.Seq, 10),
+			Source:     letter.Source,
+			Reason:     letter.Reason,
+			Body:       string(letter.Body),
+			RejectedAt: &rejectedAt,
+		})
+	}
+
+	return deadLetters, nil
+}
+
+func (q *memoryDeadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	letters := q.broker.DeadLetters().Take(limit)
+
+	for _, letter := range letters {
+		// presence is broadcast, the queues are reached by name
+		if letter.Source == domain.ChannelExchangePresence {
+			q.broker.Publish(letter.Source, "", letter.Body, 0)
+		} else {
+			q.broker.Publish("", letter.Source, letter.Body, 0)
+		}
+	}
+
+	return len(letters), nil
+}
+
+func (q *memoryDeadLetterQueue) Count(ctx context.Context) (int, error) {
+	return q.broker.DeadLetters().Len(), nil
+}
+
+func NewMemory(broker *memqueue.Broker) *memory {
+	return &memory{
+		broker: broker,
+	}
+}
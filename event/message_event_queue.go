@@ -0,0 +1,128 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/stream"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type messageEventQueue struct {
+	channel   *amqp.Channel
+	queueName string
+}
+
+func (q *messageEventQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.MessageEvent) error,
+) error {
+	deliveries, err := q.channel.ConsumeWithContext(
+		ctx,
+		q.queueName, // queue
+		"",          // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	defer internal.LogGoroutineClosed("MessageEventQueue.Consume")
+
+	for d := range deliveries {
+		var event domain.MessageEvent
+
+		if err = json.Unmarshal(d.Body, &event); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			stream.RejectDelivery(q.channel, &d, q.queueName, fmt.Errorf("json decode: %w", err))
+
+			continue
+		}
+
+		if err = handle(ctx, &event); err != nil {
+			log.Printf("err: handle %s event: %s", event.Type, err)
+
+			// retried once
+			d.Nack(false, !d.Redelivered)
+
+			continue
+		}
+
+		if err = d.Ack(false); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (q *messageEventQueue) Close() {
+	q.channel.Close()
+}
+
+// The queue is durable, so events published while the
+// consumer is down are handled when it comes back
+func NewMessageEventQueue(conn *amqp.Connection, queueName string) (*messageEventQueue, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	err = ch.ExchangeDeclare(
+		stream.MessageEventsExchange, // name
+		"fanout",                     // type
+		true,                         // durable
+		false,                        // auto-delete
+		false,                        // internal
+		false,                        // no-wait
+		nil,                          // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup exchange: %w", err)
+	}
+
+	if err = stream.DeclareDeadLetters(ch); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup dead letters: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(
+		queueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup queue: %w", err)
+	}
+
+	err = ch.QueueBind(
+		queueName,                    // queue
+		"",                           // routing key (ignored by fanout)
+		stream.MessageEventsExchange, // exchange
+		false,                        // no-wait
+		nil,                          // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("bind queue: %w", err)
+	}
+
+	return &messageEventQueue{
+		channel:   ch,
+		queueName: queueName,
+	}, nil
+}
@@ -0,0 +1,532 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/stream"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	natsThumbnailsStream   = "THUMBNAILS"
+	natsThumbnailsConsumer = "thumbnail-workers"
+	// Time a worker has to generate the thumbnails
+	// before the job is delivered to another one
+	natsThumbnailsAckWait = 5 * time.Minute
+	// Message events are kept for the consumers that are down
+	natsMessageEventsMaxAge = 7 * 24 * time.Hour
+	natsSetupTimeout        = 10 * time.Second
+)
+
+// Presence is broadcast over core NATS, as it was never
+// persisted on RabbitMQ either, and the queues are streams
+type natsBroker struct {
+	conn            *nats.Conn
+	js              jetstream.JetStream
+	userQueuePolicy *domain.UserQueuePolicy
+}
+
+type natsChannel struct {
+	conn         *nats.Conn
+	js           jetstream.JetStream
+	subscription *nats.Subscription
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+func (n *natsBroker) NewChannel() (domain.StreamChannel, error) {
+	subscription, err := n.conn.SubscribeSync(domain.ChannelExchangePresence)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &natsChannel{
+		conn:         n.conn,
+		js:           n.js,
+		subscription: subscription,
+		ctx:          ctx,
+		cancel:       cancel,
+	}, nil
+}
+
+// The exchange is the subject, there is no routing key
+func (c *natsChannel) Publish(exchange, key string, body any) error {
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	if err = c.conn.Publish(exchange, encodedBody); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	return nil
+}
+
+func (c *natsChannel) Subscribe(buff domain.WebsocketWriteBuffer) error {
+	defer internal.LogGoroutineClosed("NATSChannel.Subscribe")
+
+	for {
+		m, err := c.subscription.NextMsgWithContext(c.ctx)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("next message: %w", err)
+		}
+
+		var msg domain.Presence
+
+		if err = json.Unmarshal(m.Data, &msg); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			err = stream.PublishNATSDeadLetter(
+				c.js,
+				domain.ChannelExchangePresence,
+				m.Data,
+				fmt.Errorf("json decode: %w", err),
+			)
+			if err != nil {
+				log.Printf("err: publish dead letter: %s", err)
+			}
+
+			continue
+		}
+
+		buff.Write(&msg)
+	}
+}
+
+func (c *natsChannel) Close() {
+	c.cancel()
+	c.subscription.Unsubscribe()
+}
+
+func (n *natsBroker) NewChatStream(userID uint64) (domain.ChatStream, error) {
+	chat, err := stream.NewNATSChat(n.js, userID, n.userQueuePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+func (n *natsBroker) NewChatDispatcher() (domain.ChatStream, error) {
+	return stream.NewNATSChatDispatcher(n.js), nil
+}
+
+func (n *natsBroker) NewMessageEventQueue(queueName string) (domain.MessageEventQueue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	// consumer names can't contain dots
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, stream.NATSMessageEventsStream, jetstream.ConsumerConfig{
+		Durable:       strings.ReplaceAll(queueName, ".", "-"),
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup consumer: %w", err)
+	}
+
+	return &natsMessageEventQueue{
+		js:       n.js,
+		consumer: consumer,
+	}, nil
+}
+
+func (n *natsBroker) NewThumbnailQueue() (domain.ThumbnailQueue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, natsThumbnailsStream, jetstream.ConsumerConfig{
+		Durable:   natsThumbnailsConsumer,
+		AckPolicy: jetstream.AckExplicitPolicy,
+		AckWait:   natsThumbnailsAckWait,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup consumer: %w", err)
+	}
+
+	return &natsThumbnailQueue{
+		js:       n.js,
+		consumer: consumer,
+	}, nil
+}
+
+func (n *natsBroker) NewDeadLetterQueue() (domain.DeadLetterQueue, error) {
+	deadLetters, err := n.js.Stream(context.Background(), stream.NATSDeadLettersStream)
+	if err != nil {
+		return nil, fmt.Errorf("open stream: %w", err)
+	}
+
+	return &natsDeadLetterQueue{
+		conn:        n.conn,
+		js:          n.js,
+		deadLetters: deadLetters,
+	}, nil
+}
+
+func (n *natsBroker) NewControlPlane() (domain.ControlPlane, error) {
+	subscription, err := n.conn.SubscribeSync(domain.ChannelExchangeControl)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	return &natsControlPlane{
+		conn:         n.conn,
+		subscription: subscription,
+	}, nil
+}
+
+func (n *natsBroker) Close() {
+	n.conn.Close()
+}
+
+// Stops msgs when ctx is canceled, calling handle for each
+// message until then
+func consumeNATS(ctx context.Context, msgs jetstream.MessagesContext, handle func(jetstream.Msg)) error {
+	stop := context.AfterFunc(ctx, msgs.Stop)
+	defer stop()
+
+	defer msgs.Stop()
+
+	for {
+		msg, err := msgs.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("next message: %w", err)
+		}
+
+		handle(msg)
+	}
+}
+
+// Retried once, like a nack of a delivery that wasn't redelivered
+func retryNATS(msg jetstream.Msg) {
+	metadata, err := msg.Metadata()
+	if err == nil && metadata.NumDelivered > 1 {
+		msg.Term()
+		return
+	}
+
+	msg.Nak()
+}
+
+type natsMessageEventQueue struct {
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+}
+
+func (q *natsMessageEventQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.MessageEvent) error,
+) error {
+	msgs, err := q.consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	defer internal.LogGoroutineClosed("NATSMessageEventQueue.Consume")
+
+	return consumeNATS(ctx, msgs, func(msg jetstream.Msg) {
+		var event domain.MessageEvent
+
+		if err := json.Unmarshal(msg.Data(), &event); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			stream.RejectNATSMessage(q.js, msg, fmt.Errorf("json decode: %w", err))
+
+			return
+		}
+
+		if err := handle(ctx, &event); err != nil {
+			log.Printf("err: handle %s event: %s", event.Type, err)
+
+			retryNATS(msg)
+
+			return
+		}
+
+		if err := msg.Ack(); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	})
+}
+
+func (q *natsMessageEventQueue) Close() {}
+
+type natsThumbnailQueue struct {
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+}
+
+func (q *natsThumbnailQueue) Enqueue(job *domain.ThumbnailJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json encode job: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	if _, err = q.js.Publish(ctx, thumbnailQueueName, body); err != nil {
+		return fmt.Errorf("publish job: %w", err)
+	}
+
+	return nil
+}
+
+func (q *natsThumbnailQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.ThumbnailJob) error,
+) error {
+	// one job at a time per worker, as they are cpu bound
+	msgs, err := q.consumer.Messages(jetstream.PullMaxMessages(1))
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	defer internal.LogGoroutineClosed("NATSThumbnailQueue.Consume")
+
+	return consumeNATS(ctx, msgs, func(msg jetstream.Msg) {
+		var job domain.ThumbnailJob
+
+		if err := json.Unmarshal(msg.Data(), &job); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			stream.RejectNATSMessage(q.js, msg, fmt.Errorf("json decode: %w", err))
+
+			return
+		}
+
+		if err := handle(ctx, &job); err != nil {
+			log.Printf("err: handle thumbnail job %d: %s", job.AttachmentID, err)
+
+			retryNATS(msg)
+
+			return
+		}
+
+		if err := msg.Ack(); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	})
+}
+
+func (q *natsThumbnailQueue) Close() {}
+
+// Over core NATS, like presence
+type natsControlPlane struct {
+	conn         *nats.Conn
+	subscription *nats.Subscription
+}
+
+func (p *natsControlPlane) Publish(ctx context.Context, command *domain.ControlCommand) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("json encode command: %w", err)
+	}
+
+	if err = p.conn.Publish(domain.ChannelExchangeControl, body); err != nil {
+		return fmt.Errorf("publish command: %w", err)
+	}
+
+	return nil
+}
+
+// Undecodable commands are dropped instead of dead lettered,
+// replaying them later would close the connections opened since
+func (p *natsControlPlane) Subscribe(ctx context.Context, handle func(*domain.ControlCommand)) error {
+	defer internal.LogGoroutineClosed("NATSControlPlane.Subscribe")
+
+	for {
+		m, err := p.subscription.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("next message: %w", err)
+		}
+
+		var command domain.ControlCommand
+
+		if err = json.Unmarshal(m.Data, &command); err != nil {
+			log.Printf("err: json decode: %s", err)
+			continue
+		}
+
+		handle(&command)
+	}
+}
+
+func (p *natsControlPlane) Close() {
+	p.subscription.Unsubscribe()
+}
+
+type natsDeadLetterQueue struct {
+	conn        *nats.Conn
+	js          jetstream.JetStream
+	deadLetters jetstream.Stream
+}
+
+// Calls yield with the oldest dead letters, in order, until it
+// returns false. Deleted sequences are skipped
+func (q *natsDeadLetterQueue) each(ctx context.Context, yield func(*jetstream.RawStreamMsg) (bool, error)) error {
+	info, err := q.deadLetters.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("stream info: %w", err)
+	}
+
+	for seq := info.State.FirstSeq; seq > 0 && seq <= info.State.LastSeq; seq++ {
+		msg, err := q.deadLetters.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("get message %d: %w", seq, err)
+		}
+
+		next, err := yield(msg)
+		if err != nil {
+			return err
+		}
+
+		if !next {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (q *natsDeadLetterQueue) List(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	var deadLetters []domain.DeadLetter
+
+	err := q.each(ctx, func(msg *jetstream.RawStreamMsg) (bool, error) {
+		deadLetters = append(deadLetters, natsDeadLetter(msg))
+
+		return len(deadLetters) < limit, nil
+	})
+
+	return deadLetters, err
+}
+
+func (q *natsDeadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	replayed := 0
+
+	err := q.each(ctx, func(msg *jetstream.RawStreamMsg) (bool, error) {
+		source := msg.Header.Get(stream.SourceNATSHeader)
+
+		var err error
+
+		// presence is broadcast over core NATS
+		if source == domain.ChannelExchangePresence {
+			err = q.conn.Publish(source, msg.Data)
+		} else {
+			_, err = q.js.Publish(ctx, source, msg.Data)
+		}
+		if err != nil {
+			return false, fmt.Errorf("publish: %w", err)
+		}
+
+		if err = q.deadLetters.DeleteMsg(ctx, msg.Sequence); err != nil {
+			return false, fmt.Errorf("delete message %d: %w", msg.Sequence, err)
+		}
+
+		replayed++
+
+		return replayed < limit, nil
+	})
+
+	return replayed, err
+}
+
+func (q *natsDeadLetterQueue) Count(ctx context.Context) (int, error) {
+	info, err := q.deadLetters.Info(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("stream info: %w", err)
+	}
+
+	return int(info.State.Msgs), nil
+}
+
+func natsDeadLetter(msg *jetstream.RawStreamMsg) domain.DeadLetter {
+	deadLetter := domain.DeadLetter{
+		ID:     strconv.FormatUint(msg.Sequence, 10),
+		Source: msg.Header.Get(stream.SourceNATSHeader),
+		Reason: msg.Header.Get(stream.RejectionReasonNATSHeader),
+		Body:   string(msg.Data),
+	}
+
+	rejectedAt, err := time.Parse(time.RFC3339Nano, msg.Header.Get(stream.RejectedAtNATSHeader))
+	if err == nil {
+		deadLetter.RejectedAt = &rejectedAt
+	}
+
+	return deadLetter
+}
+
+// Creates the streams if they don't exist yet, or
+// updates them when the policy changed
+func NewNATS(conn *nats.Conn, userQueuePolicy *domain.UserQueuePolicy) (*natsBroker, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, fmt.Errorf("open jetstream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+
+	streams := []jetstream.StreamConfig{
+		stream.NATSUsersStreamConfig(userQueuePolicy),
+		{
+			Name:     stream.NATSMessageEventsStream,
+			Subjects: []string{stream.NATSMessageEventsSubject},
+			MaxAge:   natsMessageEventsMaxAge,
+		},
+		{
+			Name:      natsThumbnailsStream,
+			Subjects:  []string{thumbnailQueueName},
+			Retention: jetstream.WorkQueuePolicy,
+		},
+		{
+			Name:     stream.NATSDeadLettersStream,
+			Subjects: []string{stream.NATSDeadLettersSubject},
+		},
+	}
+
+	for _, config := range streams {
+		if _, err = js.CreateOrUpdateStream(ctx, config); err != nil {
+			return nil, fmt.Errorf("setup stream %s: %w", config.Name, err)
+		}
+	}
+
+	return &natsBroker{
+		conn:            conn,
+		js:              js,
+		userQueuePolicy: userQueuePolicy,
+	}, nil
+}
@@ -7,9 +7,12 @@ import (
 
 	"github.com/lam0glia/chat-system/domain"
 	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/stream"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// The limits of the user queues are a policy, applied
+// by bootstrap or set by the operators
 type rabbitMQ struct {
 	connection *amqp.Connection
 }
@@ -43,6 +46,10 @@ func (r *rabbitMQ) NewChannel() (domain.StreamChannel, error) {
 		return nil, err
 	}
 
+	if err = stream.DeclareDeadLetters(ch); err != nil {
+		return nil, fmt.Errorf("setup dead letters: %w", err)
+	}
+
 	q, err := ch.QueueDeclare(
 		"",    // nome vazio cria uma fila exclusiva e aleatória
 		false, // durable
@@ -113,17 +120,22 @@ func (c *rabbitMQChannel) Subscribe(buff domain.WebsocketWriteBuffer) error {
 	defer internal.LogGoroutineClosed("RabbitMQChannel.Subscribe")
 
 	for d := range msgs {
-		var msg any
+		var msg domain.Presence
 
 		if err = json.Unmarshal(d.Body, &msg); err != nil {
 			log.Printf("err: json decode: %s", err)
 
-			d.Reject(false)
+			stream.RejectDelivery(
+				c.channel,
+				&d,
+				domain.ChannelExchangePresence,
+				fmt.Errorf("json decode: %w", err),
+			)
 
 			continue
 		}
 
-		buff.Write(msg)
+		buff.Write(&msg)
 
 		if err = d.Ack(false); err != nil {
 			log.Printf("err: ack: %s", err)
@@ -134,6 +146,64 @@ func (c *rabbitMQChannel) Subscribe(buff domain.WebsocketWriteBuffer) error {
 	return nil
 }
 
+func (r *rabbitMQ) NewChatStream(userID uint64) (domain.ChatStream, error) {
+	chat, err := stream.NewChat(r.connection, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+func (r *rabbitMQ) NewChatDispatcher() (domain.ChatStream, error) {
+	chat, err := stream.NewChatDispatcher(r.connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+func (r *rabbitMQ) NewMessageEventQueue(queueName string) (domain.MessageEventQueue, error) {
+	queue, err := NewMessageEventQueue(r.connection, queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func (r *rabbitMQ) NewThumbnailQueue() (domain.ThumbnailQueue, error) {
+	queue, err := NewThumbnailQueue(r.connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func (r *rabbitMQ) NewDeadLetterQueue() (domain.DeadLetterQueue, error) {
+	queue, err := NewDeadLetterQueue(r.connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func (r *rabbitMQ) NewControlPlane() (domain.ControlPlane, error) {
+	controlPlane, err := NewControlPlane(r.connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return controlPlane, nil
+}
+
+func (r *rabbitMQ) Close() {
+	r.connection.Close()
+}
+
 func NewRabbitMQ(conn *amqp.Connection) *rabbitMQ {
 	return &rabbitMQ{
 		connection: conn,
@@ -0,0 +1,468 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/internal/redisstream"
+	"github.com/lam0glia/chat-system/stream"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisThumbnailsGroup = "thumbnail-workers"
+
+// Returned by the handlers of consumeRedis for entries that
+// must be delivered again instead of acknowledged
+var errRedisKeepPending = errors.New("entry left pending")
+
+// Presence is broadcast over Pub/Sub, as it was never
+// persisted on RabbitMQ either, and the queues are streams
+type redisStreams struct {
+	client          *redis.Client
+	userQueuePolicy *domain.UserQueuePolicy
+}
+
+type redisChannel struct {
+	client *redis.Client
+	pubSub *redis.PubSub
+}
+
+func (r *redisStreams) NewChannel() (domain.StreamChannel, error) {
+	pubSub := r.client.Subscribe(context.Background(), domain.ChannelExchangePresence)
+
+	// waits for the confirmation, so publishes made after
+	// returning are received
+	if _, err := pubSub.Receive(context.Background()); err != nil {
+		pubSub.Close()
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	return &redisChannel{
+		client: r.client,
+		pubSub: pubSub,
+	}, nil
+}
+
+// The exchange is the channel, there is no routing key
+func (c *redisChannel) Publish(exchange, key string, body any) error {
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	if err = c.client.Publish(context.Background(), exchange, encodedBody).Err(); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	return nil
+}
+
+func (c *redisChannel) Subscribe(buff domain.WebsocketWriteBuffer) error {
+	defer internal.LogGoroutineClosed("RedisChannel.Subscribe")
+
+	// closed by Close
+	for m := range c.pubSub.Channel() {
+		var msg domain.Presence
+
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			// Pub/Sub can't deliver it again
+			err = rejectRedis(c.client, domain.ChannelExchangePresence, []byte(m.Payload), err)
+			if err != nil {
+				log.Printf("err: %s", err)
+			}
+
+			continue
+		}
+
+		buff.Write(&msg)
+	}
+
+	return nil
+}
+
+func (c *redisChannel) Close() {
+	c.pubSub.Close()
+}
+
+func (r *redisStreams) NewChatStream(userID uint64) (domain.ChatStream, error) {
+	chat, err := stream.NewRedisChat(r.client, userID, r.userQueuePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+func (r *redisStreams) NewChatDispatcher() (domain.ChatStream, error) {
+	return stream.NewRedisChatDispatcher(r.client, r.userQueuePolicy), nil
+}
+
+// Each queue is a group of the message stream that only
+// reads the events published after it was created
+func (r *redisStreams) NewMessageEventQueue(queueName string) (domain.MessageEventQueue, error) {
+	err := redisstream.EnsureGroup(
+		context.Background(),
+		r.client,
+		stream.RedisMessageEventsStream,
+		queueName,
+		"$",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("setup group: %w", err)
+	}
+
+	return &redisMessageEventQueue{
+		client: r.client,
+		reader: redisstream.NewReader(
+			r.client,
+			stream.RedisMessageEventsStream,
+			queueName,
+			queueName,
+		),
+	}, nil
+}
+
+func (r *redisStreams) NewThumbnailQueue() (domain.ThumbnailQueue, error) {
+	err := redisstream.EnsureGroup(
+		context.Background(),
+		r.client,
+		thumbnailQueueName,
+		redisThumbnailsGroup,
+		"0",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("setup group: %w", err)
+	}
+
+	// a worker restarted on the same host resumes its pending jobs
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("get hostname: %w", err)
+	}
+
+	return &redisThumbnailQueue{
+		client: r.client,
+		reader: redisstream.NewReader(
+			r.client,
+			thumbnailQueueName,
+			redisThumbnailsGroup,
+			hostname,
+		),
+	}, nil
+}
+
+func (r *redisStreams) NewDeadLetterQueue() (domain.DeadLetterQueue, error) {
+	return &redisDeadLetterQueue{
+		client: r.client,
+	}, nil
+}
+
+func (r *redisStreams) NewControlPlane() (domain.ControlPlane, error) {
+	pubSub := r.client.Subscribe(context.Background(), domain.ChannelExchangeControl)
+
+	if _, err := pubSub.Receive(context.Background()); err != nil {
+		pubSub.Close()
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	return &redisControlPlane{
+		client: r.client,
+		pubSub: pubSub,
+	}, nil
+}
+
+func (r *redisStreams) Close() {
+	r.client.Close()
+}
+
+// Calls handle for each entry until ctx is canceled, an entry
+// that fails is retried once in the same process and then dropped.
+// The ones failing with errRedisKeepPending are left pending, the
+// reader delivers them again when it is created
+func consumeRedis(
+	ctx context.Context,
+	reader *redisstream.Reader,
+	handle func(body []byte) error,
+	ack func(ctx context.Context, id string) error,
+) error {
+	for {
+		entry, err := reader.Next(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			return fmt.Errorf("next entry: %w", err)
+		}
+
+		body, _ := entry.Values[stream.RedisBodyField].(string)
+
+		if err = handle([]byte(body)); err != nil {
+			if errors.Is(err, errRedisKeepPending) {
+				log.Printf("err: entry %s: %s", entry.ID, err)
+				continue
+			}
+
+			if err = handle([]byte(body)); err != nil {
+				log.Printf("err: dropping entry %s: %s", entry.ID, err)
+			}
+		}
+
+		if err = ack(context.Background(), entry.ID); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	}
+}
+
+// Moves an undecodable body to the dead letters
+func rejectRedis(client *redis.Client, source string, body []byte, err error) error {
+	err = stream.AddRedisDeadLetter(client, source, body, fmt.Errorf("json decode: %w", err))
+	if err != nil {
+		return fmt.Errorf("add dead letter: %w", err)
+	}
+
+	return nil
+}
+
+type redisMessageEventQueue struct {
+	client *redis.Client
+	reader *redisstream.Reader
+}
+
+func (q *redisMessageEventQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.MessageEvent) error,
+) error {
+	defer internal.LogGoroutineClosed("RedisMessageEventQueue.Consume")
+
+	// the entry stays in the stream for the other groups,
+	// it is trimmed by its maximum length
+	return consumeRedis(ctx, q.reader, func(body []byte) error {
+		var event domain.MessageEvent
+
+		if err := json.Unmarshal(body, &event); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			if err = rejectRedis(q.client, stream.RedisMessageEventsStream, body, err); err != nil {
+				return fmt.Errorf("%w: %w", errRedisKeepPending, err)
+			}
+
+			return nil
+		}
+
+		if err := handle(ctx, &event); err != nil {
+			return fmt.Errorf("handle %s event: %w", event.Type, err)
+		}
+
+		return nil
+	}, q.reader.Ack)
+}
+
+func (q *redisMessageEventQueue) Close() {}
+
+type redisThumbnailQueue struct {
+	client *redis.Client
+	reader *redisstream.Reader
+}
+
+func (q *redisThumbnailQueue) Enqueue(job *domain.ThumbnailJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json encode job: %w", err)
+	}
+
+	err = q.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: thumbnailQueueName,
+		Values: map[string]any{stream.RedisBodyField: body},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("publish job: %w", err)
+	}
+
+	return nil
+}
+
+// The reader fetches one job at a time, as they are cpu bound
+func (q *redisThumbnailQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.ThumbnailJob) error,
+) error {
+	defer internal.LogGoroutineClosed("RedisThumbnailQueue.Consume")
+
+	return consumeRedis(ctx, q.reader, func(body []byte) error {
+		var job domain.ThumbnailJob
+
+		if err := json.Unmarshal(body, &job); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			if err = rejectRedis(q.client, thumbnailQueueName, body, err); err != nil {
+				return fmt.Errorf("%w: %w", errRedisKeepPending, err)
+			}
+
+			return nil
+		}
+
+		if err := handle(ctx, &job); err != nil {
+			return fmt.Errorf("handle thumbnail job %d: %w", job.AttachmentID, err)
+		}
+
+		return nil
+	}, q.reader.Done)
+}
+
+func (q *redisThumbnailQueue) Close() {}
+
+// Over Pub/Sub, like presence
+type redisControlPlane struct {
+	client *redis.Client
+	pubSub *redis.PubSub
+}
+
+func (p *redisControlPlane) Publish(ctx context.Context, command *domain.ControlCommand) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("json encode command: %w", err)
+	}
+
+	if err = p.client.Publish(ctx, domain.ChannelExchangeControl, body).Err(); err != nil {
+		return fmt.Errorf("publish command: %w", err)
+	}
+
+	return nil
+}
+
+// Undecodable commands are dropped instead of dead lettered,
+// replaying them later would close the connections opened since
+func (p *redisControlPlane) Subscribe(ctx context.Context, handle func(*domain.ControlCommand)) error {
+	defer internal.LogGoroutineClosed("RedisControlPlane.Subscribe")
+
+	messages := p.pubSub.Channel()
+
+	for {
+		var m *redis.Message
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case m = <-messages:
+		}
+
+		// closed by Close
+		if m == nil {
+			return nil
+		}
+
+		var command domain.ControlCommand
+
+		if err := json.Unmarshal([]byte(m.Payload), &command); err != nil {
+			log.Printf("err: json decode: %s", err)
+			continue
+		}
+
+		handle(&command)
+	}
+}
+
+func (p *redisControlPlane) Close() {
+	p.pubSub.Close()
+}
+
+type redisDeadLetterQueue struct {
+	client *redis.Client
+}
+
+func (q *redisDeadLetterQueue) List(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	entries, err := q.client.XRangeN(ctx, stream.RedisDeadLettersStream, "-", "+", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("range: %w", err)
+	}
+
+	var deadLetters []domain.DeadLetter
+
+	for _, entry := range entries {
+		deadLetters = append(deadLetters, redisDeadLetter(&entry))
+	}
+
+	return deadLetters, nil
+}
+
+func (q *redisDeadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	entries, err := q.client.XRangeN(ctx, stream.RedisDeadLettersStream, "-", "+", int64(limit)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("range: %w", err)
+	}
+
+	replayed := 0
+
+	for _, entry := range entries {
+		deadLetter := redisDeadLetter(&entry)
+
+		// presence is broadcast over Pub/Sub
+		if deadLetter.Source == domain.ChannelExchangePresence {
+			err = q.client.Publish(ctx, deadLetter.Source, deadLetter.Body).Err()
+		} else {
+			err = q.client.XAdd(ctx, &redis.XAddArgs{
+				Stream: deadLetter.Source,
+				Values: map[string]any{stream.RedisBodyField: deadLetter.Body},
+			}).Err()
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("publish: %w", err)
+		}
+
+		if err = q.client.XDel(ctx, stream.RedisDeadLettersStream, entry.ID).Err(); err != nil {
+			return replayed, fmt.Errorf("delete %s: %w", entry.ID, err)
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (q *redisDeadLetterQueue) Count(ctx context.Context) (int, error) {
+	count, err := q.client.XLen(ctx, stream.RedisDeadLettersStream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("length: %w", err)
+	}
+
+	return int(count), nil
+}
+
+func redisDeadLetter(entry *redis.XMessage) domain.DeadLetter {
+	deadLetter := domain.DeadLetter{
+		ID: entry.ID,
+	}
+
+	deadLetter.Body, _ = entry.Values[stream.RedisBodyField].(string)
+	deadLetter.Reason, _ = entry.Values[stream.RedisReasonField].(string)
+	deadLetter.Source, _ = entry.Values[stream.RedisSourceField].(string)
+
+	rejectedAt, _ := entry.Values[stream.RedisRejectedAtField].(string)
+
+	if milliseconds, err := strconv.ParseInt(rejectedAt, 10, 64); err == nil {
+		t := time.UnixMilli(milliseconds).UTC()
+		deadLetter.RejectedAt = &t
+	}
+
+	return deadLetter
+}
+
+// The client is used only by the broker, every connection
+// blocks one of its pooled connections while reading
+func NewRedisStreams(client *redis.Client, userQueuePolicy *domain.UserQueuePolicy) *redisStreams {
+	return &redisStreams{
+		client:          client,
+		userQueuePolicy: userQueuePolicy,
+	}
+}
@@ -0,0 +1,90 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/stream"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestConsumeRedisKeepsEntryWhenDeadLetterFails(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() { client.Close() })
+
+	queue, err := NewRedisStreams(client, &domain.UserQueuePolicy{}).NewThumbnailQueue()
+	if err != nil {
+		t.Fatalf("new thumbnail queue: %s", err)
+	}
+
+	// XADD to a key of another type fails
+	if err = server.Set(stream.RedisDeadLettersStream, "not a stream"); err != nil {
+		t.Fatalf("set: %s", err)
+	}
+
+	poisonID := addRedisEntry(t, client, thumbnailQueueName, "{")
+	addRedisEntry(t, client, thumbnailQueueName, `{"attachmentId":1}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// entries are handled in order, so the poison one
+	// was rejected once the next one is handled
+	handled := make(chan *domain.ThumbnailJob, 1)
+
+	consumed := make(chan error, 1)
+
+	go func() {
+		consumed <- queue.Consume(ctx, func(ctx context.Context, job *domain.ThumbnailJob) error {
+			handled <- job
+			return nil
+		})
+	}()
+
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the valid job was not handled")
+	}
+
+	cancel()
+
+	if err = <-consumed; err != nil {
+		t.Fatalf("consume: %s", err)
+	}
+
+	pending, err := client.XPending(context.Background(), thumbnailQueueName, redisThumbnailsGroup).Result()
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+
+	if pending.Count != 1 || pending.Lower != poisonID {
+		t.Fatalf("got pending %+v, want only %s", pending, poisonID)
+	}
+
+	entries, err := client.XRange(context.Background(), thumbnailQueueName, poisonID, poisonID).Result()
+	if err != nil {
+		t.Fatalf("range: %s", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want the poison one kept in the stream", len(entries))
+	}
+}
+
+func addRedisEntry(t *testing.T, client *redis.Client, key, body string) string {
+	id, err := client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: key,
+		Values: map[string]any{stream.RedisBodyField: body},
+	}).Result()
+	if err != nil {
+		t.Fatalf("add entry: %s", err)
+	}
+
+	return id
+}
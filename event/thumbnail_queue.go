@@ -0,0 +1,135 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/stream"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const thumbnailQueueName = "attachment.thumbnails"
+
+type thumbnailQueue struct {
+	channel *amqp.Channel
+	mu      sync.Mutex
+}
+
+func (q *thumbnailQueue) Enqueue(job *domain.ThumbnailJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("json encode job: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	err = q.channel.Publish(
+		"",                 // exchange
+		thumbnailQueueName, // routing key
+		false,              // mandatory
+		false,              // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
+		})
+	if err != nil {
+		return fmt.Errorf("publish job: %w", err)
+	}
+
+	return nil
+}
+
+func (q *thumbnailQueue) Consume(
+	ctx context.Context,
+	handle func(context.Context, *domain.ThumbnailJob) error,
+) error {
+	// one job at a time per worker, as they are cpu bound
+	if err := q.channel.Qos(1, 0, false); err != nil {
+		return fmt.Errorf("set qos: %w", err)
+	}
+
+	deliveries, err := q.channel.ConsumeWithContext(
+		ctx,
+		thumbnailQueueName, // queue
+		"",                 // consumer
+		false,              // auto-ack
+		false,              // exclusive
+		false,              // no-local
+		false,              // no-wait
+		nil,                // args
+	)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	defer internal.LogGoroutineClosed("ThumbnailQueue.Consume")
+
+	for d := range deliveries {
+		var job domain.ThumbnailJob
+
+		if err = json.Unmarshal(d.Body, &job); err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			q.mu.Lock()
+			stream.RejectDelivery(q.channel, &d, thumbnailQueueName, fmt.Errorf("json decode: %w", err))
+			q.mu.Unlock()
+
+			continue
+		}
+
+		if err = handle(ctx, &job); err != nil {
+			log.Printf("err: handle thumbnail job %d: %s", job.AttachmentID, err)
+
+			// retried once
+			d.Nack(false, !d.Redelivered)
+
+			continue
+		}
+
+		if err = d.Ack(false); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (q *thumbnailQueue) Close() {
+	q.channel.Close()
+}
+
+func NewThumbnailQueue(conn *amqp.Connection) (*thumbnailQueue, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	if err = stream.DeclareDeadLetters(ch); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup dead letters: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(
+		thumbnailQueueName, // name
+		true,               // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("setup queue: %w", err)
+	}
+
+	return &thumbnailQueue{
+		channel: ch,
+	}, nil
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+// Users, their conversations and connections, for support and moderators
+type Admin struct {
+	chatRepository            domain.ChatRepository
+	conversationRepository    domain.ConversationRepository
+	userRepository            domain.UserRepository
+	presenceRepository        domain.PresenceRepository
+	connectionCountRepository domain.ConnectionCountRepository
+	controlPlane              domain.ControlPlane
+	rateLimitPolicy           *domain.RateLimitPolicy
+}
+
+func (h *Admin) GetUser(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	status := domain.UserStatus{
+		UserID: userID,
+	}
+
+	var err error
+
+	if status.Connections, err = h.connectionCountRepository.CountUser(ctx, userID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if status.Exists, err = h.userRepository.Exists(ctx, userID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if status.Suspension, err = h.userRepository.GetSuspension(ctx, userID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if status.Tier, err = h.userRepository.GetTier(ctx, userID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	// presence is best effort, as in the rest of the chat
+	if status.Online, err = h.presenceRepository.IsOnline(ctx, userID); err != nil {
+		log.Printf("err: get presence of %d: %s", userID, err)
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Admin) Suspend(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	var request domain.SuspendUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.UserID = userID
+
+	suspension, err := use_case.NewSuspendUser(h.userRepository, h.controlPlane).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+func (h *Admin) Unsuspend(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepository.Unsuspend(c.Request.Context(), userID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Admin) SetTier(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	var request domain.SetUserTierRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.UserID = userID
+
+	err := use_case.NewSetUserTier(h.userRepository, h.rateLimitPolicy).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Accepted once the command is published, the
+// nodes close the connections as they receive it
+func (h *Admin) Disconnect(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	err := use_case.NewDisconnectUser(h.controlPlane).Execute(
+		c.Request.Context(),
+		userID,
+		domain.CloseCodeDisconnected,
+		"disconnected by an administrator",
+	)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (h *Admin) Connections(c *gin.Context) {
+	count, err := h.connectionCountRepository.Count(c.Request.Context())
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, count)
+}
+
+// The conversations as the user sees them
+func (h *Admin) ListConversations(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	var params domain.ListConversationsRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	conversations, err := use_case.NewListConversations(h.conversationRepository).Execute(
+		c.Request.Context(),
+		userID,
+		&params,
+	)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	if conversations == nil {
+		conversations = []domain.Conversation{}
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// Compliance read of a conversation, including the
+// messages the user deleted only for themselves
+func (h *Admin) ListMessages(c *gin.Context) {
+	userID, ok := bindUserID(c)
+	if !ok {
+		return
+	}
+
+	peerID, err := strconv.ParseUint(c.Param("peerId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// set before binding, so the query doesn't need it
+	params := domain.ListMessageRequest{
+		To: peerID,
+	}
+
+	if err = c.ShouldBindQuery(&params); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	params.To = peerID
+	params.IncludeHidden = true
+
+	page, err := use_case.NewListMessages(h.chatRepository).Execute(
+		c.Request.Context(),
+		userID,
+		&params,
+	)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func bindUserID(c *gin.Context) (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func NewAdmin(
+	chatRepository domain.ChatRepository,
+	conversationRepository domain.ConversationRepository,
+	userRepository domain.UserRepository,
+	presenceRepository domain.PresenceRepository,
+	connectionCountRepository domain.ConnectionCountRepository,
+	controlPlane domain.ControlPlane,
+	rateLimitPolicy *domain.RateLimitPolicy,
+) *Admin {
+	return &Admin{
+		chatRepository:            chatRepository,
+		conversationRepository:    conversationRepository,
+		userRepository:            userRepository,
+		presenceRepository:        presenceRepository,
+		connectionCountRepository: connectionCountRepository,
+		controlPlane:              controlPlane,
+		rateLimitPolicy:           rateLimitPolicy,
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/http/middleware"
+)
+
+// Room for the multipart headers and the other form fields
+const multipartOverhead = 1 << 20
+
+type Attachment struct {
+	chatRepository          domain.ChatRepository
+	blobStore               domain.BlobStore
+	uploadAttachmentUseCase domain.UploadAttachmentUseCase
+	maxSize                 int64
+}
+
+func (h *Attachment) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+
+	var params struct {
+		To uint64 `form:"to" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&params); err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer file.Close()
+
+	attachment, err := h.uploadAttachmentUseCase.Execute(
+		c.Request.Context(),
+		&domain.UploadAttachmentRequest{
+			From:    middleware.GetUserIDFromContext(c),
+			To:      params.To,
+			Name:    header.Filename,
+			Size:    header.Size,
+			Content: file,
+		},
+	)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *Attachment) Download(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	attachment, ok := h.getAttachment(c, id)
+	if !ok {
+		return
+	}
+
+	h.writeBlob(c, attachment, attachment.BlobKey, attachment.MIMEType, attachment.Size)
+}
+
+func (h *Attachment) DownloadThumbnail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	attachment, ok := h.getAttachment(c, id)
+	if !ok {
+		return
+	}
+
+	thumbnail := attachment.Thumbnail(size)
+	if thumbnail == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	h.writeBlob(c, attachment, thumbnail.BlobKey, thumbnail.MIMEType, -1)
+}
+
+// Aborts the request if the user is not a participant
+func (h *Attachment) getAttachment(c *gin.Context, id uint64) (*domain.Attachment, bool) {
+	attachment, err := h.chatRepository.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return nil, false
+	}
+
+	// not revealing that the attachment exists
+	if !attachment.IsParticipant(middleware.GetUserIDFromContext(c)) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil, false
+	}
+
+	return attachment, true
+}
+
+// A negative size omits the Content-Length
+func (h *Attachment) writeBlob(
+	c *gin.Context,
+	attachment *domain.Attachment,
+	key,
+	mimeType string,
+	size int64,
+) {
+	content, err := h.blobStore.Get(c.Request.Context(), key)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(mimeType, "image/") {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(
+		http.StatusOK,
+		size,
+		mimeType,
+		content,
+		map[string]string{
+			"Content-Disposition": mime.FormatMediaType(disposition, map[string]string{
+				"filename": attachment.Name,
+			}),
+			"X-Content-Type-Options": "nosniff",
+			"Cache-Control":          "private, max-age=86400",
+		},
+	)
+}
+
+func abortWithUploadError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+
+	if errors.As(err, &maxBytesErr) {
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusBadRequest)
+}
+
+func NewAttachment(
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	uploadAttachmentUseCase domain.UploadAttachmentUseCase,
+	maxSize int64,
+) *Attachment {
+	return &Attachment{
+		chatRepository:          chatRepository,
+		blobStore:               blobStore,
+		uploadAttachmentUseCase: uploadAttachmentUseCase,
+		maxSize:                 maxSize,
+	}
+}
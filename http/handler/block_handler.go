@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/http/middleware"
+)
+
+type Block struct {
+	repository domain.BlockRepository
+}
+
+func (h *Block) Create(c *gin.Context) {
+	var request domain.BlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+
+	if request.UserID == userID {
+		abortWithUseCaseError(c, domain.ErrCannotBlockSelf)
+		return
+	}
+
+	if err := h.repository.Block(c.Request.Context(), userID, request.UserID); err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Block) Delete(c *gin.Context) {
+	blockedID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	err = h.repository.Unblock(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
+		blockedID,
+	)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Block) List(c *gin.Context) {
+	blocks, err := h.repository.ListBlocked(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
+	)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if blocks == nil {
+		blocks = []domain.Block{}
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+func NewBlock(repository domain.BlockRepository) *Block {
+	return &Block{
+		repository: repository,
+	}
+}
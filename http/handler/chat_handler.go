@@ -1,27 +1,43 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/lam0glia/chat-system/domain"
 	"github.com/lam0glia/chat-system/http/middleware"
-	"github.com/lam0glia/chat-system/stream"
 	"github.com/lam0glia/chat-system/use_case"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const threadPageSize = 50
+
 type Chat struct {
-	queueConn            *amqp.Connection
-	upgrader             websocket.Upgrader
-	chatRepository       domain.ChatRepository
-	uidGenerator         domain.UIDGenerator
-	presenceService      domain.PresenceService
-	channelFactory       domain.ChannelFactory
-	websocketWriteBuffer domain.WebsocketWriteBuffer
+	chatStreamFactory      domain.ChatStreamFactory
+	upgrader               websocket.Upgrader
+	chatRepository         domain.ChatRepository
+	blobStore              domain.BlobStore
+	blockRepository        domain.BlockRepository
+	conversationRepository domain.ConversationRepository
+	rateLimitService       domain.RateLimitService
+	userRepository         domain.UserRepository
+	moderator              domain.Moderator
+	reviewRepository       domain.ReviewRepository
+	uidGenerator           domain.UIDGenerator
+	presenceService        domain.PresenceService
+	channelFactory         domain.ChannelFactory
+	connections            domain.ConnectionRegistry
+	controlPlane           domain.ControlPlane
+	messageEditWindow      time.Duration
+	// In runes
+	messageMaxLength int
+	// In bytes, larger frames close the connection
+	websocketReadLimit int64
 }
 
 /*
@@ -31,7 +47,14 @@ to write to the client and its not concurrency safe.
 func (h *Chat) WebSocket(c *gin.Context) {
 	userID := middleware.GetUserIDFromContext(c)
 
-	chatStream, err := stream.NewChat(h.queueConn, userID)
+	// refused before upgrading, so clients get a status
+	err := use_case.NewCheckSuspension(h.userRepository).Execute(c.Request.Context(), userID)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	chatStream, err := h.chatStreamFactory.NewChatStream(userID)
 	if err != nil {
 		abortWithInternalError(c, err)
 		return
@@ -43,8 +66,33 @@ func (h *Chat) WebSocket(c *gin.Context) {
 		chatStream,
 		h.chatRepository,
 		h.uidGenerator,
+		h.blockRepository,
+		h.conversationRepository,
+		h.rateLimitService,
+		h.userRepository,
+		h.moderator,
+		h.reviewRepository,
+		h.messageMaxLength,
 	)
 
+	editMessageUseCase := use_case.NewEditMessage(
+		chatStream,
+		h.chatRepository,
+		h.blockRepository,
+		h.moderator,
+		h.reviewRepository,
+		h.messageEditWindow,
+		h.messageMaxLength,
+	)
+
+	deleteMessageUseCase := use_case.NewDeleteMessage(chatStream, h.chatRepository, h.blobStore)
+
+	reactionUseCase := use_case.NewReaction(chatStream, h.chatRepository, h.blockRepository)
+
+	typingUseCase := use_case.NewTyping(chatStream, h.blockRepository, userID)
+
+	defer typingUseCase.Close()
+
 	channel, err := h.channelFactory.NewChannel()
 	if err != nil {
 		abortWithInternalError(c, err)
@@ -54,11 +102,16 @@ func (h *Chat) WebSocket(c *gin.Context) {
 	ws, err := newChatWS(
 		c,
 		h.upgrader,
-		userID,
+		middleware.GetClient(c),
+		h.rateLimitService,
 		sendMessageUseCase,
+		editMessageUseCase,
+		deleteMessageUseCase,
+		reactionUseCase,
+		typingUseCase,
 		chatStream,
 		h.presenceService,
-		h.websocketWriteBuffer,
+		h.websocketReadLimit,
 	)
 	if err != nil {
 		log.Printf("err: upgrade: %s", err)
@@ -67,6 +120,13 @@ func (h *Chat) WebSocket(c *gin.Context) {
 
 	defer ws.close()
 
+	defer h.connections.Add(userID, ws)()
+
+	// after upgrading, so failed upgrades don't register users
+	if err = h.userRepository.Register(c.Request.Context(), userID); err != nil {
+		log.Printf("err: register user: %s", err)
+	}
+
 	h.presenceService.SetChannel(channel)
 
 	ctx := c.Request.Context()
@@ -84,10 +144,16 @@ func (h *Chat) WebSocket(c *gin.Context) {
 	go ws.readFromClient(ctx)
 
 	// TODO: Close goroutine
-	go h.websocketWriteBuffer.DeliveryToClient()
+	go ws.websocketWriteBuffer.DeliveryToClient()
 
 	// TODO: Handle error
-	go h.presenceService.SubscribeUserPresenceUpdate(h.websocketWriteBuffer)
+	go h.presenceService.SubscribeUserPresenceUpdate(userID, ws.websocketWriteBuffer)
+
+	go func() {
+		if err := chatStream.ConsumeMessages(ws.websocketWriteBuffer); err != nil {
+			log.Printf("err: consume messages: %s", err)
+		}
+	}()
 
 	go ws.ping(ctx)
 
@@ -106,37 +172,300 @@ func (h *Chat) ListMessages(c *gin.Context) {
 
 	from := middleware.GetUserIDFromContext(c)
 
-	messages, err := h.chatRepository.ListMessages(
+	page, err := use_case.NewListMessages(h.chatRepository).Execute(
 		c.Request.Context(),
 		from,
+		&params,
+	)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Chat) EditMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.EditMessageRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+
+	request.ID = id
+	request.From = userID
+
+	chatStream, err := h.chatStreamFactory.NewChatDispatcher()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer chatStream.Close()
+
+	message, err := use_case.NewEditMessage(
+		chatStream,
+		h.chatRepository,
+		h.blockRepository,
+		h.moderator,
+		h.reviewRepository,
+		h.messageEditWindow,
+		h.messageMaxLength,
+	).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *Chat) DeleteMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.DeleteMessageRequest
+	if err = c.ShouldBindQuery(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+
+	request.ID = id
+	request.From = userID
+
+	chatStream, err := h.chatStreamFactory.NewChatDispatcher()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer chatStream.Close()
+
+	err = use_case.NewDeleteMessage(
+		chatStream,
+		h.chatRepository,
+		h.blobStore,
+	).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Chat) AddReaction(c *gin.Context) {
+	h.handleReaction(c, c.ShouldBindJSON, domain.ReactionUseCase.Add)
+}
+
+func (h *Chat) RemoveReaction(c *gin.Context) {
+	h.handleReaction(c, c.ShouldBindQuery, domain.ReactionUseCase.Remove)
+}
+
+func (h *Chat) handleReaction(
+	c *gin.Context,
+	bind func(any) error,
+	execute func(domain.ReactionUseCase, context.Context, *domain.ReactionRequest) error,
+) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.ReactionRequest
+	if err = bind(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+
+	request.MessageID = id
+	request.From = userID
+
+	chatStream, err := h.chatStreamFactory.NewChatDispatcher()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer chatStream.Close()
+
+	useCase := use_case.NewReaction(chatStream, h.chatRepository, h.blockRepository)
+
+	if err = execute(useCase, c.Request.Context(), &request); err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Chat) ListThread(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var params domain.ListThreadRequest
+	if err = c.ShouldBindQuery(&params); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.chatRepository.ListThread(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
 		params.To,
-		params.BeforeID,
-		10)
+		id,
+		params.AfterID,
+		threadPageSize,
+	)
 	if err != nil {
 		abortWithInternalError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, messages)
 }
 
+func (h *Chat) ListMessageEdits(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var params domain.ListMessageEditsRequest
+	if err = c.ShouldBindQuery(&params); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	edits, err := h.chatRepository.ListMessageEdits(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
+		params.To,
+		id,
+	)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, edits)
+}
+
+func (h *Chat) ListConversations(c *gin.Context) {
+	var params domain.ListConversationsRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	conversations, err := use_case.NewListConversations(h.conversationRepository).Execute(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
+		&params,
+	)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	if conversations == nil {
+		conversations = []domain.Conversation{}
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+func (h *Chat) UpdateConversation(c *gin.Context) {
+	peerID, err := strconv.ParseUint(c.Param("peerId"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.UpdateConversationRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+
+	request.PeerID = peerID
+	request.From = userID
+
+	conversation, err := use_case.NewUpdateConversation(
+		h.controlPlane,
+		h.conversationRepository,
+		h.userRepository,
+	).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
 func NewChat(
-	queueConn *amqp.Connection,
+	chatStreamFactory domain.ChatStreamFactory,
 	uidGenerator domain.UIDGenerator,
 	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	blockRepository domain.BlockRepository,
+	conversationRepository domain.ConversationRepository,
+	rateLimitService domain.RateLimitService,
+	userRepository domain.UserRepository,
+	moderator domain.Moderator,
+	reviewRepository domain.ReviewRepository,
 	channelFactory domain.ChannelFactory,
 	presenceService domain.PresenceService,
-	websocketWriteBuffer domain.WebsocketWriteBuffer,
+	connections domain.ConnectionRegistry,
+	controlPlane domain.ControlPlane,
+	messageEditWindow time.Duration,
+	messageMaxLength int,
+	websocketReadLimit int64,
 ) *Chat {
 	return &Chat{
 		upgrader: websocket.Upgrader{
 			HandshakeTimeout: 10 * time.Second,
 		},
-		queueConn:            queueConn,
-		uidGenerator:         uidGenerator,
-		chatRepository:       chatRepository,
-		channelFactory:       channelFactory,
-		presenceService:      presenceService,
-		websocketWriteBuffer: websocketWriteBuffer,
+		chatStreamFactory:      chatStreamFactory,
+		uidGenerator:           uidGenerator,
+		chatRepository:         chatRepository,
+		blobStore:              blobStore,
+		blockRepository:        blockRepository,
+		conversationRepository: conversationRepository,
+		rateLimitService:       rateLimitService,
+		userRepository:         userRepository,
+		moderator:              moderator,
+		reviewRepository:       reviewRepository,
+		channelFactory:         channelFactory,
+		presenceService:        presenceService,
+		connections:            connections,
+		controlPlane:           controlPlane,
+		messageEditWindow:      messageEditWindow,
+		messageMaxLength:       messageMaxLength,
+		websocketReadLimit:     websocketReadLimit,
 	}
 }
 
@@ -144,3 +473,49 @@ func abortWithInternalError(c *gin.Context, err error) {
 	log.Println(err)
 	c.AbortWithStatus(http.StatusInternalServerError)
 }
+
+func abortWithUseCaseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrMessageNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotMessageSender),
+		errors.Is(err, domain.ErrUserSuspended):
+		c.AbortWithStatus(http.StatusForbidden)
+	case errors.Is(err, domain.ErrEditWindowExpired),
+		errors.Is(err, domain.ErrReviewDecided),
+		errors.Is(err, domain.ErrAlreadyReported),
+		errors.Is(err, domain.ErrReportResolved):
+		c.AbortWithStatus(http.StatusConflict)
+	case errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrInvalidDeleteScope),
+		errors.Is(err, domain.ErrInvalidReaction),
+		errors.Is(err, domain.ErrTooManyAttachments),
+		errors.Is(err, domain.ErrCannotBlockSelf),
+		errors.Is(err, domain.ErrInvalidMuteExpiry),
+		errors.Is(err, domain.ErrEmptyMessage),
+		errors.Is(err, domain.ErrMessageTooLong),
+		errors.Is(err, domain.ErrInvalidEncoding),
+		errors.Is(err, domain.ErrCannotReportOwn),
+		errors.Is(err, domain.ErrInvalidDuration),
+		errors.Is(err, domain.ErrUnknownTier):
+		c.AbortWithStatus(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrAttachmentNotFound),
+		errors.Is(err, domain.ErrRecipientNotFound),
+		errors.Is(err, domain.ErrBlobNotFound),
+		errors.Is(err, domain.ErrReviewNotFound),
+		errors.Is(err, domain.ErrReportNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, domain.ErrBlocked):
+		// answered like any other failure, but isn't one
+		c.AbortWithStatus(http.StatusInternalServerError)
+	case errors.Is(err, domain.ErrAttachmentTooLarge),
+		errors.Is(err, domain.ErrImageTooLarge):
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, domain.ErrAttachmentTypeNotAllowed):
+		c.AbortWithStatus(http.StatusUnsupportedMediaType)
+	case errors.Is(err, domain.ErrMessageRejected):
+		c.AbortWithStatus(http.StatusUnprocessableEntity)
+	default:
+		abortWithInternalError(c, err)
+	}
+}
@@ -3,14 +3,16 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/lam0glia/chat-system/domain"
-	"github.com/lam0glia/chat-system/stream"
+	"github.com/lam0glia/chat-system/websocket_buffer"
 )
 
 const (
@@ -21,9 +23,15 @@ const (
 type chatWS struct {
 	conn                 *websocket.Conn
 	userID               uint64
+	client               *domain.Client
+	rateLimitService     domain.RateLimitService
 	sendMessageUseCase   domain.SendMessageUseCase
+	editMessageUseCase   domain.EditMessageUseCase
+	deleteMessageUseCase domain.DeleteMessageUseCase
+	reactionUseCase      domain.ReactionUseCase
+	typingUseCase        domain.TypingUseCase
 	pingTicker           *time.Ticker
-	consumer             *stream.Chat
+	consumer             domain.ChatStream
 	done                 chan bool
 	presenceService      domain.PresenceService
 	websocketWriteBuffer domain.WebsocketWriteBuffer
@@ -32,17 +40,28 @@ type chatWS struct {
 func newChatWS(
 	c *gin.Context,
 	upgrader websocket.Upgrader,
-	userID uint64,
+	client *domain.Client,
+	rateLimitService domain.RateLimitService,
 	sendMessageUseCase domain.SendMessageUseCase,
-	consumer *stream.Chat,
+	editMessageUseCase domain.EditMessageUseCase,
+	deleteMessageUseCase domain.DeleteMessageUseCase,
+	reactionUseCase domain.ReactionUseCase,
+	typingUseCase domain.TypingUseCase,
+	consumer domain.ChatStream,
 	presenceService domain.PresenceService,
-	websocketWriteBuffer domain.WebsocketWriteBuffer,
+	readLimit int64,
 ) (*chatWS, error) {
+	userID := client.UserID
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return nil, fmt.Errorf("upgrade http connection: %w", err)
 	}
 
+	// oversized frames are rejected before being read, closing
+	// the connection with websocket.CloseMessageTooBig
+	conn.SetReadLimit(readLimit)
+
 	ticker := time.NewTicker(pingTickerDuration)
 
 	conn.SetPongHandler(func(string) error {
@@ -61,17 +80,21 @@ func newChatWS(
 		return nil
 	})
 
-	websocketWriteBuffer.Setup(conn)
-
 	return &chatWS{
 		conn:                 conn,
 		userID:               userID,
+		client:               client,
+		rateLimitService:     rateLimitService,
 		sendMessageUseCase:   sendMessageUseCase,
+		editMessageUseCase:   editMessageUseCase,
+		deleteMessageUseCase: deleteMessageUseCase,
+		reactionUseCase:      reactionUseCase,
+		typingUseCase:        typingUseCase,
 		pingTicker:           ticker,
 		consumer:             consumer,
 		done:                 make(chan bool),
 		presenceService:      presenceService,
-		websocketWriteBuffer: websocketWriteBuffer,
+		websocketWriteBuffer: websocket_buffer.NewWriteBuffer(conn),
 	}, nil
 }
 
@@ -94,16 +117,123 @@ func (ws *chatWS) readFromClient(ctx context.Context) {
 			break
 		}
 
+		frame, err := io.ReadAll(r)
+		if err != nil {
+			log.Printf("err: read frame: %s", err)
+			continue
+		}
+
+		if err = ws.handleClientEvent(ctx, frame); err != nil {
+			log.Printf("err: %s", err)
+		}
+	}
+}
+
+func (ws *chatWS) handleClientEvent(ctx context.Context, frame []byte) error {
+	var event domain.ClientEvent
+
+	if err := json.Unmarshal(frame, &event); err != nil {
+		return fmt.Errorf("decode json: %w", err)
+	}
+
+	// sends have limits of their own
+	if event.Type != "" && event.Type != domain.EventTypeMessageSend {
+		err := ws.rateLimitService.Allow(ctx, ws.client, domain.RateLimitActionEvent)
+		if err != nil {
+			ws.writeError(event.Type, err)
+			return fmt.Errorf("%s: %w", event.Type, err)
+		}
+	}
+
+	switch event.Type {
+	case "", domain.EventTypeMessageSend:
 		message := domain.SendMessageRequest{
 			From: ws.userID,
 		}
 
-		if err := json.NewDecoder(r).Decode(&message); err != nil {
-			log.Printf("err: decode json: %s", err)
-		} else if err = ws.sendMessageUseCase.Execute(ctx, &message); err != nil {
-			log.Printf("err: send message: %s", err)
+		if err := json.Unmarshal(frame, &message); err != nil {
+			return fmt.Errorf("decode json: %w", err)
+		}
+
+		// a sent message ends the typing indicator
+		typing := domain.TypingRequest{To: message.To}
+
+		if err := ws.typingUseCase.Stop(ctx, &typing); err != nil {
+			log.Printf("err: stop typing: %s", err)
+		}
+
+		// set after decoding, so the frame can't change it
+		message.Client = ws.client
+
+		if err := ws.sendMessageUseCase.Execute(ctx, &message); err != nil {
+			ws.writeError(domain.EventTypeMessageSend, err)
+			return fmt.Errorf("send message: %w", err)
+		}
+	case domain.EventTypeMessageEdit:
+		request := domain.EditMessageRequest{
+			From: ws.userID,
+		}
+
+		if err := json.Unmarshal(frame, &request); err != nil {
+			return fmt.Errorf("decode json: %w", err)
+		}
+
+		if _, err := ws.editMessageUseCase.Execute(ctx, &request); err != nil {
+			ws.writeError(event.Type, err)
+			return fmt.Errorf("edit message: %w", err)
+		}
+	case domain.EventTypeMessageDelete:
+		request := domain.DeleteMessageRequest{
+			From: ws.userID,
+		}
+
+		if err := json.Unmarshal(frame, &request); err != nil {
+			return fmt.Errorf("decode json: %w", err)
 		}
+
+		if err := ws.deleteMessageUseCase.Execute(ctx, &request); err != nil {
+			ws.writeError(event.Type, err)
+			return fmt.Errorf("delete message: %w", err)
+		}
+	case domain.EventTypeReactionAdd, domain.EventTypeReactionRemove:
+		request := domain.ReactionRequest{
+			From: ws.userID,
+		}
+
+		if err := json.Unmarshal(frame, &request); err != nil {
+			return fmt.Errorf("decode json: %w", err)
+		}
+
+		if event.Type == domain.EventTypeReactionAdd {
+			if err := ws.reactionUseCase.Add(ctx, &request); err != nil {
+				ws.writeError(event.Type, err)
+				return fmt.Errorf("add reaction: %w", err)
+			}
+		} else if err := ws.reactionUseCase.Remove(ctx, &request); err != nil {
+			ws.writeError(event.Type, err)
+			return fmt.Errorf("remove reaction: %w", err)
+		}
+	case domain.EventTypeTypingStart, domain.EventTypeTypingStop:
+		var typing domain.TypingRequest
+
+		if err := json.Unmarshal(frame, &typing); err != nil {
+			return fmt.Errorf("decode json: %w", err)
+		}
+
+		if event.Type == domain.EventTypeTypingStart {
+			if err := ws.typingUseCase.Start(ctx, &typing); err != nil {
+				ws.writeError(event.Type, err)
+				return fmt.Errorf("start typing: %w", err)
+			}
+		} else if err := ws.typingUseCase.Stop(ctx, &typing); err != nil {
+			ws.writeError(event.Type, err)
+			return fmt.Errorf("stop typing: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown event type: %s", event.Type)
 	}
+
+	return nil
 }
 
 // func (ws *chatWS) writeToClient(ctx context.Context) {
@@ -162,10 +292,76 @@ func (ws *chatWS) ping(ctx context.Context) {
 // 	}
 // }
 
+// Only throttling, suspensions, invalid and rejected messages are explained,
+// any other failure, blocks included, has the same generic frame
+func (ws *chatWS) writeError(eventType string, err error) {
+	var (
+		rateLimitErr *domain.RateLimitError
+		frame        domain.ErrorFrame
+	)
+
+	switch {
+	case errors.As(err, &rateLimitErr):
+		frame = domain.ErrorFrame{
+			Code:         domain.ErrorCodeRateLimited,
+			Message:      fmt.Sprintf("too many %s requests", rateLimitErr.Action),
+			RetryAfterMs: rateLimitErr.RetryAfter.Milliseconds(),
+		}
+	case errors.Is(err, domain.ErrEmptyMessage),
+		errors.Is(err, domain.ErrMessageTooLong),
+		errors.Is(err, domain.ErrInvalidEncoding),
+		errors.Is(err, domain.ErrInvalidRecipient),
+		errors.Is(err, domain.ErrRecipientNotFound):
+		frame = domain.ErrorFrame{
+			Code:    domain.ErrorCodeInvalidMessage,
+			Message: err.Error(),
+		}
+	case errors.Is(err, domain.ErrUserSuspended):
+		frame = domain.ErrorFrame{
+			Code:    domain.ErrorCodeSuspended,
+			Message: err.Error(),
+		}
+	case errors.Is(err, domain.ErrMessageRejected):
+		// which rule matched isn't told, so it can't be worked around
+		frame = domain.ErrorFrame{
+			Code:    domain.ErrorCodeRejected,
+			Message: err.Error(),
+		}
+	default:
+		frame = domain.ErrorFrame{
+			Code:    domain.ErrorCodeFailed,
+			Message: domain.ErrBlocked.Error(),
+		}
+	}
+
+	frame.Event = eventType
+
+	ws.websocketWriteBuffer.Write(domain.NewEvent(domain.EventTypeError, frame))
+}
+
 func logGoroutineDone(name string) {
 	log.Printf("%s goroutine done", name)
 }
 
+// Safe to call while reading and writing, reading then
+// fails and ends the connection
+func (ws *chatWS) Disconnect(code int, reason string) {
+	err := ws.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second),
+	)
+	if err != nil && err != websocket.ErrCloseSent {
+		log.Printf("err: send close message: %s", err)
+	}
+
+	ws.conn.Close()
+}
+
+func (ws *chatWS) Send(event json.RawMessage) {
+	ws.websocketWriteBuffer.Write(event)
+}
+
 func (ws *chatWS) close() {
 	ws.conn.Close()
 }
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type DeadLetter struct {
+	queue domain.DeadLetterQueue
+}
+
+func (h *DeadLetter) List(c *gin.Context) {
+	params, ok := bindDeadLetterRequest(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.queue.Count(c.Request.Context())
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	deadLetters, err := h.queue.List(c.Request.Context(), params.Limit)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if deadLetters == nil {
+		deadLetters = []domain.DeadLetter{}
+	}
+
+	c.JSON(http.StatusOK, domain.DeadLetterResponse{
+		Count:       count,
+		DeadLetters: deadLetters,
+	})
+}
+
+func (h *DeadLetter) Replay(c *gin.Context) {
+	params, ok := bindDeadLetterRequest(c)
+	if !ok {
+		return
+	}
+
+	replayed, err := h.queue.Replay(c.Request.Context(), params.Limit)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.DeadLetterReplayResponse{
+		Replayed: replayed,
+	})
+}
+
+func bindDeadLetterRequest(c *gin.Context) (domain.DeadLetterRequest, bool) {
+	var params domain.DeadLetterRequest
+	if err := c.ShouldBindQuery(&params); err != nil || params.Limit < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return params, false
+	}
+
+	if params.Limit == 0 || params.Limit > domain.MaxDeadLetterLimit {
+		params.Limit = domain.DefaultDeadLetterLimit
+	}
+
+	return params, true
+}
+
+func NewDeadLetter(queue domain.DeadLetterQueue) *DeadLetter {
+	return &DeadLetter{
+		queue: queue,
+	}
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/http/middleware"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+type Report struct {
+	chatStreamFactory domain.ChatStreamFactory
+	chatRepository    domain.ChatRepository
+	blobStore         domain.BlobStore
+	reportRepository  domain.ReportRepository
+	userRepository    domain.UserRepository
+	controlPlane      domain.ControlPlane
+	uidGenerator      domain.UIDGenerator
+}
+
+func (h *Report) Create(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.ReportMessageRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	request.MessageID = id
+	request.From = middleware.GetUserIDFromContext(c)
+
+	report, err := use_case.NewReportMessage(
+		h.chatRepository,
+		h.reportRepository,
+		h.uidGenerator,
+	).Execute(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.ReportMessageResponse{
+		ID:     report.ID,
+		Status: report.Status,
+	})
+}
+
+func (h *Report) List(c *gin.Context) {
+	var params domain.ListReportsRequest
+	if err := c.ShouldBindQuery(&params); err != nil || params.Limit < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if params.Status == "" {
+		params.Status = domain.ReportStatusOpen
+	}
+
+	if !slices.Contains([]string{
+		domain.ReportStatusOpen,
+		domain.ReportStatusTriaged,
+		domain.ReportStatusDismissed,
+		domain.ReportStatusResolved,
+	}, params.Status) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if params.Limit == 0 || params.Limit > domain.MaxReportLimit {
+		params.Limit = domain.DefaultReportLimit
+	}
+
+	reports, err := h.reportRepository.ListReports(c.Request.Context(), params.Status, params.Limit)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if reports == nil {
+		reports = []domain.Report{}
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+func (h *Report) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reportRepository.GetReport(c.Request.Context(), id)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Report) Triage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.TriageReportRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.ID = id
+
+	// triaging doesn't dispatch events
+	report, err := use_case.NewModerateReport(
+		nil,
+		h.chatRepository,
+		h.blobStore,
+		h.reportRepository,
+		h.userRepository,
+		nil,
+	).Triage(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Report) Act(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.ReportActionRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.ID = id
+
+	chatStream, err := h.chatStreamFactory.NewChatDispatcher()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer chatStream.Close()
+
+	report, err := use_case.NewModerateReport(
+		chatStream,
+		h.chatRepository,
+		h.blobStore,
+		h.reportRepository,
+		h.userRepository,
+		h.controlPlane,
+	).Act(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func NewReport(
+	chatStreamFactory domain.ChatStreamFactory,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	reportRepository domain.ReportRepository,
+	userRepository domain.UserRepository,
+	controlPlane domain.ControlPlane,
+	uidGenerator domain.UIDGenerator,
+) *Report {
+	return &Report{
+		chatStreamFactory: chatStreamFactory,
+		chatRepository:    chatRepository,
+		blobStore:         blobStore,
+		reportRepository:  reportRepository,
+		userRepository:    userRepository,
+		controlPlane:      controlPlane,
+		uidGenerator:      uidGenerator,
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+type Review struct {
+	chatStreamFactory domain.ChatStreamFactory
+	chatRepository    domain.ChatRepository
+	blobStore         domain.BlobStore
+	reviewRepository  domain.ReviewRepository
+}
+
+func (h *Review) List(c *gin.Context) {
+	var params domain.ListReviewsRequest
+	if err := c.ShouldBindQuery(&params); err != nil || params.Limit < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if params.Status == "" {
+		params.Status = domain.ReviewStatusPending
+	}
+
+	if !slices.Contains([]string{
+		domain.ReviewStatusPending,
+		domain.ReviewStatusApproved,
+		domain.ReviewStatusRemoved,
+	}, params.Status) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if params.Limit == 0 || params.Limit > domain.MaxReviewLimit {
+		params.Limit = domain.DefaultReviewLimit
+	}
+
+	reviews, err := h.reviewRepository.ListReviews(c.Request.Context(), params.Status, params.Limit)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	if reviews == nil {
+		reviews = []domain.Review{}
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+func (h *Review) Decide(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("messageId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var request domain.ReviewDecisionRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	request.MessageID = id
+
+	chatStream, err := h.chatStreamFactory.NewChatDispatcher()
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	defer chatStream.Close()
+
+	review, err := use_case.NewReview(
+		chatStream,
+		h.chatRepository,
+		h.blobStore,
+		h.reviewRepository,
+	).Decide(c.Request.Context(), &request)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func NewReview(
+	chatStreamFactory domain.ChatStreamFactory,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	reviewRepository domain.ReviewRepository,
+) *Review {
+	return &Review{
+		chatStreamFactory: chatStreamFactory,
+		chatRepository:    chatRepository,
+		blobStore:         blobStore,
+		reviewRepository:  reviewRepository,
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/http/middleware"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+type Search struct {
+	index          domain.SearchIndex
+	chatRepository domain.ChatRepository
+}
+
+func (h *Search) Search(c *gin.Context) {
+	var params domain.SearchRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	response, err := use_case.NewSearchMessages(h.index, h.chatRepository).Execute(
+		c.Request.Context(),
+		middleware.GetUserIDFromContext(c),
+		&params,
+	)
+	if err != nil {
+		abortWithInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func NewSearch(index domain.SearchIndex, chatRepository domain.ChatRepository) *Search {
+	return &Search{
+		index:          index,
+		chatRepository: chatRepository,
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Responds 429 with Retry-After, in seconds, to the
+// clients that exceeded the limits of action
+func NewRateLimit(service domain.RateLimitService, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := service.Allow(c.Request.Context(), GetClient(c), action)
+
+		var rateLimitErr *domain.RateLimitError
+
+		if errors.As(err, &rateLimitErr) {
+			seconds := math.Ceil(rateLimitErr.RetryAfter.Seconds())
+
+			c.Header("Retry-After", strconv.Itoa(int(seconds)))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Must run after NewUser
+func GetClient(c *gin.Context) *domain.Client {
+	return &domain.Client{
+		UserID: GetUserIDFromContext(c),
+		IP:     c.ClientIP(),
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Responds 403 to suspended users, for the routes that act
+// on behalf of the user. Must run after NewUser
+func NewSuspension(checkSuspension domain.CheckSuspensionUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := checkSuspension.Execute(c.Request.Context(), GetUserIDFromContext(c))
+
+		if errors.Is(err, domain.ErrUserSuspended) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/http/handler"
+	"github.com/lam0glia/chat-system/http/middleware"
+)
+
+func adminRouter(r gin.IRouter, app *bootstrap.App) {
+	admin := handler.NewAdmin(
+		app.ChatRepository,
+		app.ConversationRepository,
+		app.UserRepository,
+		app.PresenceRepository,
+		app.ConnectionCountRepository,
+		app.ControlPlane,
+		app.RateLimitPolicy,
+	)
+
+	support := r.Group("", middleware.NewRole(domain.AdminRoleSupport))
+	{
+		support.GET("/connections", admin.Connections)
+		support.GET("/users/:userId", admin.GetUser)
+		support.GET("/users/:userId/conversations", admin.ListConversations)
+		support.GET("/users/:userId/conversations/:peerId/messages", admin.ListMessages)
+	}
+
+	review := handler.NewReview(app.Broker, app.ChatRepository, app.BlobStore, app.ReviewRepository)
+
+	report := handler.NewReport(
+		app.Broker,
+		app.ChatRepository,
+		app.BlobStore,
+		app.ReportRepository,
+		app.UserRepository,
+		app.ControlPlane,
+		app.SonyFlake,
+	)
+
+	moderator := r.Group("", middleware.NewRole(domain.AdminRoleModerator))
+	{
+		moderator.PUT("/users/:userId/suspension", admin.Suspend)
+		moderator.DELETE("/users/:userId/suspension", admin.Unsuspend)
+		moderator.POST("/users/:userId/disconnect", admin.Disconnect)
+
+		moderator.GET("/reviews", review.List)
+		moderator.POST("/reviews/:messageId", review.Decide)
+
+		moderator.GET("/reports", report.List)
+		moderator.GET("/reports/:id", report.Get)
+		moderator.PATCH("/reports/:id", report.Triage)
+		moderator.POST("/reports/:id/actions", report.Act)
+	}
+
+	deadLetter := handler.NewDeadLetter(app.DeadLetterQueue)
+
+	operator := r.Group("", middleware.NewRole(domain.AdminRoleAdmin))
+	{
+		operator.PUT("/users/:userId/tier", admin.SetTier)
+
+		operator.GET("/dlq", deadLetter.List)
+		operator.POST("/dlq/replay", deadLetter.Replay)
+	}
+}
@@ -0,0 +1,35 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/http/handler"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+func attachmentRouter(r gin.IRouter, app *bootstrap.App) {
+	chatRepository := app.ChatRepository
+
+	uploadAttachmentUseCase := use_case.NewUploadAttachment(
+		chatRepository,
+		app.BlobStore,
+		app.SonyFlake,
+		app.ThumbnailQueue,
+		app.Env.AttachmentMaxSize,
+		app.Env.AttachmentAllowedTypes,
+		app.Env.AttachmentMaxPixels,
+	)
+
+	h := handler.NewAttachment(
+		chatRepository,
+		app.BlobStore,
+		uploadAttachmentUseCase,
+		app.Env.AttachmentMaxSize,
+	)
+
+	attachments := r.Group("/chat/attachments")
+
+	attachments.POST("", h.Upload)
+	attachments.GET("/:id", h.Download)
+	attachments.GET("/:id/thumbnails/:size", h.DownloadThumbnail)
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/http/handler"
+)
+
+func blockRouter(r gin.IRouter, app *bootstrap.App) {
+	h := handler.NewBlock(app.BlockRepository)
+
+	blocks := r.Group("/chat/blocks")
+
+	blocks.GET("", h.List)
+	blocks.POST("", h.Create)
+	blocks.DELETE("/:userId", h.Delete)
+}
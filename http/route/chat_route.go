@@ -3,31 +3,73 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/lam0glia/chat-system/bootstrap"
-	"github.com/lam0glia/chat-system/event"
+	"github.com/lam0glia/chat-system/domain"
 	"github.com/lam0glia/chat-system/http/handler"
-	"github.com/lam0glia/chat-system/repository"
+	"github.com/lam0glia/chat-system/http/middleware"
 	"github.com/lam0glia/chat-system/service"
-	"github.com/lam0glia/chat-system/websocket_buffer"
+	"github.com/lam0glia/chat-system/use_case"
 )
 
-func chatRouter(r gin.IRouter, app *bootstrap.App) {
-	chatRepository := repository.NewChat(app.CassandraSession)
-	messageBroker := event.NewRabbitMQ(app.RabbitMQConnection)
-	presenceRepository := repository.NewPresence(app.RedisClient)
-	presenceService := service.NewPresence(presenceRepository)
-	writeBuffer := &websocket_buffer.WriteBuffer{}
+func chatRouter(r gin.IRouter, app *bootstrap.App, connections domain.ConnectionRegistry) {
+	chatRepository := app.ChatRepository
+	presenceService := service.NewPresence(app.PresenceRepository, app.BlockRepository)
+	rateLimitService := service.NewRateLimit(app.RateLimiter, app.RateLimitPolicy, app.UserRepository)
 
 	h := handler.NewChat(
-		app.RabbitMQConnection,
+		app.Broker,
 		app.SonyFlake,
 		chatRepository,
-		messageBroker,
+		app.BlobStore,
+		app.BlockRepository,
+		app.ConversationRepository,
+		rateLimitService,
+		app.UserRepository,
+		app.Moderator,
+		app.ReviewRepository,
+		app.Broker,
 		presenceService,
-		writeBuffer,
+		connections,
+		app.ControlPlane,
+		app.Env.MessageEditWindow,
+		app.Env.MessageMaxLength,
+		app.Env.WebsocketReadLimit,
+	)
+
+	report := handler.NewReport(
+		app.Broker,
+		chatRepository,
+		app.BlobStore,
+		app.ReportRepository,
+		app.UserRepository,
+		app.ControlPlane,
+		app.SonyFlake,
 	)
 
 	chat := r.Group("/chat")
 
-	chat.GET("/ws", h.WebSocket)
+	chat.GET(
+		"/ws",
+		middleware.NewRateLimit(rateLimitService, domain.RateLimitActionConnect),
+		h.WebSocket,
+	)
+	limitEvents := middleware.NewRateLimit(rateLimitService, domain.RateLimitActionEvent)
+
+	// the websocket refuses them on its own
+	notSuspended := middleware.NewSuspension(use_case.NewCheckSuspension(app.UserRepository))
+
 	chat.GET("/messages", h.ListMessages)
+	chat.PATCH("/messages/:id", notSuspended, limitEvents, h.EditMessage)
+	chat.DELETE("/messages/:id", notSuspended, limitEvents, h.DeleteMessage)
+	chat.POST("/messages/:id/reactions", notSuspended, limitEvents, h.AddReaction)
+	chat.DELETE("/messages/:id/reactions", notSuspended, limitEvents, h.RemoveReaction)
+	chat.GET("/messages/:id/edits", h.ListMessageEdits)
+	chat.GET("/messages/:id/thread", h.ListThread)
+	chat.POST(
+		"/messages/:id/report",
+		notSuspended,
+		middleware.NewRateLimit(rateLimitService, domain.RateLimitActionReport),
+		report.Create,
+	)
+	chat.GET("/conversations", h.ListConversations)
+	chat.PATCH("/conversations/:peerId", notSuspended, h.UpdateConversation)
 }
@@ -0,0 +1,13 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/http/handler"
+)
+
+func searchRouter(r gin.IRouter, app *bootstrap.App) {
+	h := handler.NewSearch(app.SearchIndex, app.ChatRepository)
+
+	r.GET("/chat/search", h.Search)
+}
@@ -3,12 +3,14 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/lam0glia/chat-system/bootstrap"
+	"github.com/lam0glia/chat-system/domain"
 	"github.com/lam0glia/chat-system/http/middleware"
 )
 
 const v1Prefix = "/v1"
 
-func Setup(app *bootstrap.App) *gin.Engine {
+// connections are the websockets open on this node
+func Setup(app *bootstrap.App, connections domain.ConnectionRegistry) *gin.Engine {
 	if app.Env.EnvironmentName == bootstrap.ProductionEnvironmentName {
 		gin.SetMode(gin.ReleaseMode)
 	} else {
@@ -21,7 +23,15 @@ func Setup(app *bootstrap.App) *gin.Engine {
 
 	v1 := eng.Group(v1Prefix, middleware.NewUser)
 	{
-		chatRouter(v1, app)
+		chatRouter(v1, app, connections)
+		attachmentRouter(v1, app)
+		searchRouter(v1, app)
+		blockRouter(v1, app)
+	}
+
+	admin := eng.Group("/admin", middleware.NewAdmin(app.Env.AdminRoles()))
+	{
+		adminRouter(admin, app)
 	}
 
 	return eng
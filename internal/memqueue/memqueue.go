@@ -0,0 +1,239 @@
+// Package memqueue is an in-process stand-in for the subset of
+// RabbitMQ the service relies on: named queues, the default
+// exchange routing by queue name, fanout exchanges and per
+// message expiration. Nothing survives a restart.
+package memqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type item struct {
+	body []byte
+	// zero if it never expires
+	expiresAt time.Time
+}
+
+type Queue struct {
+	mu    sync.Mutex
+	items []item
+	// signaled when an item is pushed
+	ready chan struct{}
+}
+
+func newQueue() *Queue {
+	return &Queue{
+		ready: make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) push(body []byte, ttl time.Duration) {
+	var expiresAt time.Time
+
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	q.mu.Lock()
+	q.items = append(q.items, item{body: body, expiresAt: expiresAt})
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// Puts a body that failed to be handled back in the queue
+func (q *Queue) Requeue(body []byte) {
+	q.push(body, 0)
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Blocks until a body is available or ctx is canceled,
+// in which case it returns false. Expired bodies are dropped
+func (q *Queue) Get(ctx context.Context) ([]byte, bool) {
+	for {
+		if body, ok := q.pop(); ok {
+			return body, true
+		}
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (q *Queue) pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	for len(q.items) > 0 {
+		next := q.items[0]
+		q.items = q.items[1:]
+
+		if !next.expiresAt.IsZero() && now.After(next.expiresAt) {
+			continue
+		}
+
+		// wake up other consumers waiting for the remaining ones
+		if len(q.items) > 0 {
+			q.signal()
+		}
+
+		return next.body, true
+	}
+
+	return nil, false
+}
+
+// Body a consumer rejected
+type Letter struct {
+	// Increasing, never reused
+	Seq uint64
+	// Queue or exchange it was consumed from
+	Source     string
+	Reason     string
+	Body       []byte
+	RejectedAt time.Time
+}
+
+type DeadLetters struct {
+	mu      sync.Mutex
+	lastSeq uint64
+	letters []Letter
+}
+
+func (d *DeadLetters) Add(source string, body []byte, reason error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastSeq++
+
+	d.letters = append(d.letters, Letter{
+		Seq:        d.lastSeq,
+		Source:     source,
+		Reason:     reason.Error(),
+		Body:       body,
+		RejectedAt: time.Now().UTC(),
+	})
+}
+
+// Oldest first, without removing them
+func (d *DeadLetters) List(limit int) []Letter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Letter(nil), d.letters[:min(limit, len(d.letters))]...)
+}
+
+// Removes and returns the oldest ones
+func (d *DeadLetters) Take(limit int) []Letter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := min(limit, len(d.letters))
+	taken := append([]Letter(nil), d.letters[:n]...)
+
+	d.letters = d.letters[n:]
+
+	return taken
+}
+
+func (d *DeadLetters) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.letters)
+}
+
+type Broker struct {
+	mu     sync.Mutex
+	queues map[string]*Queue
+	// queues bound to each fanout exchange
+	bindings    map[string]map[*Queue]struct{}
+	deadLetters DeadLetters
+}
+
+func (b *Broker) DeadLetters() *DeadLetters {
+	return &b.deadLetters
+}
+
+// Declares the queue if it doesn't exist yet
+func (b *Broker) Queue(name string) *Queue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = newQueue()
+		b.queues[name] = q
+	}
+
+	return q
+}
+
+// Queue only reachable through the exchanges it is bound to,
+// call Unbind to discard it
+func (b *Broker) AnonymousQueue() *Queue {
+	return newQueue()
+}
+
+func (b *Broker) Bind(exchange string, q *Queue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.bindings[exchange] == nil {
+		b.bindings[exchange] = make(map[*Queue]struct{})
+	}
+
+	b.bindings[exchange][q] = struct{}{}
+}
+
+func (b *Broker) Unbind(exchange string, q *Queue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.bindings[exchange], q)
+}
+
+// An empty exchange routes to the queue named key, dropping the
+// body if it was never declared. Any other exchange is a fanout.
+// A ttl of zero keeps the body until it is consumed
+func (b *Broker) Publish(exchange, key string, body []byte, ttl time.Duration) {
+	b.mu.Lock()
+
+	var targets []*Queue
+
+	if exchange == "" {
+		if q, ok := b.queues[key]; ok {
+			targets = append(targets, q)
+		}
+	} else {
+		for q := range b.bindings[exchange] {
+			targets = append(targets, q)
+		}
+	}
+
+	b.mu.Unlock()
+
+	for _, q := range targets {
+		q.push(body, ttl)
+	}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		queues:   make(map[string]*Queue),
+		bindings: make(map[string]map[*Queue]struct{}),
+	}
+}
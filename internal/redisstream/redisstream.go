@@ -0,0 +1,137 @@
+// Package redisstream reads a Redis stream through a consumer
+// group, first the entries delivered to the consumer but never
+// acknowledged and then the new ones.
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Blocking reads are not interrupted by the context,
+// so it is checked at least this often
+const blockDuration = 5 * time.Second
+
+// Creates the group and the stream if they don't exist yet,
+// start is the id after which the group reads, "$" for new entries only
+func EnsureGroup(ctx context.Context, client *redis.Client, stream, group, start string) error {
+	err := client.XGroupCreateMkStream(ctx, stream, group, start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+type Reader struct {
+	client   *redis.Client
+	stream   string
+	group    string
+	consumer string
+	// read the pending entries of the consumer before the new ones,
+	// after the last one delivered
+	pending bool
+	lastID  string
+	buffer  []redis.XMessage
+	// zero keeps the stream forever
+	expiry time.Duration
+}
+
+func NewReader(client *redis.Client, stream, group, consumer string) *Reader {
+	return &Reader{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		pending:  true,
+		lastID:   "0",
+	}
+}
+
+// Renews the expiration of the stream before each read, so it is
+// only deleted once it is neither read nor written for that long
+func (r *Reader) KeepAlive(expiry time.Duration) {
+	r.expiry = expiry
+}
+
+// Blocks until an entry is available or ctx is canceled,
+// in which case it returns ctx.Err()
+func (r *Reader) Next(ctx context.Context) (redis.XMessage, error) {
+	for len(r.buffer) == 0 {
+		if err := ctx.Err(); err != nil {
+			return redis.XMessage{}, err
+		}
+
+		if r.expiry > 0 {
+			if err := r.client.PExpire(ctx, r.stream, r.expiry).Err(); err != nil {
+				return redis.XMessage{}, fmt.Errorf("renew expiration: %w", err)
+			}
+		}
+
+		start := ">"
+		if r.pending {
+			start = r.lastID
+		}
+
+		streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.group,
+			Consumer: r.consumer,
+			Streams:  []string{r.stream, start},
+			Count:    1,
+			Block:    blockDuration,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return redis.XMessage{}, ctx.Err()
+			}
+
+			return redis.XMessage{}, fmt.Errorf("read group: %w", err)
+		}
+
+		for _, stream := range streams {
+			r.buffer = append(r.buffer, stream.Messages...)
+		}
+
+		if !r.pending {
+			continue
+		}
+
+		// the pending entries were all delivered again
+		if len(r.buffer) == 0 {
+			r.pending = false
+		} else {
+			r.lastID = r.buffer[len(r.buffer)-1].ID
+		}
+	}
+
+	next := r.buffer[0]
+	r.buffer = r.buffer[1:]
+
+	return next, nil
+}
+
+// Acknowledges and removes the entry, so a stream read
+// by a single group doesn't grow
+func (r *Reader) Done(ctx context.Context, id string) error {
+	pipe := r.client.TxPipeline()
+
+	pipe.XAck(ctx, r.stream, r.group, id)
+	pipe.XDel(ctx, r.stream, id)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Acknowledges the entry, which stays in the stream
+// for the other groups
+func (r *Reader) Ack(ctx context.Context, id string) error {
+	return r.client.XAck(ctx, r.stream, r.group, id).Err()
+}
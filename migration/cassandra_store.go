@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+type cassandraStore struct {
+	db *gocql.Session
+}
+
+func (s *cassandraStore) createTable(ctx context.Context) error {
+	return s.db.Query(
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version int,
+			name text,
+			applied_at TIMESTAMP,
+			PRIMARY KEY (version)
+		)`,
+	).WithContext(ctx).Exec()
+}
+
+func (s *cassandraStore) applied(ctx context.Context) (map[int]time.Time, error) {
+	scanner := s.db.Query(
+		"SELECT version, applied_at FROM schema_migrations",
+	).WithContext(ctx).Iter().Scanner()
+
+	applied := make(map[int]time.Time)
+
+	for scanner.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+
+		if err := scanner.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return applied, nil
+}
+
+// Cassandra can't apply a file atomically, so the statements
+// must be idempotent for a failed migration to be retried
+func (s *cassandraStore) apply(ctx context.Context, migration *Migration) error {
+	for _, statement := range migration.Statements {
+		if err := s.db.Query(statement).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+
+	// later migrations may depend on the ones just applied
+	if err := s.db.AwaitSchemaAgreement(ctx); err != nil {
+		return fmt.Errorf("await schema agreement: %w", err)
+	}
+
+	if err := s.db.Query(
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		migration.Version,
+		migration.Name,
+		time.Now(),
+	).WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	return nil
+}
+
+func NewCassandraMigrator(session *gocql.Session) (*migrator, error) {
+	return newMigrator(&cassandraStore{db: session}, "cql")
+}
@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are applied in version order and must be idempotent
+// (IF NOT EXISTS), since Cassandra can't apply a file atomically
+// and a failed run is simply retried.
+//
+//go:embed cql/*.cql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// <version>_<name>.cql or <version>_<name>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(cql|sql)$`)
+
+var ErrSchemaMismatch = errors.New("schema is not up to date")
+
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Embedded migrations of the directory sorted by version
+func load(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	var migrations []Migration
+
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("parse version of %q: %w", entry.Name(), err)
+		}
+
+		content, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       match[2],
+			Statements: splitStatements(string(content)),
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicated migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// The driver executes a single statement per query, so files
+// are split on semicolons. Lines starting with "--" are comments
+func splitStatements(content string) []string {
+	var lines []string
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	var statements []string
+
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		statement = strings.TrimSpace(statement)
+
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements
+}
+
+type Migrator interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Status(ctx context.Context) ([]Status, error)
+	Check(ctx context.Context) error
+}
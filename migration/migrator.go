@@ -0,0 +1,134 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Database specific bookkeeping of the migrations
+type store interface {
+	createTable(ctx context.Context) error
+	// Applied versions and when they were applied
+	applied(ctx context.Context) (map[int]time.Time, error)
+	// Runs the statements and records the version
+	apply(ctx context.Context, migration *Migration) error
+}
+
+type migrator struct {
+	store      store
+	migrations []Migration
+}
+
+// Applies the pending migrations and returns them
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.store.createTable(ctx); err != nil {
+		return nil, fmt.Errorf("create migrations table: %w", err)
+	}
+
+	applied, err := m.store.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	var done []Migration
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Printf("Applying migration %d_%s...", migration.Version, migration.Name)
+
+		if err = m.store.apply(ctx, &migration); err != nil {
+			return done, fmt.Errorf(
+				"apply migration %d_%s: %w",
+				migration.Version,
+				migration.Name,
+				err,
+			)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Every known migration and, for the applied ones, when
+// it happened. Versions applied by a newer binary are
+// listed without a name
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.store.createTable(ctx); err != nil {
+		return nil, fmt.Errorf("create migrations table: %w", err)
+	}
+
+	applied, err := m.store.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	var statuses []Status
+
+	for _, migration := range m.migrations {
+		status := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for version, appliedAt := range applied {
+		statuses = append(statuses, Status{
+			Version:   version,
+			AppliedAt: &appliedAt,
+		})
+	}
+
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return a.Version - b.Version
+	})
+
+	return statuses, nil
+}
+
+// Fails with ErrSchemaMismatch when the applied migrations
+// differ from the ones embedded in this binary
+func (m *migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			return fmt.Errorf("%w: migration %d_%s is pending", ErrSchemaMismatch, status.Version, status.Name)
+		}
+
+		if status.Name == "" {
+			return fmt.Errorf("%w: migration %d is unknown to this build", ErrSchemaMismatch, status.Version)
+		}
+	}
+
+	return nil
+}
+
+func newMigrator(store store, dir string) (*migrator, error) {
+	migrations, err := load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+
+	return &migrator{
+		store:      store,
+		migrations: migrations,
+	}, nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE reviews (
+    message_id BIGINT PRIMARY KEY,
+    from_id BIGINT NOT NULL,
+    to_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    flagged_at TIMESTAMPTZ NOT NULL,
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX reviews_status_idx ON reviews (status, flagged_at, message_id);
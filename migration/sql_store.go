@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	PostgresDialect = "postgres"
+	SQLiteDialect   = "sqlite"
+)
+
+type sqlStore struct {
+	db *sql.DB
+}
+
+func (s *sqlStore) createTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`,
+	)
+
+	return err
+}
+
+func (s *sqlStore) applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// Both databases have transactional DDL, so a migration
+// is either fully applied or not at all
+func (s *sqlStore) apply(ctx context.Context, migration *Migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	for _, statement := range migration.Statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		migration.Version,
+		migration.Name,
+		time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Migrations of dialect, postgres or sqlite
+func NewSQLMigrator(db *sql.DB, dialect string) (*migrator, error) {
+	return newMigrator(&sqlStore{db: db}, dialect)
+}
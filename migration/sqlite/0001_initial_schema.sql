@@ -0,0 +1,59 @@
+CREATE TABLE messages (
+    pair TEXT NOT NULL,
+    id BIGINT NOT NULL,
+    from_id BIGINT NOT NULL,
+    to_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    reply_to BIGINT,
+    last_reply_at TIMESTAMP,
+    reply_count INTEGER NOT NULL DEFAULT 0,
+    -- JSON array, keeps the order they were sent
+    attachment_ids TEXT NOT NULL DEFAULT '[]',
+    PRIMARY KEY (pair, id)
+);
+
+CREATE INDEX messages_thread_idx ON messages (pair, reply_to, id) WHERE reply_to IS NOT NULL;
+
+CREATE TABLE message_edits (
+    pair TEXT NOT NULL,
+    message_id BIGINT NOT NULL,
+    edited_at TIMESTAMP NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (pair, message_id, edited_at)
+);
+
+CREATE TABLE hidden_messages (
+    user_id BIGINT NOT NULL,
+    pair TEXT NOT NULL,
+    message_id BIGINT NOT NULL,
+    PRIMARY KEY (user_id, pair, message_id)
+);
+
+CREATE TABLE reactions (
+    pair TEXT NOT NULL,
+    message_id BIGINT NOT NULL,
+    emoji TEXT NOT NULL,
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (pair, message_id, emoji, user_id)
+);
+
+CREATE TABLE attachments (
+    id INTEGER PRIMARY KEY,
+    uploader_id BIGINT NOT NULL,
+    peer_id BIGINT NOT NULL,
+    message_id BIGINT,
+    name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    checksum TEXT NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    blob_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    -- JSON array of thumbnails
+    thumbnails TEXT NOT NULL DEFAULT '[]',
+    placeholder TEXT NOT NULL DEFAULT ''
+);
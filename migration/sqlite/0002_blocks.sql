@@ -0,0 +1,8 @@
+CREATE TABLE blocks (
+    user_id BIGINT NOT NULL,
+    blocked_id BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, blocked_id)
+);
+
+CREATE INDEX blocks_blocked_idx ON blocks (blocked_id, user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE conversations (
+    user_id BIGINT NOT NULL,
+    peer_id BIGINT NOT NULL,
+    last_message_id BIGINT,
+    last_message_at TIMESTAMP,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    muted_until TIMESTAMP,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned_at TIMESTAMP,
+    PRIMARY KEY (user_id, peer_id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE users (
+    id BIGINT PRIMARY KEY,
+    first_seen_at TIMESTAMP NOT NULL
+);
+
+-- participants of the existing conversations
+INSERT INTO users (id, first_seen_at)
+SELECT id, MIN(created_at) FROM (
+    SELECT from_id AS id, created_at FROM messages
+    UNION ALL
+    SELECT to_id AS id, created_at FROM messages
+) participants
+GROUP BY id;
@@ -0,0 +1,25 @@
+CREATE TABLE reports (
+    id BIGINT PRIMARY KEY,
+    message_id BIGINT NOT NULL,
+    reporter_id BIGINT NOT NULL,
+    reported_id BIGINT NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    details TEXT NOT NULL,
+    -- JSON arrays
+    context TEXT NOT NULL,
+    actions TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (message_id, reporter_id)
+);
+
+CREATE INDEX reports_status_idx ON reports (status, created_at, id);
+
+CREATE TABLE suspensions (
+    user_id BIGINT PRIMARY KEY,
+    reason TEXT NOT NULL,
+    suspended_at TIMESTAMP NOT NULL,
+    suspended_until TIMESTAMP
+);
@@ -0,0 +1,4 @@
+CREATE TABLE user_tiers (
+    user_id BIGINT PRIMARY KEY,
+    tier VARCHAR(32) NOT NULL
+);
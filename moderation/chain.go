@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Runs the moderators in order, each one sees the content redacted
+// by the previous ones. The most severe action wins and a rejection
+// stops the chain
+type chain struct {
+	moderators []domain.Moderator
+}
+
+func (m *chain) Moderate(ctx context.Context, message *domain.Message) (*domain.ModerationVerdict, error) {
+	verdict := domain.ModerationVerdict{
+		Action:  domain.ModerationActionAllow,
+		Content: message.Content,
+	}
+
+	var reasons []string
+
+	for i, moderator := range m.moderators {
+		current := *message
+		current.Content = verdict.Content
+
+		v, err := moderator.Moderate(ctx, &current)
+		if err != nil {
+			return nil, fmt.Errorf("moderator %d: %w", i, err)
+		}
+
+		if domain.ModerationActionExceeds(v.Action, verdict.Action) {
+			verdict.Action = v.Action
+		}
+
+		// a moderator may redact and flag at once
+		verdict.Content = v.Content
+
+		if v.Reason != "" {
+			reasons = append(reasons, v.Reason)
+		}
+
+		if v.Action == domain.ModerationActionReject {
+			break
+		}
+	}
+
+	verdict.Reason = strings.Join(reasons, "; ")
+
+	return &verdict, nil
+}
+
+func NewChain(moderators ...domain.Moderator) *chain {
+	return &chain{
+		moderators: moderators,
+	}
+}
@@ -0,0 +1,141 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Rule of the rules file, words are matched as whole
+// words regardless of case, patterns as they are
+type Rule struct {
+	Action   string   `json:"action"`
+	Words    []string `json:"words"`
+	Patterns []string `json:"patterns"`
+	Reason   string   `json:"reason"`
+}
+
+type compiledRule struct {
+	action  string
+	reason  string
+	matcher *regexp.Regexp
+}
+
+// Moderates with word lists and regular expressions, redact rules
+// mask what they match and the others act on the whole message
+type rules struct {
+	rules []compiledRule
+}
+
+func (m *rules) Moderate(ctx context.Context, message *domain.Message) (*domain.ModerationVerdict, error) {
+	verdict := domain.ModerationVerdict{
+		Action:  domain.ModerationActionAllow,
+		Content: message.Content,
+	}
+
+	var reasons []string
+
+	for _, rule := range m.rules {
+		if !rule.matcher.MatchString(verdict.Content) {
+			continue
+		}
+
+		if rule.action == domain.ModerationActionRedact {
+			verdict.Content = rule.matcher.ReplaceAllStringFunc(verdict.Content, mask)
+		}
+
+		if domain.ModerationActionExceeds(rule.action, verdict.Action) {
+			verdict.Action = rule.action
+		}
+
+		if rule.reason != "" {
+			reasons = append(reasons, rule.reason)
+		}
+	}
+
+	verdict.Reason = strings.Join(reasons, "; ")
+
+	return &verdict, nil
+}
+
+func mask(match string) string {
+	return strings.Repeat("*", len([]rune(match)))
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if !domain.ModerationActionExceeds(rule.Action, domain.ModerationActionAllow) {
+		return nil, fmt.Errorf("invalid action %q", rule.Action)
+	}
+
+	var expressions []string
+
+	if len(rule.Words) > 0 {
+		words := make([]string, len(rule.Words))
+
+		for i, word := range rule.Words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+
+		expressions = append(expressions, `(?i)\b(?:`+strings.Join(words, "|")+`)\b`)
+	}
+
+	for _, pattern := range rule.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("compile pattern %q: %w", pattern, err)
+		}
+
+		expressions = append(expressions, "(?:"+pattern+")")
+	}
+
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("no words nor patterns")
+	}
+
+	matcher, err := regexp.Compile(strings.Join(expressions, "|"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &compiledRule{
+		action:  rule.Action,
+		reason:  rule.Reason,
+		matcher: matcher,
+	}, nil
+}
+
+func NewRules(rs []Rule) (*rules, error) {
+	compiled := make([]compiledRule, len(rs))
+
+	for i, rule := range rs {
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		compiled[i] = *c
+	}
+
+	return &rules{
+		rules: compiled,
+	}, nil
+}
+
+// Reads the rules from a JSON array
+func NewRulesFromFile(path string) (*rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+
+	var rs []Rule
+	if err = json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("json decode rules: %w", err)
+	}
+
+	return NewRules(rs)
+}
@@ -0,0 +1,84 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Body posted to the webhook
+type WebhookRequest struct {
+	ID      uint64 `json:"id"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Content string `json:"content"`
+}
+
+// Delegates to an external service, which answers with the
+// verdict. The content is kept when the answer has none
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func (m *webhook) Moderate(ctx context.Context, message *domain.Message) (*domain.ModerationVerdict, error) {
+	body, err := json.Marshal(WebhookRequest{
+		ID:      message.ID,
+		From:    message.FromID,
+		To:      message.ToID,
+		Content: message.Content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("json encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook responded %s", res.Status)
+	}
+
+	var verdict domain.ModerationVerdict
+	if err = json.NewDecoder(res.Body).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("json decode verdict: %w", err)
+	}
+
+	switch verdict.Action {
+	case domain.ModerationActionAllow, domain.ModerationActionFlag, domain.ModerationActionReject:
+		verdict.Content = message.Content
+	case domain.ModerationActionRedact:
+		if verdict.Content == "" {
+			return nil, fmt.Errorf("redact verdict without content")
+		}
+	default:
+		return nil, fmt.Errorf("invalid action %q", verdict.Action)
+	}
+
+	return &verdict, nil
+}
+
+func NewWebhook(url string, timeout time.Duration) *webhook {
+	return &webhook{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// How often the buckets that refilled are removed
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	period time.Duration
+}
+
+// Buckets of a single process, the limits aren't
+// shared with other nodes
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+func (l *memoryLimiter) Take(ctx context.Context, key string, limit domain.RateLimit) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(limit.Requests)
+
+	if now.Sub(l.sweptAt) >= memorySweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, at: now}
+		l.buckets[key] = b
+	}
+
+	b.period = limit.Period
+	b.tokens = math.Min(capacity, b.tokens+capacity*float64(now.Sub(b.at))/float64(limit.Period))
+	b.at = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, nil
+	}
+
+	return time.Duration(math.Ceil((1 - b.tokens) * float64(limit.Period) / capacity)), nil
+}
+
+// A bucket untouched for its period is full again,
+// the same as the one created when it's missing
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.at) >= b.period {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.sweptAt = now
+}
+
+func NewMemory() *memoryLimiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+	}
+}
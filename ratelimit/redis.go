@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Refills the bucket for the time elapsed since the last take, using
+// the clock of redis so every node agrees, and takes a token. Returns
+// the milliseconds until a token is available, zero when taken
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(bucket[1]) or capacity
+local at = tonumber(bucket[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - at) * capacity / period)
+
+local retry = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	retry = math.ceil((1 - tokens) * period / capacity)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "at", now)
+-- a full bucket is the same as none
+redis.call("PEXPIRE", KEYS[1], period)
+
+return retry
+`)
+
+type redisLimiter struct {
+	client *redis.Client
+}
+
+func (l *redisLimiter) Take(ctx context.Context, key string, limit domain.RateLimit) (time.Duration, error) {
+	retry, err := takeScript.Run(
+		ctx,
+		l.client,
+		[]string{key},
+		limit.Requests,
+		limit.Period.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(retry) * time.Millisecond, nil
+}
+
+func NewRedis(client *redis.Client) *redisLimiter {
+	return &redisLimiter{
+		client: client,
+	}
+}
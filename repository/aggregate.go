@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const pairFormat = "%d.%d"
+
+// Key of the conversation, the same for both participants
+func pairOf(fromID, toID uint64) string {
+	if fromID < toID {
+		return fmt.Sprintf(pairFormat, fromID, toID)
+	} else {
+		return fmt.Sprintf(pairFormat, toID, fromID)
+	}
+}
+
+// Adds the reaction to the counts of its message. Reactions
+// must be ordered by message and emoji, so the same emoji is
+// always the last one counted
+func countReaction(
+	counts map[uint64][]domain.ReactionCount,
+	reaction *domain.Reaction,
+	userID uint64,
+) {
+	messageCounts := counts[reaction.MessageID]
+
+	if n := len(messageCounts); n == 0 || messageCounts[n-1].Emoji != reaction.Emoji {
+		messageCounts = append(messageCounts, domain.ReactionCount{
+			Emoji: reaction.Emoji,
+		})
+	}
+
+	count := &messageCounts[len(messageCounts)-1]
+
+	count.Count++
+
+	if reaction.UserID == userID {
+		count.Reacted = true
+	}
+
+	counts[reaction.MessageID] = messageCounts
+}
+
+// Attachments of the message in the order they were sent
+func messageAttachments(
+	message *domain.Message,
+	attachments map[uint64]domain.Attachment,
+) []domain.Attachment {
+	var result []domain.Attachment
+
+	for _, id := range message.AttachmentIDs {
+		if attachment, ok := attachments[id]; ok {
+			result = append(result, attachment)
+		}
+	}
+
+	return result
+}
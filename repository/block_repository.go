@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type block struct {
+	db *gocql.Session
+}
+
+func (r *block) Block(ctx context.Context, userID, blockedID uint64) error {
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"INSERT INTO blocks (user_id, blocked_id, created_at) VALUES (?, ?, ?)",
+		userID,
+		blockedID,
+		time.Now().UTC(),
+	)
+
+	batch.Query(
+		"INSERT INTO blocks_by_blocked (blocked_id, user_id) VALUES (?, ?)",
+		blockedID,
+		userID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *block) Unblock(ctx context.Context, userID, blockedID uint64) error {
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"DELETE FROM blocks WHERE user_id = ? AND blocked_id = ?",
+		userID,
+		blockedID,
+	)
+
+	batch.Query(
+		"DELETE FROM blocks_by_blocked WHERE blocked_id = ? AND user_id = ?",
+		blockedID,
+		userID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *block) ListBlocked(ctx context.Context, userID uint64) ([]domain.Block, error) {
+	scanner := r.db.Query(
+		"SELECT blocked_id, created_at FROM blocks WHERE user_id = ?",
+		userID,
+	).WithContext(ctx).Iter().Scanner()
+
+	var (
+		blocks []domain.Block
+		err    error
+	)
+
+	for scanner.Next() {
+		var block domain.Block
+
+		if err = scanner.Scan(&block.UserID, &block.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	// clustered by the blocked user
+	slices.SortStableFunc(blocks, func(a, b domain.Block) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return blocks, nil
+}
+
+func (r *block) IsBlocked(ctx context.Context, userID, peerID uint64) (bool, error) {
+	for _, ids := range [][2]uint64{{userID, peerID}, {peerID, userID}} {
+		var count int
+
+		err := r.db.Query(
+			"SELECT COUNT(*) FROM blocks WHERE user_id = ? AND blocked_id = ?",
+			ids[0],
+			ids[1],
+		).WithContext(ctx).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *block) ListBlockedPeers(ctx context.Context, userID uint64) ([]uint64, error) {
+	var peers []uint64
+
+	for _, query := range []string{
+		"SELECT blocked_id FROM blocks WHERE user_id = ?",
+		"SELECT user_id FROM blocks_by_blocked WHERE blocked_id = ?",
+	} {
+		scanner := r.db.Query(query, userID).WithContext(ctx).Iter().Scanner()
+
+		for scanner.Next() {
+			var id uint64
+
+			if err := scanner.Scan(&id); err != nil {
+				return nil, fmt.Errorf("failed to scan row: %s", err)
+			}
+
+			peers = append(peers, id)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to close scanner: %s", err)
+		}
+	}
+
+	slices.Sort(peers)
+
+	return slices.Compact(peers), nil
+}
+
+func NewBlock(session *gocql.Session) *block {
+	return &block{
+		db: session,
+	}
+}
@@ -2,35 +2,132 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/gocql/gocql"
 	"github.com/lam0glia/chat-system/domain"
 )
 
-const pairFormat = "%d.%d"
-
 type chat struct {
 	db *gocql.Session
 }
 
-func (r *chat) pair(fromID, toID uint64) string {
-	if fromID < toID {
-		return fmt.Sprintf(pairFormat, fromID, toID)
-	} else {
-		return fmt.Sprintf(pairFormat, toID, fromID)
+const messageColumns = "id, content, created_at, from_id, to_id, edited_at, deleted_at, reply_to, last_reply_at, attachment_ids"
+
+const attachmentColumns = "id, uploader_id, peer_id, message_id, name, mime_type, size, checksum, width, height, blob_key, created_at, thumbnails, placeholder"
+
+func (r *chat) InsertMessage(ctx context.Context, message *domain.Message) error {
+	if err := r.claimAttachments(ctx, message); err != nil {
+		return err
+	}
+
+	if err := r.insertMessage(ctx, message); err != nil {
+		r.releaseAttachments(ctx, message, message.AttachmentIDs)
+
+		return err
 	}
+
+	return nil
 }
 
-func (r *chat) InsertMessage(ctx context.Context, message *domain.Message) error {
-	return r.db.Query(
-		"INSERT INTO messages (id, content, from_id, to_id, created_at, pair) VALUES (?, ?, ?, ?, ?, ?)",
+// Lightweight transactions can't be batched with other tables, so
+// the attachments are taken before the message is written. Taking
+// the ones of a message written again is allowed
+func (r *chat) claimAttachments(ctx context.Context, message *domain.Message) error {
+	for i, attachmentID := range message.AttachmentIDs {
+		current := make(map[string]any)
+
+		applied, err := r.db.Query(
+			"UPDATE attachments SET message_id = ? WHERE id = ? IF message_id = null",
+			message.ID,
+			attachmentID,
+		).WithContext(ctx).MapScanCAS(current)
+		if err != nil {
+			r.releaseAttachments(ctx, message, message.AttachmentIDs[:i])
+
+			return fmt.Errorf("claim attachment %d: %w", attachmentID, err)
+		}
+
+		if !applied && current["message_id"] != int64(message.ID) {
+			r.releaseAttachments(ctx, message, message.AttachmentIDs[:i])
+
+			return domain.ErrAttachmentNotFound
+		}
+	}
+
+	return nil
+}
+
+// Undoes claimAttachments, for the attachments still taken by message
+func (r *chat) releaseAttachments(ctx context.Context, message *domain.Message, attachmentIDs []uint64) {
+	for _, attachmentID := range attachmentIDs {
+		_, err := r.db.Query(
+			"UPDATE attachments SET message_id = null WHERE id = ? IF message_id = ?",
+			attachmentID,
+			message.ID,
+		).WithContext(ctx).MapScanCAS(map[string]any{})
+		if err != nil {
+			log.Printf("err: release attachment %d: %s", attachmentID, err)
+		}
+	}
+}
+
+func (r *chat) insertMessage(ctx context.Context, message *domain.Message) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	bucket := messageBucket(message.ID)
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"INSERT INTO messages_by_bucket (id, content, from_id, to_id, created_at, pair, bucket, reply_to, attachment_ids) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		message.ID,
 		message.Content,
 		message.FromID,
 		message.ToID,
 		message.CreatedAt,
-		r.pair(message.FromID, message.ToID),
+		pair,
+		bucket,
+		message.ReplyToID,
+		message.AttachmentIDs,
+	)
+
+	batch.Query(
+		"INSERT INTO message_buckets (pair, bucket) VALUES (?, ?)",
+		pair,
+		bucket,
+	)
+
+	if message.ReplyToID == nil {
+		return r.db.ExecuteBatch(batch)
+	}
+
+	batch.Query(
+		"INSERT INTO thread_messages (pair, root_id, id) VALUES (?, ?, ?)",
+		pair,
+		message.ReplyToID,
+		message.ID,
+	)
+
+	batch.Query(
+		"UPDATE messages_by_bucket SET last_reply_at = ? WHERE pair = ? AND bucket = ? AND id = ?",
+		message.CreatedAt,
+		pair,
+		messageBucket(*message.ReplyToID),
+		message.ReplyToID,
+	)
+
+	if err := r.db.ExecuteBatch(batch); err != nil {
+		return err
+	}
+
+	// counters can't be batched with other tables
+	return r.db.Query(
+		"UPDATE thread_reply_counts SET reply_count = reply_count + 1 WHERE pair = ? AND root_id = ?",
+		pair,
+		message.ReplyToID,
 	).WithContext(ctx).Exec()
 }
 
@@ -38,38 +135,250 @@ func (r *chat) ListMessages(
 	ctx context.Context,
 	fromID,
 	toID uint64,
-	beforeID *uint64,
-	limit int,
+	messageRange *domain.MessageRange,
 ) ([]domain.Message, error) {
+	pair := pairOf(fromID, toID)
+
+	buckets, err := r.messageBuckets(ctx, pair, messageRange)
+	if err != nil {
+		return nil, fmt.Errorf("list buckets: %w", err)
+	}
+
+	var messages []domain.Message
+
+	// buckets are visited in the requested order until the page is full
+	for _, bucket := range buckets {
+		bucketMessages, err := r.listBucketMessages(
+			ctx,
+			pair,
+			bucket,
+			messageRange,
+			messageRange.Limit-len(messages),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list bucket %d: %w", bucket, err)
+		}
+
+		messages = append(messages, bucketMessages...)
+
+		if len(messages) >= messageRange.Limit {
+			break
+		}
+	}
+
+	return r.decorateMessages(ctx, fromID, toID, messages)
+}
+
+// Buckets of the conversation that may hold messages in
+// the range, in the order they must be read
+func (r *chat) messageBuckets(
+	ctx context.Context,
+	pair string,
+	messageRange *domain.MessageRange,
+) ([]int64, error) {
 	query := `SELECT
-			id, content, created_at, from_id, to_id
+			bucket
 		FROM
-			messages
+			message_buckets
 		WHERE
 			pair = ?
 			%s
-		ORDER BY id ASC LIMIT ?`
+		ORDER BY bucket %s`
+
+	values := []any{pair}
+
+	var conditions string
+
+	if messageRange.BeforeID != nil {
+		conditions += " AND bucket <= ?"
+		values = append(values, messageBucket(*messageRange.BeforeID))
+	}
 
-	var values []any
+	if messageRange.AfterID != nil {
+		conditions += " AND bucket >= ?"
+		values = append(values, messageBucket(*messageRange.AfterID))
+	}
+
+	scanner := r.db.Query(
+		fmt.Sprintf(query, conditions, r.order(messageRange.Descending)),
+		values...,
+	).WithContext(ctx).Iter().Scanner()
 
-	values = append(values, r.pair(fromID, toID))
+	var buckets []int64
 
-	var beforeCondition string
+	for scanner.Next() {
+		var bucket int64
+
+		if err := scanner.Scan(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return buckets, nil
+}
+
+func (r *chat) listBucketMessages(
+	ctx context.Context,
+	pair string,
+	bucket int64,
+	messageRange *domain.MessageRange,
+	limit int,
+) ([]domain.Message, error) {
+	query := `SELECT
+			%s
+		FROM
+			messages_by_bucket
+		WHERE
+			pair = ? AND bucket = ?
+			%s
+		ORDER BY id %s LIMIT ?`
+
+	values := []any{pair, bucket}
+
+	var conditions string
+
+	if messageRange.BeforeID != nil {
+		conditions += " AND id < ?"
+		values = append(values, messageRange.BeforeID)
+	}
 
-	if beforeID != nil {
-		beforeCondition = "AND id < ?"
-		values = append(values, beforeID)
+	if messageRange.AfterID != nil {
+		conditions += " AND id > ?"
+		values = append(values, messageRange.AfterID)
 	}
 
 	values = append(values, limit)
 
-	query = fmt.Sprintf(query, beforeCondition)
+	query = fmt.Sprintf(query, messageColumns, conditions, r.order(messageRange.Descending))
 
 	scanner := r.db.Query(
 		query,
 		values...,
 	).WithContext(ctx).Iter().Scanner()
 
+	return r.scanMessages(scanner)
+}
+
+func (r *chat) order(descending bool) string {
+	if descending {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+func (r *chat) ListThread(
+	ctx context.Context,
+	fromID,
+	toID,
+	rootID uint64,
+	afterID *uint64,
+	limit int,
+) ([]domain.Message, error) {
+	query := `SELECT
+			id
+		FROM
+			thread_messages
+		WHERE
+			pair = ? AND root_id = ?
+			%s
+		ORDER BY id ASC LIMIT ?`
+
+	pair := pairOf(fromID, toID)
+
+	values := []any{pair, rootID}
+
+	var afterCondition string
+
+	if afterID != nil {
+		afterCondition = "AND id > ?"
+		values = append(values, afterID)
+	}
+
+	values = append(values, limit)
+
+	scanner := r.db.Query(
+		fmt.Sprintf(query, afterCondition),
+		values...,
+	).WithContext(ctx).Iter().Scanner()
+
+	var ids []uint64
+
+	for scanner.Next() {
+		var id uint64
+
+		if err := scanner.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	var messages []domain.Message
+
+	// ids are ascending, so each bucket is a contiguous run of them
+	for len(ids) > 0 {
+		bucket := messageBucket(ids[0])
+
+		n := 1
+		for n < len(ids) && messageBucket(ids[n]) == bucket {
+			n++
+		}
+
+		scanner = r.db.Query(
+			fmt.Sprintf(
+				"SELECT %s FROM messages_by_bucket WHERE pair = ? AND bucket = ? AND id IN ?",
+				messageColumns,
+			),
+			pair,
+			bucket,
+			ids[:n],
+		).WithContext(ctx).Iter().Scanner()
+
+		bucketMessages, err := r.scanMessages(scanner)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, bucketMessages...)
+
+		ids = ids[n:]
+	}
+
+	messages, err := r.decorateMessages(ctx, fromID, toID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.VisibleMessages(messages), nil
+}
+
+func (r *chat) CountReplies(ctx context.Context, fromID, toID, rootID uint64) (int, error) {
+	var count int64
+
+	err := r.db.Query(
+		"SELECT reply_count FROM thread_reply_counts WHERE pair = ? AND root_id = ?",
+		pairOf(fromID, toID),
+		rootID,
+	).WithContext(ctx).Scan(&count)
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (r *chat) scanMessages(scanner gocql.Scanner) ([]domain.Message, error) {
 	var (
 		messages []domain.Message
 		err      error
@@ -78,13 +387,7 @@ func (r *chat) ListMessages(
 	for scanner.Next() {
 		var message domain.Message
 
-		err = scanner.Scan(
-			&message.ID,
-			&message.Content,
-			&message.CreatedAt,
-			&message.FromID,
-			&message.ToID,
-		)
+		err = scanner.Scan(r.messageFields(&message)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %s", err)
 		}
@@ -99,6 +402,518 @@ func (r *chat) ListMessages(
 	return messages, nil
 }
 
+// Destinations of messageColumns
+func (r *chat) messageFields(message *domain.Message) []any {
+	return []any{
+		&message.ID,
+		&message.Content,
+		&message.CreatedAt,
+		&message.FromID,
+		&message.ToID,
+		&message.EditedAt,
+		&message.DeletedAt,
+		&message.ReplyToID,
+		&message.LastReplyAt,
+		&message.AttachmentIDs,
+	}
+}
+
+// Flags the messages hidden by userID and fills the
+// aggregated data of the others
+func (r *chat) decorateMessages(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	messages []domain.Message,
+) ([]domain.Message, error) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	firstID, lastID := messages[0].ID, messages[len(messages)-1].ID
+
+	if firstID > lastID {
+		firstID, lastID = lastID, firstID
+	}
+
+	hidden, err := r.ListHiddenMessageIDs(ctx, userID, peerID, firstID, lastID)
+	if err != nil {
+		return nil, fmt.Errorf("list hidden messages: %w", err)
+	}
+
+	ids := make([]uint64, len(messages))
+
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	reactions, err := r.reactionCounts(ctx, userID, peerID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list reactions: %w", err)
+	}
+
+	replies, err := r.replyCounts(ctx, userID, peerID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list reply counts: %w", err)
+	}
+
+	var attachmentIDs []uint64
+
+	for _, message := range messages {
+		attachmentIDs = append(attachmentIDs, message.AttachmentIDs...)
+	}
+
+	attachments, err := r.listAttachments(ctx, attachmentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+
+	for i := range messages {
+		message := &messages[i]
+
+		if _, ok := hidden[message.ID]; ok {
+			message.Hidden = true
+			continue
+		}
+
+		message.Reactions = reactions[message.ID]
+		message.ReplyCount = replies[message.ID]
+		message.Attachments = messageAttachments(message, attachments)
+	}
+
+	return messages, nil
+}
+
+// Reply counts of the messages that are thread roots, each
+// root is its own partition
+func (r *chat) replyCounts(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	messageIDs []uint64,
+) (map[uint64]int, error) {
+	scanner := r.db.Query(
+		`SELECT
+			root_id, reply_count
+		FROM
+			thread_reply_counts
+		WHERE
+			pair = ? AND root_id IN ?`,
+		pairOf(userID, peerID),
+		messageIDs,
+	).WithContext(ctx).Iter().Scanner()
+
+	counts := make(map[uint64]int)
+
+	for scanner.Next() {
+		var (
+			rootID uint64
+			count  int64
+		)
+
+		if err := scanner.Scan(&rootID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		counts[rootID] = int(count)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return counts, nil
+}
+
+// Ids between firstID and lastID hidden by userID
+func (r *chat) ListHiddenMessageIDs(
+	ctx context.Context,
+	userID,
+	peerID,
+	firstID,
+	lastID uint64,
+) (map[uint64]struct{}, error) {
+	scanner := r.db.Query(
+		`SELECT
+			message_id
+		FROM
+			hidden_messages
+		WHERE
+			user_id = ? AND pair = ? AND message_id >= ? AND message_id <= ?`,
+		userID,
+		pairOf(userID, peerID),
+		firstID,
+		lastID,
+	).WithContext(ctx).Iter().Scanner()
+
+	hidden := make(map[uint64]struct{})
+
+	for scanner.Next() {
+		var id uint64
+
+		if err := scanner.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		hidden[id] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return hidden, nil
+}
+
+func (r *chat) GetMessage(ctx context.Context, fromID, toID, id uint64) (*domain.Message, error) {
+	var message domain.Message
+
+	err := r.db.Query(
+		fmt.Sprintf(
+			"SELECT %s FROM messages_by_bucket WHERE pair = ? AND bucket = ? AND id = ?",
+			messageColumns,
+		),
+		pairOf(fromID, toID),
+		messageBucket(id),
+		id,
+	).WithContext(ctx).Scan(r.messageFields(&message)...)
+	if err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, domain.ErrMessageNotFound
+		}
+
+		return nil, err
+	}
+
+	if len(message.AttachmentIDs) > 0 {
+		attachments, err := r.listAttachments(ctx, message.AttachmentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("list attachments: %w", err)
+		}
+
+		message.Attachments = messageAttachments(&message, attachments)
+	}
+
+	return &message, nil
+}
+
+func (r *chat) ScanMessages(ctx context.Context, handle func(*domain.Message) error) error {
+	scanner := r.db.Query(
+		fmt.Sprintf("SELECT %s FROM messages_by_bucket", messageColumns),
+	).WithContext(ctx).PageSize(migrationPageSize).Iter().Scanner()
+
+	for scanner.Next() {
+		var message domain.Message
+
+		if err := scanner.Scan(r.messageFields(&message)...); err != nil {
+			return fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		if err := handle(&message); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return nil
+}
+
+func (r *chat) InsertAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	return r.db.Query(
+		fmt.Sprintf(
+			"INSERT INTO attachments (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			attachmentColumns,
+		),
+		attachment.ID,
+		attachment.UploaderID,
+		attachment.PeerID,
+		attachment.MessageID,
+		attachment.Name,
+		attachment.MIMEType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.Width,
+		attachment.Height,
+		attachment.BlobKey,
+		attachment.CreatedAt,
+		attachment.Thumbnails,
+		attachment.Placeholder,
+	).WithContext(ctx).Exec()
+}
+
+// Conditional, an upsert would bring back a partial
+// row of an attachment deleted meanwhile
+func (r *chat) UpdateAttachmentThumbnails(ctx context.Context, attachment *domain.Attachment) error {
+	applied, err := r.db.Query(
+		"UPDATE attachments SET thumbnails = ?, placeholder = ? WHERE id = ? IF EXISTS",
+		attachment.Thumbnails,
+		attachment.Placeholder,
+		attachment.ID,
+	).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (r *chat) GetAttachment(ctx context.Context, id uint64) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+
+	err := r.db.Query(
+		fmt.Sprintf("SELECT %s FROM attachments WHERE id = ?", attachmentColumns),
+		id,
+	).WithContext(ctx).Scan(r.attachmentFields(&attachment)...)
+	if err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, domain.ErrAttachmentNotFound
+		}
+
+		return nil, err
+	}
+
+	attachment.SetURLs()
+
+	return &attachment, nil
+}
+
+func (r *chat) listAttachments(ctx context.Context, ids []uint64) (map[uint64]domain.Attachment, error) {
+	attachments := make(map[uint64]domain.Attachment)
+
+	if len(ids) == 0 {
+		return attachments, nil
+	}
+
+	scanner := r.db.Query(
+		fmt.Sprintf("SELECT %s FROM attachments WHERE id IN ?", attachmentColumns),
+		ids,
+	).WithContext(ctx).Iter().Scanner()
+
+	for scanner.Next() {
+		var attachment domain.Attachment
+
+		if err := scanner.Scan(r.attachmentFields(&attachment)...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		attachment.SetURLs()
+
+		attachments[attachment.ID] = attachment
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return attachments, nil
+}
+
+// Destinations of attachmentColumns
+func (r *chat) attachmentFields(attachment *domain.Attachment) []any {
+	return []any{
+		&attachment.ID,
+		&attachment.UploaderID,
+		&attachment.PeerID,
+		&attachment.MessageID,
+		&attachment.Name,
+		&attachment.MIMEType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.Width,
+		&attachment.Height,
+		&attachment.BlobKey,
+		&attachment.CreatedAt,
+		&attachment.Thumbnails,
+		&attachment.Placeholder,
+	}
+}
+
+func (r *chat) UpdateMessageContent(
+	ctx context.Context,
+	message *domain.Message,
+	previousContent string,
+) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"INSERT INTO message_edits (pair, message_id, edited_at, content) VALUES (?, ?, ?, ?)",
+		pair,
+		message.ID,
+		message.EditedAt,
+		previousContent,
+	)
+
+	batch.Query(
+		"UPDATE messages_by_bucket SET content = ?, edited_at = ? WHERE pair = ? AND bucket = ? AND id = ?",
+		message.Content,
+		message.EditedAt,
+		pair,
+		messageBucket(message.ID),
+		message.ID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *chat) ListMessageEdits(
+	ctx context.Context,
+	fromID,
+	toID,
+	messageID uint64,
+) ([]domain.MessageEdit, error) {
+	scanner := r.db.Query(
+		`SELECT
+			message_id, content, edited_at
+		FROM
+			message_edits
+		WHERE
+			pair = ? AND message_id = ?`,
+		pairOf(fromID, toID),
+		messageID,
+	).WithContext(ctx).Iter().Scanner()
+
+	var (
+		edits []domain.MessageEdit
+		err   error
+	)
+
+	for scanner.Next() {
+		var edit domain.MessageEdit
+
+		if err = scanner.Scan(&edit.MessageID, &edit.Content, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		edits = append(edits, edit)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return edits, nil
+}
+
+// Reactions of the messages aggregated by message id. Each
+// message is its own partition, at most a page of them is read
+func (r *chat) reactionCounts(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	messageIDs []uint64,
+) (map[uint64][]domain.ReactionCount, error) {
+	scanner := r.db.Query(
+		`SELECT
+			message_id, emoji, user_id
+		FROM
+			reactions
+		WHERE
+			pair = ? AND message_id IN ?`,
+		pairOf(userID, peerID),
+		messageIDs,
+	).WithContext(ctx).Iter().Scanner()
+
+	counts := make(map[uint64][]domain.ReactionCount)
+
+	for scanner.Next() {
+		var reaction domain.Reaction
+
+		if err := scanner.Scan(
+			&reaction.MessageID,
+			&reaction.Emoji,
+			&reaction.UserID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		countReaction(counts, &reaction, userID)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return counts, nil
+}
+
+func (r *chat) AddReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	return r.db.Query(
+		"INSERT INTO reactions (pair, message_id, emoji, user_id) VALUES (?, ?, ?, ?)",
+		pairOf(reaction.UserID, peerID),
+		reaction.MessageID,
+		reaction.Emoji,
+		reaction.UserID,
+	).WithContext(ctx).Exec()
+}
+
+func (r *chat) RemoveReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	return r.db.Query(
+		"DELETE FROM reactions WHERE pair = ? AND message_id = ? AND emoji = ? AND user_id = ?",
+		pairOf(reaction.UserID, peerID),
+		reaction.MessageID,
+		reaction.Emoji,
+		reaction.UserID,
+	).WithContext(ctx).Exec()
+}
+
+func (r *chat) DeleteMessage(ctx context.Context, message *domain.Message) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"UPDATE messages_by_bucket SET content = ?, deleted_at = ?, attachment_ids = null WHERE pair = ? AND bucket = ? AND id = ?",
+		message.Content,
+		message.DeletedAt,
+		pair,
+		messageBucket(message.ID),
+		message.ID,
+	)
+
+	batch.Query(
+		"DELETE FROM message_edits WHERE pair = ? AND message_id = ?",
+		pair,
+		message.ID,
+	)
+
+	for _, attachmentID := range message.AttachmentIDs {
+		batch.Query("DELETE FROM attachments WHERE id = ?", attachmentID)
+	}
+
+	if message.ReplyToID == nil {
+		return r.db.ExecuteBatch(batch)
+	}
+
+	if err := r.db.ExecuteBatch(batch); err != nil {
+		return err
+	}
+
+	// counters can't be batched with other tables
+	return r.db.Query(
+		"UPDATE thread_reply_counts SET reply_count = reply_count - 1 WHERE pair = ? AND root_id = ?",
+		pair,
+		message.ReplyToID,
+	).WithContext(ctx).Exec()
+}
+
+func (r *chat) HideMessage(ctx context.Context, userID, peerID, messageID uint64) error {
+	return r.db.Query(
+		"INSERT INTO hidden_messages (user_id, pair, message_id) VALUES (?, ?, ?)",
+		userID,
+		pairOf(userID, peerID),
+		messageID,
+	).WithContext(ctx).Exec()
+}
+
 func NewChat(session *gocql.Session) *chat {
 	return &chat{
 		db: session,
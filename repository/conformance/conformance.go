@@ -0,0 +1,1203 @@
+// Package conformance checks that the repositories of every
+// storage backend behave the same. go test runs it against the
+// memory and SQLite backends, and cmd/conformance against the
+// configured database, which must be an empty development one
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type check struct {
+	name string
+	run  func(s *suite) error
+}
+
+var checks = []check{
+	{"insert and get message", checkInsertAndGet},
+	{"list messages in both directions", checkListMessages},
+	{"hide message", checkHideMessage},
+	{"edit message", checkEditMessage},
+	{"delete message", checkDeleteMessage},
+	{"scan messages", checkScanMessages},
+	{"reactions", checkReactions},
+	{"threads", checkThreads},
+	{"attachments", checkAttachments},
+	{"blocks", checkBlocks},
+	{"conversations", checkConversations},
+	{"users", checkUsers},
+	{"reviews", checkReviews},
+	{"reports", checkReports},
+	{"suspensions", checkSuspensions},
+}
+
+type suite struct {
+	ctx                    context.Context
+	repository             domain.ChatRepository
+	blockRepository        domain.BlockRepository
+	conversationRepository domain.ConversationRepository
+	userRepository         domain.UserRepository
+	reviewRepository       domain.ReviewRepository
+	reportRepository       domain.ReportRepository
+	uidGenerator           domain.UIDGenerator
+}
+
+// Runs every check and returns their failures joined. Each check
+// uses its own users, so data left by previous runs is ignored
+func Run(
+	ctx context.Context,
+	repository domain.ChatRepository,
+	blockRepository domain.BlockRepository,
+	conversationRepository domain.ConversationRepository,
+	userRepository domain.UserRepository,
+	reviewRepository domain.ReviewRepository,
+	reportRepository domain.ReportRepository,
+	uidGenerator domain.UIDGenerator,
+) error {
+	s := &suite{
+		ctx:                    ctx,
+		repository:             repository,
+		blockRepository:        blockRepository,
+		conversationRepository: conversationRepository,
+		userRepository:         userRepository,
+		reviewRepository:       reviewRepository,
+		reportRepository:       reportRepository,
+		uidGenerator:           uidGenerator,
+	}
+
+	var errs []error
+
+	for _, c := range checks {
+		if err := c.run(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *suite) id() uint64 {
+	id, err := s.uidGenerator.NextID()
+	if err != nil {
+		panic(fmt.Sprintf("next id: %s", err))
+	}
+
+	return id
+}
+
+// Two users that never talked before
+func (s *suite) users() (uint64, uint64) {
+	return s.id(), s.id()
+}
+
+func (s *suite) send(fromID, toID uint64, content string) (*domain.Message, error) {
+	message := &domain.Message{
+		ID:        s.id(),
+		FromID:    fromID,
+		ToID:      toID,
+		Content:   content,
+		CreatedAt: now(),
+	}
+
+	if err := s.repository.InsertMessage(s.ctx, message); err != nil {
+		return nil, fmt.Errorf("insert message: %w", err)
+	}
+
+	return message, nil
+}
+
+// Backends store milliseconds at least
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func ids(messages []domain.Message) []uint64 {
+	var result []uint64
+
+	for _, message := range messages {
+		result = append(result, message.ID)
+	}
+
+	return result
+}
+
+func expectIDs(got []domain.Message, want ...uint64) error {
+	if !slices.Equal(ids(got), want) {
+		return fmt.Errorf("got messages %v, want %v", ids(got), want)
+	}
+
+	return nil
+}
+
+func checkInsertAndGet(s *suite) error {
+	fromID, toID := s.users()
+
+	sent, err := s.send(fromID, toID, "hello")
+	if err != nil {
+		return err
+	}
+
+	// conversations are the same for both participants
+	message, err := s.repository.GetMessage(s.ctx, toID, fromID, sent.ID)
+	if err != nil {
+		return fmt.Errorf("get message: %w", err)
+	}
+
+	if message.FromID != fromID || message.ToID != toID || message.Content != "hello" {
+		return fmt.Errorf("got %+v, want the sent message", message)
+	}
+
+	if !message.CreatedAt.Equal(sent.CreatedAt) {
+		return fmt.Errorf("got created at %s, want %s", message.CreatedAt, sent.CreatedAt)
+	}
+
+	if message.ReplyToID != nil || message.EditedAt != nil || message.DeletedAt != nil {
+		return fmt.Errorf("got %+v, want no reply, edit nor deletion", message)
+	}
+
+	_, err = s.repository.GetMessage(s.ctx, fromID, toID, s.id())
+	if !errors.Is(err, domain.ErrMessageNotFound) {
+		return fmt.Errorf("get missing message: got %v, want %s", err, domain.ErrMessageNotFound)
+	}
+
+	return nil
+}
+
+func checkListMessages(s *suite) error {
+	fromID, toID := s.users()
+
+	var sent []uint64
+
+	for i := range 5 {
+		message, err := s.send(fromID, toID, fmt.Sprintf("message %d", i))
+		if err != nil {
+			return err
+		}
+
+		sent = append(sent, message.ID)
+	}
+
+	// a message of another conversation must never be listed
+	if _, err := s.send(fromID, s.id(), "other"); err != nil {
+		return err
+	}
+
+	newest, err := s.repository.ListMessages(s.ctx, fromID, toID, &domain.MessageRange{
+		Descending: true,
+		Limit:      2,
+	})
+	if err != nil {
+		return fmt.Errorf("list newest: %w", err)
+	}
+
+	if err = expectIDs(newest, sent[4], sent[3]); err != nil {
+		return fmt.Errorf("newest: %w", err)
+	}
+
+	older, err := s.repository.ListMessages(s.ctx, toID, fromID, &domain.MessageRange{
+		BeforeID:   &sent[3],
+		Descending: true,
+		Limit:      10,
+	})
+	if err != nil {
+		return fmt.Errorf("list older: %w", err)
+	}
+
+	if err = expectIDs(older, sent[2], sent[1], sent[0]); err != nil {
+		return fmt.Errorf("older: %w", err)
+	}
+
+	newer, err := s.repository.ListMessages(s.ctx, fromID, toID, &domain.MessageRange{
+		AfterID: &sent[1],
+		Limit:   2,
+	})
+	if err != nil {
+		return fmt.Errorf("list newer: %w", err)
+	}
+
+	if err = expectIDs(newer, sent[2], sent[3]); err != nil {
+		return fmt.Errorf("newer: %w", err)
+	}
+
+	between, err := s.repository.ListMessages(s.ctx, fromID, toID, &domain.MessageRange{
+		AfterID:  &sent[0],
+		BeforeID: &sent[4],
+		Limit:    10,
+	})
+	if err != nil {
+		return fmt.Errorf("list between: %w", err)
+	}
+
+	return expectIDs(between, sent[1], sent[2], sent[3])
+}
+
+func checkHideMessage(s *suite) error {
+	fromID, toID := s.users()
+
+	message, err := s.send(fromID, toID, "secret")
+	if err != nil {
+		return err
+	}
+
+	if err = s.repository.HideMessage(s.ctx, fromID, toID, message.ID); err != nil {
+		return fmt.Errorf("hide message: %w", err)
+	}
+
+	messageRange := &domain.MessageRange{Descending: true, Limit: 10}
+
+	own, err := s.repository.ListMessages(s.ctx, fromID, toID, messageRange)
+	if err != nil {
+		return fmt.Errorf("list as sender: %w", err)
+	}
+
+	// flagged instead of removed, so pages keep their size
+	if len(own) != 1 || !own[0].Hidden {
+		return fmt.Errorf("got %+v, want the message flagged as hidden", own)
+	}
+
+	peer, err := s.repository.ListMessages(s.ctx, toID, fromID, messageRange)
+	if err != nil {
+		return fmt.Errorf("list as recipient: %w", err)
+	}
+
+	if len(peer) != 1 || peer[0].Hidden {
+		return fmt.Errorf("got %+v, want the message visible to the recipient", peer)
+	}
+
+	hidden, err := s.repository.ListHiddenMessageIDs(s.ctx, fromID, toID, message.ID, message.ID)
+	if err != nil {
+		return fmt.Errorf("list hidden as sender: %w", err)
+	}
+
+	if _, ok := hidden[message.ID]; !ok || len(hidden) != 1 {
+		return fmt.Errorf("got hidden ids %v, want only %d", hidden, message.ID)
+	}
+
+	hidden, err = s.repository.ListHiddenMessageIDs(s.ctx, toID, fromID, message.ID, message.ID)
+	if err != nil {
+		return fmt.Errorf("list hidden as recipient: %w", err)
+	}
+
+	if len(hidden) != 0 {
+		return fmt.Errorf("got hidden ids %v, want none for the recipient", hidden)
+	}
+
+	return nil
+}
+
+func checkEditMessage(s *suite) error {
+	fromID, toID := s.users()
+
+	message, err := s.send(fromID, toID, "first")
+	if err != nil {
+		return err
+	}
+
+	for _, content := range []string{"second", "third"} {
+		previous := message.Content
+		editedAt := now()
+
+		message.Content = content
+		message.EditedAt = &editedAt
+
+		if err = s.repository.UpdateMessageContent(s.ctx, message, previous); err != nil {
+			return fmt.Errorf("update content: %w", err)
+		}
+
+		// edits must have distinct timestamps
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	got, err := s.repository.GetMessage(s.ctx, fromID, toID, message.ID)
+	if err != nil {
+		return fmt.Errorf("get message: %w", err)
+	}
+
+	if got.Content != "third" || got.EditedAt == nil || !got.EditedAt.Equal(*message.EditedAt) {
+		return fmt.Errorf("got %+v, want the last edit", got)
+	}
+
+	edits, err := s.repository.ListMessageEdits(s.ctx, toID, fromID, message.ID)
+	if err != nil {
+		return fmt.Errorf("list edits: %w", err)
+	}
+
+	var contents []string
+
+	for _, edit := range edits {
+		contents = append(contents, edit.Content)
+	}
+
+	if !slices.Equal(contents, []string{"first", "second"}) {
+		return fmt.Errorf("got edits %v, want the previous contents oldest first", contents)
+	}
+
+	return nil
+}
+
+func checkDeleteMessage(s *suite) error {
+	fromID, toID := s.users()
+
+	message, err := s.send(fromID, toID, "oops")
+	if err != nil {
+		return err
+	}
+
+	editedAt := now()
+	message.Content = "oops!"
+	message.EditedAt = &editedAt
+
+	if err = s.repository.UpdateMessageContent(s.ctx, message, "oops"); err != nil {
+		return fmt.Errorf("update content: %w", err)
+	}
+
+	deletedAt := now()
+	message.Content = ""
+	message.DeletedAt = &deletedAt
+
+	if err = s.repository.DeleteMessage(s.ctx, message); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	got, err := s.repository.GetMessage(s.ctx, fromID, toID, message.ID)
+	if err != nil {
+		return fmt.Errorf("get tombstone: %w", err)
+	}
+
+	if got.Content != "" || got.DeletedAt == nil {
+		return fmt.Errorf("got %+v, want a tombstone", got)
+	}
+
+	edits, err := s.repository.ListMessageEdits(s.ctx, fromID, toID, message.ID)
+	if err != nil {
+		return fmt.Errorf("list edits: %w", err)
+	}
+
+	if len(edits) != 0 {
+		return fmt.Errorf("got %d edits, want the history erased", len(edits))
+	}
+
+	return nil
+}
+
+func checkScanMessages(s *suite) error {
+	fromID, toID := s.users()
+	_, otherID := s.users()
+
+	want := make(map[uint64]string)
+
+	for _, peerID := range []uint64{toID, otherID} {
+		message, err := s.send(fromID, peerID, "scanned")
+		if err != nil {
+			return err
+		}
+
+		want[message.ID] = message.Content
+	}
+
+	deleted, err := s.send(toID, fromID, "gone")
+	if err != nil {
+		return err
+	}
+
+	deletedAt := now()
+	deleted.Content = ""
+	deleted.DeletedAt = &deletedAt
+
+	if err = s.repository.DeleteMessage(s.ctx, deleted); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	var tombstone bool
+
+	// other checks share the repository, only the messages
+	// of these users are looked at
+	err = s.repository.ScanMessages(s.ctx, func(message *domain.Message) error {
+		if message.FromID != fromID && message.FromID != toID {
+			return nil
+		}
+
+		if message.ID == deleted.ID {
+			tombstone = message.DeletedAt != nil
+
+			return nil
+		}
+
+		if content, ok := want[message.ID]; !ok || content != message.Content {
+			return fmt.Errorf("got unexpected message %+v", message)
+		}
+
+		delete(want, message.ID)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scan messages: %w", err)
+	}
+
+	if len(want) > 0 {
+		return fmt.Errorf("got %d messages not scanned", len(want))
+	}
+
+	if !tombstone {
+		return fmt.Errorf("got no tombstone of %d", deleted.ID)
+	}
+
+	return nil
+}
+
+func checkReactions(s *suite) error {
+	fromID, toID := s.users()
+
+	message, err := s.send(fromID, toID, "react")
+	if err != nil {
+		return err
+	}
+
+	reactions := []domain.Reaction{
+		{MessageID: message.ID, UserID: fromID, Emoji: "👍"},
+		{MessageID: message.ID, UserID: toID, Emoji: "👍"},
+		{MessageID: message.ID, UserID: toID, Emoji: "🎉"},
+		// adding the same reaction twice is a no-op
+		{MessageID: message.ID, UserID: toID, Emoji: "🎉"},
+	}
+
+	for _, reaction := range reactions {
+		peerID := toID
+		if reaction.UserID == toID {
+			peerID = fromID
+		}
+
+		if err = s.repository.AddReaction(s.ctx, peerID, &reaction); err != nil {
+			return fmt.Errorf("add reaction: %w", err)
+		}
+	}
+
+	counts, err := s.reactionCounts(fromID, toID)
+	if err != nil {
+		return err
+	}
+
+	if counts["👍"] != (domain.ReactionCount{Emoji: "👍", Count: 2, Reacted: true}) ||
+		counts["🎉"] != (domain.ReactionCount{Emoji: "🎉", Count: 1}) {
+		return fmt.Errorf("got counts %+v", counts)
+	}
+
+	if err = s.repository.RemoveReaction(s.ctx, toID, &reactions[0]); err != nil {
+		return fmt.Errorf("remove reaction: %w", err)
+	}
+
+	counts, err = s.reactionCounts(fromID, toID)
+	if err != nil {
+		return err
+	}
+
+	if counts["👍"] != (domain.ReactionCount{Emoji: "👍", Count: 1}) {
+		return fmt.Errorf("got counts %+v after removing", counts)
+	}
+
+	return nil
+}
+
+// Reaction counts of the only message of the conversation, by emoji
+func (s *suite) reactionCounts(userID, peerID uint64) (map[string]domain.ReactionCount, error) {
+	messages, err := s.repository.ListMessages(s.ctx, userID, peerID, &domain.MessageRange{
+		Descending: true,
+		Limit:      1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+
+	if len(messages) != 1 {
+		return nil, fmt.Errorf("got %d messages, want 1", len(messages))
+	}
+
+	counts := make(map[string]domain.ReactionCount)
+
+	for _, count := range messages[0].Reactions {
+		counts[count.Emoji] = count
+	}
+
+	return counts, nil
+}
+
+func checkThreads(s *suite) error {
+	fromID, toID := s.users()
+
+	root, err := s.send(fromID, toID, "root")
+	if err != nil {
+		return err
+	}
+
+	var replies []uint64
+
+	for i := range 3 {
+		reply := &domain.Message{
+			ID:        s.id(),
+			FromID:    toID,
+			ToID:      fromID,
+			Content:   fmt.Sprintf("reply %d", i),
+			CreatedAt: now(),
+			ReplyToID: &root.ID,
+		}
+
+		if err = s.repository.InsertMessage(s.ctx, reply); err != nil {
+			return fmt.Errorf("insert reply: %w", err)
+		}
+
+		replies = append(replies, reply.ID)
+	}
+
+	count, err := s.repository.CountReplies(s.ctx, fromID, toID, root.ID)
+	if err != nil {
+		return fmt.Errorf("count replies: %w", err)
+	}
+
+	if count != 3 {
+		return fmt.Errorf("got %d replies, want 3", count)
+	}
+
+	thread, err := s.repository.ListThread(s.ctx, fromID, toID, root.ID, nil, 2)
+	if err != nil {
+		return fmt.Errorf("list thread: %w", err)
+	}
+
+	if err = expectIDs(thread, replies[0], replies[1]); err != nil {
+		return fmt.Errorf("thread: %w", err)
+	}
+
+	thread, err = s.repository.ListThread(s.ctx, toID, fromID, root.ID, &replies[1], 2)
+	if err != nil {
+		return fmt.Errorf("list thread after: %w", err)
+	}
+
+	if err = expectIDs(thread, replies[2]); err != nil {
+		return fmt.Errorf("thread after: %w", err)
+	}
+
+	messages, err := s.repository.ListMessages(s.ctx, fromID, toID, &domain.MessageRange{
+		Limit: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("list messages: %w", err)
+	}
+
+	if len(messages) != 1 || messages[0].ID != root.ID {
+		return fmt.Errorf("got %v, want the root first", ids(messages))
+	}
+
+	if messages[0].ReplyCount != 3 || messages[0].LastReplyAt == nil {
+		return fmt.Errorf("got root %+v, want its reply count and last reply", messages[0])
+	}
+
+	deletedAt := now()
+
+	// deleted for everyone, it is no longer counted
+	err = s.repository.DeleteMessage(s.ctx, &domain.Message{
+		ID:        replies[1],
+		FromID:    toID,
+		ToID:      fromID,
+		DeletedAt: &deletedAt,
+		ReplyToID: &root.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("delete reply: %w", err)
+	}
+
+	count, err = s.repository.CountReplies(s.ctx, fromID, toID, root.ID)
+	if err != nil {
+		return fmt.Errorf("count replies after deletion: %w", err)
+	}
+
+	if count != 2 {
+		return fmt.Errorf("got %d replies after deletion, want 2", count)
+	}
+
+	messages, err = s.repository.ListMessages(s.ctx, fromID, toID, &domain.MessageRange{
+		Limit: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("list messages after deletion: %w", err)
+	}
+
+	if len(messages) != 1 || messages[0].ReplyCount != 2 {
+		return fmt.Errorf("got %+v, want the root with 2 replies", messages)
+	}
+
+	return nil
+}
+
+func checkAttachments(s *suite) error {
+	fromID, toID := s.users()
+
+	var attachmentIDs []uint64
+
+	for i := range 2 {
+		attachment := &domain.Attachment{
+			ID:         s.id(),
+			UploaderID: fromID,
+			PeerID:     toID,
+			Name:       fmt.Sprintf("image-%d.png", i),
+			MIMEType:   "image/png",
+			Size:       1024,
+			Checksum:   "0123456789abcdef",
+			Width:      640,
+			Height:     480,
+			BlobKey:    fmt.Sprintf("attachments/%d", i),
+			CreatedAt:  now(),
+		}
+
+		if err := s.repository.InsertAttachment(s.ctx, attachment); err != nil {
+			return fmt.Errorf("insert attachment: %w", err)
+		}
+
+		attachmentIDs = append(attachmentIDs, attachment.ID)
+	}
+
+	_, err := s.repository.GetAttachment(s.ctx, s.id())
+	if !errors.Is(err, domain.ErrAttachmentNotFound) {
+		return fmt.Errorf("get missing attachment: got %v, want %s", err, domain.ErrAttachmentNotFound)
+	}
+
+	// a thumbnail job that finished after the deletion
+	err = s.repository.UpdateAttachmentThumbnails(s.ctx, &domain.Attachment{ID: s.id()})
+	if !errors.Is(err, domain.ErrAttachmentNotFound) {
+		return fmt.Errorf("update thumbnails of a missing attachment: got %v, want %s", err, domain.ErrAttachmentNotFound)
+	}
+
+	attachment, err := s.repository.GetAttachment(s.ctx, attachmentIDs[1])
+	if err != nil {
+		return fmt.Errorf("get attachment: %w", err)
+	}
+
+	attachment.Thumbnails = []domain.Thumbnail{{
+		Size:     160,
+		Width:    160,
+		Height:   120,
+		MIMEType: "image/jpeg",
+		BlobKey:  "thumbnails/160",
+	}}
+	attachment.Placeholder = "LEHV6nWB2yk8pyo0adR*.7kCMdnj"
+
+	if err = s.repository.UpdateAttachmentThumbnails(s.ctx, attachment); err != nil {
+		return fmt.Errorf("update thumbnails: %w", err)
+	}
+
+	// sent in the reverse order of the upload
+	message := &domain.Message{
+		ID:            s.id(),
+		FromID:        fromID,
+		ToID:          toID,
+		CreatedAt:     now(),
+		AttachmentIDs: []uint64{attachmentIDs[1], attachmentIDs[0]},
+	}
+
+	if err = s.repository.InsertMessage(s.ctx, message); err != nil {
+		return fmt.Errorf("insert message: %w", err)
+	}
+
+	// a concurrent send of the same upload
+	taken := &domain.Message{
+		ID:            s.id(),
+		FromID:        fromID,
+		ToID:          toID,
+		CreatedAt:     now(),
+		AttachmentIDs: []uint64{attachmentIDs[0]},
+	}
+
+	err = s.repository.InsertMessage(s.ctx, taken)
+	if !errors.Is(err, domain.ErrAttachmentNotFound) {
+		return fmt.Errorf("insert message with a sent attachment: got %v, want %s", err, domain.ErrAttachmentNotFound)
+	}
+
+	_, err = s.repository.GetMessage(s.ctx, fromID, toID, taken.ID)
+	if !errors.Is(err, domain.ErrMessageNotFound) {
+		return fmt.Errorf("get message with a sent attachment: got %v, want %s", err, domain.ErrMessageNotFound)
+	}
+
+	got, err := s.repository.GetMessage(s.ctx, toID, fromID, message.ID)
+	if err != nil {
+		return fmt.Errorf("get message: %w", err)
+	}
+
+	if len(got.Attachments) != 2 ||
+		got.Attachments[0].ID != attachmentIDs[1] ||
+		got.Attachments[1].ID != attachmentIDs[0] {
+		return fmt.Errorf("got attachments %+v, want them in the sent order", got.Attachments)
+	}
+
+	first := got.Attachments[0]
+
+	if first.MessageID == nil || *first.MessageID != message.ID {
+		return fmt.Errorf("got message id %v, want %d", first.MessageID, message.ID)
+	}
+
+	if first.Placeholder != attachment.Placeholder ||
+		len(first.Thumbnails) != 1 ||
+		first.Thumbnails[0].BlobKey != "thumbnails/160" ||
+		first.Thumbnails[0].URL == "" {
+		return fmt.Errorf("got %+v, want the generated thumbnails", first)
+	}
+
+	if first.URL == "" || first.Width != 640 || first.Height != 480 {
+		return fmt.Errorf("got %+v, want the uploaded attachment", first)
+	}
+
+	deletedAt := now()
+	got.Content = ""
+	got.DeletedAt = &deletedAt
+
+	if err = s.repository.DeleteMessage(s.ctx, got); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	got, err = s.repository.GetMessage(s.ctx, fromID, toID, message.ID)
+	if err != nil {
+		return fmt.Errorf("get tombstone: %w", err)
+	}
+
+	if len(got.AttachmentIDs) != 0 || len(got.Attachments) != 0 {
+		return fmt.Errorf("got attachments %v, want them removed with the message", got.AttachmentIDs)
+	}
+
+	for _, id := range attachmentIDs {
+		_, err = s.repository.GetAttachment(s.ctx, id)
+		if !errors.Is(err, domain.ErrAttachmentNotFound) {
+			return fmt.Errorf("get attachment of deleted message: got %v, want %s", err, domain.ErrAttachmentNotFound)
+		}
+	}
+
+	return nil
+}
+
+func checkBlocks(s *suite) error {
+	userID, blockedID := s.users()
+	otherID := s.id()
+
+	for _, id := range []uint64{blockedID, otherID, blockedID} {
+		if err := s.blockRepository.Block(s.ctx, userID, id); err != nil {
+			return fmt.Errorf("block %d: %w", id, err)
+		}
+	}
+
+	blocks, err := s.blockRepository.ListBlocked(s.ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list blocked: %w", err)
+	}
+
+	if len(blocks) != 2 {
+		return fmt.Errorf("got %+v, want the two blocked users once", blocks)
+	}
+
+	// blocks apply both ways
+	for _, ids := range [][2]uint64{{userID, blockedID}, {blockedID, userID}} {
+		blocked, err := s.blockRepository.IsBlocked(s.ctx, ids[0], ids[1])
+		if err != nil {
+			return fmt.Errorf("is blocked: %w", err)
+		}
+
+		if !blocked {
+			return fmt.Errorf("%d and %d are not blocked", ids[0], ids[1])
+		}
+	}
+
+	peers, err := s.blockRepository.ListBlockedPeers(s.ctx, blockedID)
+	if err != nil {
+		return fmt.Errorf("list blocked peers: %w", err)
+	}
+
+	if !slices.Equal(peers, []uint64{userID}) {
+		return fmt.Errorf("got peers %v, want [%d]", peers, userID)
+	}
+
+	if err = s.blockRepository.Unblock(s.ctx, userID, blockedID); err != nil {
+		return fmt.Errorf("unblock: %w", err)
+	}
+
+	blocked, err := s.blockRepository.IsBlocked(s.ctx, blockedID, userID)
+	if err != nil {
+		return fmt.Errorf("is blocked after unblock: %w", err)
+	}
+
+	if blocked {
+		return fmt.Errorf("still blocked after unblock")
+	}
+
+	return nil
+}
+
+func checkConversations(s *suite) error {
+	userID, peerID := s.users()
+
+	first, err := s.send(userID, peerID, "first")
+	if err != nil {
+		return err
+	}
+
+	last, err := s.send(peerID, userID, "last")
+	if err != nil {
+		return err
+	}
+
+	for _, message := range []*domain.Message{first, last, first} {
+		if err = s.conversationRepository.UpdateLastMessage(s.ctx, message); err != nil {
+			return fmt.Errorf("update last message: %w", err)
+		}
+	}
+
+	mutedUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+
+	settings := &domain.ConversationSettings{
+		Muted:      true,
+		MutedUntil: &mutedUntil,
+		Archived:   true,
+	}
+
+	if err = s.conversationRepository.UpdateSettings(s.ctx, userID, peerID, settings); err != nil {
+		return fmt.Errorf("update settings: %w", err)
+	}
+
+	conversation, err := s.conversationRepository.GetConversation(s.ctx, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("get conversation: %w", err)
+	}
+
+	if conversation.LastMessageID != last.ID ||
+		!conversation.Muted ||
+		conversation.MutedUntil == nil ||
+		!conversation.MutedUntil.Equal(mutedUntil) ||
+		!conversation.Archived {
+		return fmt.Errorf("got %+v, want the last message muted and archived", conversation)
+	}
+
+	// settings are not shared with the peer
+	peer, err := s.conversationRepository.ListConversations(s.ctx, peerID)
+	if err != nil {
+		return fmt.Errorf("list conversations of the peer: %w", err)
+	}
+
+	if len(peer) != 1 || peer[0].PeerID != userID || peer[0].Muted || peer[0].Archived {
+		return fmt.Errorf("got %+v, want the conversation without settings", peer)
+	}
+
+	newer, err := s.send(peerID, userID, "unarchives")
+	if err != nil {
+		return err
+	}
+
+	if err = s.conversationRepository.UpdateLastMessage(s.ctx, newer); err != nil {
+		return fmt.Errorf("update last message: %w", err)
+	}
+
+	conversation, err = s.conversationRepository.GetConversation(s.ctx, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("get conversation after new message: %w", err)
+	}
+
+	if conversation.Archived || !conversation.Muted {
+		return fmt.Errorf("got %+v, want it unarchived and still muted", conversation)
+	}
+
+	empty, err := s.conversationRepository.GetConversation(s.ctx, userID, s.id())
+	if err != nil {
+		return fmt.Errorf("get missing conversation: %w", err)
+	}
+
+	if empty.LastMessageAt != nil || empty.Muted {
+		return fmt.Errorf("got %+v, want an empty conversation", empty)
+	}
+
+	return nil
+}
+
+func checkUsers(s *suite) error {
+	userID := s.id()
+
+	exists, err := s.userRepository.Exists(s.ctx, userID)
+	if err != nil {
+		return fmt.Errorf("exists before register: %w", err)
+	}
+
+	if exists {
+		return fmt.Errorf("%d exists before registering", userID)
+	}
+
+	// registering again is how reconnecting users are seen
+	for range 2 {
+		if err = s.userRepository.Register(s.ctx, userID); err != nil {
+			return fmt.Errorf("register: %w", err)
+		}
+	}
+
+	exists, err = s.userRepository.Exists(s.ctx, userID)
+	if err != nil {
+		return fmt.Errorf("exists: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("%d doesn't exist after registering", userID)
+	}
+
+	// replaced, then removed
+	for _, want := range []string{"trusted", "bot", ""} {
+		if err = s.userRepository.SetTier(s.ctx, userID, want); err != nil {
+			return fmt.Errorf("set tier %q: %w", want, err)
+		}
+
+		tier, err := s.userRepository.GetTier(s.ctx, userID)
+		if err != nil {
+			return fmt.Errorf("get tier: %w", err)
+		}
+
+		if tier != want {
+			return fmt.Errorf("got tier %q, want %q", tier, want)
+		}
+	}
+
+	return nil
+}
+
+func checkReviews(s *suite) error {
+	flaggedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	reviews := []domain.Review{
+		{MessageID: s.id(), FromID: s.id(), ToID: s.id(), Content: "first", Reason: "spam", FlaggedAt: flaggedAt},
+		{MessageID: s.id(), FromID: s.id(), ToID: s.id(), Content: "second", Reason: "spam", FlaggedAt: flaggedAt.Add(time.Second)},
+	}
+
+	for i := range reviews {
+		reviews[i].Status = domain.ReviewStatusPending
+
+		if err := s.reviewRepository.AddReview(s.ctx, &reviews[i]); err != nil {
+			return fmt.Errorf("add review: %w", err)
+		}
+	}
+
+	pending, err := s.reviewRepository.ListReviews(s.ctx, domain.ReviewStatusPending, domain.MaxReviewLimit)
+	if err != nil {
+		return fmt.Errorf("list pending: %w", err)
+	}
+
+	if ids := reviewIDs(pending, reviews); !slices.Equal(ids, []uint64{reviews[0].MessageID, reviews[1].MessageID}) {
+		return fmt.Errorf("got pending %v, want both reviews oldest first", ids)
+	}
+
+	review, err := s.reviewRepository.GetReview(s.ctx, reviews[0].MessageID)
+	if err != nil {
+		return fmt.Errorf("get review: %w", err)
+	}
+
+	if review.Content != "first" || review.Reason != "spam" || review.ToID != reviews[0].ToID ||
+		!review.FlaggedAt.Equal(flaggedAt) || review.ReviewedAt != nil {
+		return fmt.Errorf("got %+v, want %+v", review, reviews[0])
+	}
+
+	reviewedAt := flaggedAt.Add(time.Minute)
+
+	review.Status = domain.ReviewStatusRemoved
+	review.ReviewedAt = &reviewedAt
+
+	if err = s.reviewRepository.UpdateReview(s.ctx, review); err != nil {
+		return fmt.Errorf("update review: %w", err)
+	}
+
+	pending, err = s.reviewRepository.ListReviews(s.ctx, domain.ReviewStatusPending, domain.MaxReviewLimit)
+	if err != nil {
+		return fmt.Errorf("list pending after update: %w", err)
+	}
+
+	if ids := reviewIDs(pending, reviews); !slices.Equal(ids, []uint64{reviews[1].MessageID}) {
+		return fmt.Errorf("got pending %v after removing, want only the second review", ids)
+	}
+
+	removed, err := s.reviewRepository.ListReviews(s.ctx, domain.ReviewStatusRemoved, domain.MaxReviewLimit)
+	if err != nil {
+		return fmt.Errorf("list removed: %w", err)
+	}
+
+	if ids := reviewIDs(removed, reviews); !slices.Equal(ids, []uint64{reviews[0].MessageID}) {
+		return fmt.Errorf("got removed %v, want only the first review", ids)
+	}
+
+	review, err = s.reviewRepository.GetReview(s.ctx, reviews[0].MessageID)
+	if err != nil {
+		return fmt.Errorf("get review after update: %w", err)
+	}
+
+	if review.ReviewedAt == nil || !review.ReviewedAt.Equal(reviewedAt) {
+		return fmt.Errorf("got reviewed at %v, want %v", review.ReviewedAt, reviewedAt)
+	}
+
+	if _, err = s.reviewRepository.GetReview(s.ctx, s.id()); !errors.Is(err, domain.ErrReviewNotFound) {
+		return fmt.Errorf("got %v for an unknown message, want %v", err, domain.ErrReviewNotFound)
+	}
+
+	return nil
+}
+
+// Ids of the listed reviews among the wanted ones, the
+// database may hold the reviews of earlier runs
+func reviewIDs(listed, wanted []domain.Review) []uint64 {
+	var ids []uint64
+
+	for _, review := range listed {
+		if slices.ContainsFunc(wanted, func(w domain.Review) bool {
+			return w.MessageID == review.MessageID
+		}) {
+			ids = append(ids, review.MessageID)
+		}
+	}
+
+	return ids
+}
+
+func checkReports(s *suite) error {
+	createdAt := time.Now().UTC().Truncate(time.Millisecond)
+	messageID, reporterID, reportedID := s.id(), s.id(), s.id()
+
+	report := domain.Report{
+		ID:         s.id(),
+		MessageID:  messageID,
+		ReporterID: reporterID,
+		ReportedID: reportedID,
+		Reason:     domain.ReportReasonSpam,
+		Details:    "sells followers",
+		Context: []domain.Message{
+			*domain.NewMessage(messageID, reportedID, reporterID, "buy followers"),
+		},
+		Status:    domain.ReportStatusOpen,
+		CreatedAt: createdAt,
+	}
+
+	if err := s.reportRepository.AddReport(s.ctx, &report); err != nil {
+		return fmt.Errorf("add report: %w", err)
+	}
+
+	again := report
+	again.ID = s.id()
+
+	if err := s.reportRepository.AddReport(s.ctx, &again); !errors.Is(err, domain.ErrAlreadyReported) {
+		return fmt.Errorf("got %v reporting again, want %v", err, domain.ErrAlreadyReported)
+	}
+
+	got, err := s.reportRepository.GetReport(s.ctx, report.ID)
+	if err != nil {
+		return fmt.Errorf("get report: %w", err)
+	}
+
+	if got.MessageID != messageID || got.ReportedID != reportedID || got.Details != report.Details ||
+		!got.CreatedAt.Equal(createdAt) || len(got.Context) != 1 || got.Context[0].Content != "buy followers" {
+		return fmt.Errorf("got %+v, want %+v", got, report)
+	}
+
+	open, err := s.reportRepository.ListReports(s.ctx, domain.ReportStatusOpen, domain.MaxReportLimit)
+	if err != nil {
+		return fmt.Errorf("list open: %w", err)
+	}
+
+	if !slices.ContainsFunc(open, func(r domain.Report) bool { return r.ID == report.ID }) {
+		return fmt.Errorf("report %d isn't open", report.ID)
+	}
+
+	resolvedAt := createdAt.Add(time.Minute)
+
+	got.Status = domain.ReportStatusResolved
+	got.Actions = append(got.Actions, domain.ReportActionDeleteMessage, domain.ReportActionSuspendUser)
+	got.Note = "repeated spam"
+	got.ResolvedAt = &resolvedAt
+
+	if err = s.reportRepository.UpdateReport(s.ctx, got); err != nil {
+		return fmt.Errorf("update report: %w", err)
+	}
+
+	open, err = s.reportRepository.ListReports(s.ctx, domain.ReportStatusOpen, domain.MaxReportLimit)
+	if err != nil {
+		return fmt.Errorf("list open after update: %w", err)
+	}
+
+	if slices.ContainsFunc(open, func(r domain.Report) bool { return r.ID == report.ID }) {
+		return fmt.Errorf("report %d is still open after resolving it", report.ID)
+	}
+
+	resolved, err := s.reportRepository.ListReports(s.ctx, domain.ReportStatusResolved, domain.MaxReportLimit)
+	if err != nil {
+		return fmt.Errorf("list resolved: %w", err)
+	}
+
+	i := slices.IndexFunc(resolved, func(r domain.Report) bool { return r.ID == report.ID })
+	if i < 0 {
+		return fmt.Errorf("report %d isn't resolved", report.ID)
+	}
+
+	got = &resolved[i]
+
+	if !slices.Equal(got.Actions, []string{domain.ReportActionDeleteMessage, domain.ReportActionSuspendUser}) ||
+		got.Note != "repeated spam" || got.ResolvedAt == nil || !got.ResolvedAt.Equal(resolvedAt) {
+		return fmt.Errorf("got %+v after resolving", got)
+	}
+
+	if _, err = s.reportRepository.GetReport(s.ctx, s.id()); !errors.Is(err, domain.ErrReportNotFound) {
+		return fmt.Errorf("got %v for an unknown report, want %v", err, domain.ErrReportNotFound)
+	}
+
+	return nil
+}
+
+func checkSuspensions(s *suite) error {
+	userID := s.id()
+
+	suspension, err := s.userRepository.GetSuspension(s.ctx, userID)
+	if err != nil {
+		return fmt.Errorf("get suspension before suspending: %w", err)
+	}
+
+	if suspension != nil {
+		return fmt.Errorf("got %+v before suspending", suspension)
+	}
+
+	suspendedAt := time.Now().UTC().Truncate(time.Millisecond)
+	until := suspendedAt.Add(time.Hour)
+
+	for _, want := range []domain.Suspension{
+		{UserID: userID, Reason: "spam", SuspendedAt: suspendedAt},
+		// suspending again replaces the suspension
+		{UserID: userID, Reason: "scam", SuspendedAt: suspendedAt, Until: &until},
+	} {
+		if err = s.userRepository.Suspend(s.ctx, &want); err != nil {
+			return fmt.Errorf("suspend: %w", err)
+		}
+
+		suspension, err = s.userRepository.GetSuspension(s.ctx, userID)
+		if err != nil {
+			return fmt.Errorf("get suspension: %w", err)
+		}
+
+		if suspension == nil || suspension.Reason != want.Reason || !suspension.SuspendedAt.Equal(suspendedAt) ||
+			(suspension.Until == nil) != (want.Until == nil) ||
+			(want.Until != nil && !suspension.Until.Equal(*want.Until)) {
+			return fmt.Errorf("got %+v, want %+v", suspension, want)
+		}
+	}
+
+	if err = s.userRepository.Unsuspend(s.ctx, userID); err != nil {
+		return fmt.Errorf("unsuspend: %w", err)
+	}
+
+	suspension, err = s.userRepository.GetSuspension(s.ctx, userID)
+	if err != nil {
+		return fmt.Errorf("get suspension after unsuspending: %w", err)
+	}
+
+	if suspension != nil {
+		return fmt.Errorf("got %+v after unsuspending", suspension)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package conformance_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gocql/gocql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lam0glia/chat-system/migration"
+	"github.com/lam0glia/chat-system/repository"
+	"github.com/lam0glia/chat-system/repository/conformance"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sony/sonyflake"
+)
+
+// The databases are only checked when configured, they must be
+// empty development ones since the suite writes to them
+const (
+	cassandraHostsEnv = "CONFORMANCE_CASSANDRA_HOSTS"
+	postgresURLEnv    = "CONFORMANCE_POSTGRES_URL"
+)
+
+func TestMemory(t *testing.T) {
+	err := conformance.Run(
+		context.Background(),
+		repository.NewMemoryChat(),
+		repository.NewMemoryBlock(),
+		repository.NewMemoryConversation(),
+		repository.NewMemoryUser(),
+		repository.NewMemoryReview(),
+		repository.NewMemoryReport(),
+		newUIDGenerator(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	db, err := sql.Open(
+		"sqlite3",
+		"file:"+filepath.Join(t.TempDir(), "chat.db")+"?_busy_timeout=5000&_journal_mode=WAL",
+	)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(1)
+
+	migrator, err := migration.NewSQLMigrator(db, migration.SQLiteDialect)
+	if err != nil {
+		t.Fatalf("new migrator: %s", err)
+	}
+
+	runSQL(t, db, migrator)
+}
+
+func TestPostgres(t *testing.T) {
+	url := os.Getenv(postgresURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", postgresURLEnv)
+	}
+
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migration.NewSQLMigrator(db, migration.PostgresDialect)
+	if err != nil {
+		t.Fatalf("new migrator: %s", err)
+	}
+
+	runSQL(t, db, migrator)
+}
+
+func TestCassandra(t *testing.T) {
+	hosts := os.Getenv(cassandraHostsEnv)
+	if hosts == "" {
+		t.Skipf("%s is not set", cassandraHostsEnv)
+	}
+
+	cluster := gocql.NewCluster(strings.Split(hosts, ",")...)
+	cluster.Keyspace = "chat"
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatalf("create session: %s", err)
+	}
+
+	t.Cleanup(session.Close)
+
+	migrator, err := migration.NewCassandraMigrator(session)
+	if err != nil {
+		t.Fatalf("new migrator: %s", err)
+	}
+
+	if _, err = migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %s", err)
+	}
+
+	err = conformance.Run(
+		context.Background(),
+		repository.NewChat(session),
+		repository.NewBlock(session),
+		repository.NewConversation(session),
+		repository.NewUser(session),
+		repository.NewReview(session),
+		repository.NewReport(session),
+		newUIDGenerator(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runSQL(t *testing.T, db *sql.DB, migrator migration.Migrator) {
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %s", err)
+	}
+
+	err := conformance.Run(
+		context.Background(),
+		repository.NewSQLChat(db),
+		repository.NewSQLBlock(db),
+		repository.NewSQLConversation(db),
+		repository.NewSQLUser(db),
+		repository.NewSQLReview(db),
+		repository.NewSQLReport(db),
+		newUIDGenerator(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newUIDGenerator(t *testing.T) *sonyflake.Sonyflake {
+	uidGenerator, err := sonyflake.New(sonyflake.Settings{
+		MachineID: func() (uint16, error) {
+			return 1, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("new uid generator: %s", err)
+	}
+
+	return uidGenerator
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// hash of the connections by user of each node
+	connectionCountKeyFormat = "connections.%d"
+	connectionCountPattern   = "connections.*"
+	// a few report intervals, so a node that crashed
+	// stops being counted soon
+	connectionCountDuration = 30 * time.Second
+)
+
+type connectionCount struct {
+	db *redis.Client
+}
+
+func (r *connectionCount) ReportNode(ctx context.Context, nodeID uint16, counts map[uint64]int) error {
+	key := fmt.Sprintf(connectionCountKeyFormat, nodeID)
+
+	values := make(map[string]any, len(counts))
+
+	for userID, count := range counts {
+		values[strconv.FormatUint(userID, 10)] = count
+	}
+
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+
+		// an empty node is still reported, by the key alone
+		if len(values) == 0 {
+			pipe.HSet(ctx, key, "", 0)
+		} else {
+			pipe.HSet(ctx, key, values)
+		}
+
+		pipe.Expire(ctx, key, connectionCountDuration)
+
+		return nil
+	})
+
+	return err
+}
+
+func (r *connectionCount) RemoveNode(ctx context.Context, nodeID uint16) error {
+	return r.db.Del(ctx, fmt.Sprintf(connectionCountKeyFormat, nodeID)).Err()
+}
+
+func (r *connectionCount) CountUser(ctx context.Context, userID uint64) (int, error) {
+	keys, err := r.nodeKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+
+	for _, key := range keys {
+		count, err := r.db.HGet(ctx, key, strconv.FormatUint(userID, 10)).Int()
+		if err != nil && err != redis.Nil {
+			return 0, fmt.Errorf("get count of %s: %w", key, err)
+		}
+
+		total += count
+	}
+
+	return total, nil
+}
+
+func (r *connectionCount) Count(ctx context.Context) (domain.ConnectionCount, error) {
+	var count domain.ConnectionCount
+
+	keys, err := r.nodeKeys(ctx)
+	if err != nil {
+		return count, err
+	}
+
+	// a user may be connected to many nodes
+	users := make(map[string]struct{})
+
+	for _, key := range keys {
+		counts, err := r.db.HGetAll(ctx, key).Result()
+		if err != nil {
+			return count, fmt.Errorf("get counts of %s: %w", key, err)
+		}
+
+		for userID, value := range counts {
+			n, err := strconv.Atoi(value)
+			if err != nil || userID == "" {
+				continue
+			}
+
+			users[userID] = struct{}{}
+			count.Connections += n
+		}
+
+		count.Nodes++
+	}
+
+	count.Users = len(users)
+
+	return count, nil
+}
+
+func (r *connectionCount) nodeKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+
+	iter := r.db.Scan(ctx, 0, connectionCountPattern, 0).Iterator()
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("scan nodes: %w", err)
+	}
+
+	return keys, nil
+}
+
+func NewConnectionCount(client *redis.Client) *connectionCount {
+	return &connectionCount{
+		db: client,
+	}
+}
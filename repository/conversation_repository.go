@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const conversationColumns = "peer_id, last_message_id, last_message_at, muted, muted_until, archived, pinned_at"
+
+type conversation struct {
+	db *gocql.Session
+}
+
+// Writes are last write wins, so a message written after a newer
+// one, by a concurrent send, may be kept as the last one
+func (r *conversation) UpdateLastMessage(ctx context.Context, message *domain.Message) error {
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	for _, ids := range [][2]uint64{{message.FromID, message.ToID}, {message.ToID, message.FromID}} {
+		batch.Query(
+			`UPDATE conversations SET
+				last_message_id = ?, last_message_at = ?, archived = false
+			WHERE
+				user_id = ? AND peer_id = ?`,
+			message.ID,
+			message.CreatedAt,
+			ids[0],
+			ids[1],
+		)
+	}
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *conversation) GetConversation(ctx context.Context, userID, peerID uint64) (*domain.Conversation, error) {
+	conversation := domain.Conversation{
+		PeerID: peerID,
+	}
+
+	err := r.db.Query(
+		fmt.Sprintf(
+			"SELECT %s FROM conversations WHERE user_id = ? AND peer_id = ?",
+			conversationColumns,
+		),
+		userID,
+		peerID,
+	).WithContext(ctx).Scan(r.fields(&conversation)...)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return &conversation, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+func (r *conversation) UpdateSettings(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	settings *domain.ConversationSettings,
+) error {
+	return r.db.Query(
+		`UPDATE conversations SET
+			muted = ?, muted_until = ?, archived = ?, pinned_at = ?
+		WHERE
+			user_id = ? AND peer_id = ?`,
+		settings.Muted,
+		settings.MutedUntil,
+		settings.Archived,
+		settings.PinnedAt,
+		userID,
+		peerID,
+	).WithContext(ctx).Exec()
+}
+
+func (r *conversation) ListConversations(ctx context.Context, userID uint64) ([]domain.Conversation, error) {
+	scanner := r.db.Query(
+		fmt.Sprintf("SELECT %s FROM conversations WHERE user_id = ?", conversationColumns),
+		userID,
+	).WithContext(ctx).Iter().Scanner()
+
+	var (
+		conversations []domain.Conversation
+		err           error
+	)
+
+	for scanner.Next() {
+		var conversation domain.Conversation
+
+		if err = scanner.Scan(r.fields(&conversation)...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return conversations, nil
+}
+
+func (r *conversation) fields(conversation *domain.Conversation) []any {
+	return []any{
+		&conversation.PeerID,
+		&conversation.LastMessageID,
+		&conversation.LastMessageAt,
+		&conversation.Muted,
+		&conversation.MutedUntil,
+		&conversation.Archived,
+		&conversation.PinnedAt,
+	}
+}
+
+func NewConversation(session *gocql.Session) *conversation {
+	return &conversation{
+		db: session,
+	}
+}
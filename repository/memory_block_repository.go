@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Blocks of a single process, lost on restart
+type memoryBlock struct {
+	mu sync.RWMutex
+	// blocked users by the user that blocked them
+	blocks map[uint64]map[uint64]time.Time
+}
+
+func (r *memoryBlock) Block(ctx context.Context, userID, blockedID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.blocks[userID] == nil {
+		r.blocks[userID] = make(map[uint64]time.Time)
+	}
+
+	if _, ok := r.blocks[userID][blockedID]; !ok {
+		r.blocks[userID][blockedID] = time.Now().UTC()
+	}
+
+	return nil
+}
+
+func (r *memoryBlock) Unblock(ctx context.Context, userID, blockedID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.blocks[userID], blockedID)
+
+	return nil
+}
+
+func (r *memoryBlock) ListBlocked(ctx context.Context, userID uint64) ([]domain.Block, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var blocks []domain.Block
+
+	for blockedID, createdAt := range r.blocks[userID] {
+		blocks = append(blocks, domain.Block{
+			UserID:    blockedID,
+			CreatedAt: createdAt,
+		})
+	}
+
+	slices.SortFunc(blocks, func(a, b domain.Block) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return blocks, nil
+}
+
+func (r *memoryBlock) IsBlocked(ctx context.Context, userID, peerID uint64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, blocked := r.blocks[userID][peerID]
+	_, blockedBy := r.blocks[peerID][userID]
+
+	return blocked || blockedBy, nil
+}
+
+func (r *memoryBlock) ListBlockedPeers(ctx context.Context, userID uint64) ([]uint64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var peers []uint64
+
+	for id, blocked := range r.blocks {
+		if id == userID {
+			for blockedID := range blocked {
+				peers = append(peers, blockedID)
+			}
+		} else if _, ok := blocked[userID]; ok {
+			peers = append(peers, id)
+		}
+	}
+
+	slices.Sort(peers)
+
+	return slices.Compact(peers), nil
+}
+
+func NewMemoryBlock() *memoryBlock {
+	return &memoryBlock{
+		blocks: make(map[uint64]map[uint64]time.Time),
+	}
+}
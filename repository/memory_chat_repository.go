@@ -0,0 +1,466 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Chat history of a single process, lost on restart
+type memoryChat struct {
+	mu sync.RWMutex
+	// messages of each conversation sorted by id
+	messages    map[string][]domain.Message
+	edits       map[string][]domain.MessageEdit
+	hidden      map[string]map[uint64]struct{}
+	reactions   map[string][]domain.Reaction
+	attachments map[uint64]domain.Attachment
+}
+
+// Key of the messages of a conversation by id
+func (r *memoryChat) messageKey(pair string, id uint64) string {
+	return fmt.Sprintf("%s.%d", pair, id)
+}
+
+// Key of the messages hidden by userID in a conversation
+func (r *memoryChat) hiddenKey(userID uint64, pair string) string {
+	return fmt.Sprintf("%d.%s", userID, pair)
+}
+
+func (r *memoryChat) find(pair string, id uint64) (int, bool) {
+	return slices.BinarySearchFunc(r.messages[pair], id, func(m domain.Message, id uint64) int {
+		return cmp.Compare(m.ID, id)
+	})
+}
+
+func (r *memoryChat) InsertMessage(ctx context.Context, message *domain.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pair := pairOf(message.FromID, message.ToID)
+
+	// taken by another message meanwhile
+	for _, attachmentID := range message.AttachmentIDs {
+		attachment, ok := r.attachments[attachmentID]
+		if !ok || (attachment.MessageID != nil && *attachment.MessageID != message.ID) {
+			return domain.ErrAttachmentNotFound
+		}
+	}
+
+	stored := *message
+	stored.AttachmentIDs = slices.Clone(message.AttachmentIDs)
+	stored.Attachments = nil
+	stored.Reactions = nil
+
+	i, found := r.find(pair, message.ID)
+	if found {
+		r.messages[pair][i] = stored
+	} else {
+		r.messages[pair] = slices.Insert(r.messages[pair], i, stored)
+	}
+
+	for _, attachmentID := range message.AttachmentIDs {
+		attachment := r.attachments[attachmentID]
+		attachment.MessageID = &stored.ID
+		r.attachments[attachmentID] = attachment
+	}
+
+	if message.ReplyToID != nil {
+		if i, ok := r.find(pair, *message.ReplyToID); ok {
+			root := &r.messages[pair][i]
+
+			createdAt := message.CreatedAt
+			root.LastReplyAt = &createdAt
+			root.ReplyCount++
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryChat) ListMessages(
+	ctx context.Context,
+	fromID,
+	toID uint64,
+	messageRange *domain.MessageRange,
+) ([]domain.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pair := pairOf(fromID, toID)
+
+	var messages []domain.Message
+
+	for _, message := range r.messages[pair] {
+		if messageRange.BeforeID != nil && message.ID >= *messageRange.BeforeID {
+			continue
+		}
+
+		if messageRange.AfterID != nil && message.ID <= *messageRange.AfterID {
+			continue
+		}
+
+		messages = append(messages, message)
+	}
+
+	if messageRange.Descending {
+		slices.Reverse(messages)
+	}
+
+	if len(messages) > messageRange.Limit {
+		messages = messages[:messageRange.Limit]
+	}
+
+	return r.decorateMessages(fromID, pair, messages), nil
+}
+
+func (r *memoryChat) ListThread(
+	ctx context.Context,
+	fromID,
+	toID,
+	rootID uint64,
+	afterID *uint64,
+	limit int,
+) ([]domain.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pair := pairOf(fromID, toID)
+
+	var messages []domain.Message
+
+	for _, message := range r.messages[pair] {
+		if len(messages) == limit {
+			break
+		}
+
+		if message.ReplyToID == nil || *message.ReplyToID != rootID {
+			continue
+		}
+
+		if afterID != nil && message.ID <= *afterID {
+			continue
+		}
+
+		messages = append(messages, message)
+	}
+
+	return domain.VisibleMessages(r.decorateMessages(fromID, pair, messages)), nil
+}
+
+func (r *memoryChat) CountReplies(ctx context.Context, fromID, toID, rootID uint64) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pair := pairOf(fromID, toID)
+
+	i, ok := r.find(pair, rootID)
+	if !ok {
+		return 0, nil
+	}
+
+	return r.messages[pair][i].ReplyCount, nil
+}
+
+func (r *memoryChat) GetMessage(ctx context.Context, fromID, toID, id uint64) (*domain.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pair := pairOf(fromID, toID)
+
+	i, ok := r.find(pair, id)
+	if !ok {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	message := r.copyMessage(r.messages[pair][i])
+
+	message.Attachments = r.messageAttachments(&message)
+
+	return &message, nil
+}
+
+func (r *memoryChat) ScanMessages(ctx context.Context, handle func(*domain.Message) error) error {
+	r.mu.RLock()
+
+	var messages []domain.Message
+
+	for _, conversation := range r.messages {
+		for _, message := range conversation {
+			messages = append(messages, r.copyMessage(message))
+		}
+	}
+
+	r.mu.RUnlock()
+
+	for i := range messages {
+		if err := handle(&messages[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryChat) UpdateMessageContent(
+	ctx context.Context,
+	message *domain.Message,
+	previousContent string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pair := pairOf(message.FromID, message.ToID)
+	key := r.messageKey(pair, message.ID)
+
+	r.edits[key] = append(r.edits[key], domain.MessageEdit{
+		MessageID: message.ID,
+		Content:   previousContent,
+		EditedAt:  *message.EditedAt,
+	})
+
+	if i, ok := r.find(pair, message.ID); ok {
+		stored := &r.messages[pair][i]
+
+		editedAt := *message.EditedAt
+		stored.Content = message.Content
+		stored.EditedAt = &editedAt
+	}
+
+	return nil
+}
+
+func (r *memoryChat) ListMessageEdits(
+	ctx context.Context,
+	fromID,
+	toID,
+	messageID uint64,
+) ([]domain.MessageEdit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.edits[r.messageKey(pairOf(fromID, toID), messageID)]), nil
+}
+
+func (r *memoryChat) DeleteMessage(ctx context.Context, message *domain.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pair := pairOf(message.FromID, message.ToID)
+
+	if i, ok := r.find(pair, message.ID); ok {
+		stored := &r.messages[pair][i]
+
+		deletedAt := *message.DeletedAt
+		stored.Content = message.Content
+		stored.DeletedAt = &deletedAt
+		stored.AttachmentIDs = nil
+	}
+
+	if message.ReplyToID != nil {
+		if i, ok := r.find(pair, *message.ReplyToID); ok && r.messages[pair][i].ReplyCount > 0 {
+			r.messages[pair][i].ReplyCount--
+		}
+	}
+
+	delete(r.edits, r.messageKey(pair, message.ID))
+
+	for _, attachmentID := range message.AttachmentIDs {
+		delete(r.attachments, attachmentID)
+	}
+
+	return nil
+}
+
+func (r *memoryChat) HideMessage(ctx context.Context, userID, peerID, messageID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.hiddenKey(userID, pairOf(userID, peerID))
+
+	if r.hidden[key] == nil {
+		r.hidden[key] = make(map[uint64]struct{})
+	}
+
+	r.hidden[key][messageID] = struct{}{}
+
+	return nil
+}
+
+func (r *memoryChat) ListHiddenMessageIDs(
+	ctx context.Context,
+	userID,
+	peerID,
+	firstID,
+	lastID uint64,
+) (map[uint64]struct{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hidden := make(map[uint64]struct{})
+
+	for id := range r.hidden[r.hiddenKey(userID, pairOf(userID, peerID))] {
+		if id >= firstID && id <= lastID {
+			hidden[id] = struct{}{}
+		}
+	}
+
+	return hidden, nil
+}
+
+func (r *memoryChat) AddReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pair := pairOf(reaction.UserID, peerID)
+
+	i, found := slices.BinarySearchFunc(r.reactions[pair], *reaction, r.compareReactions)
+	if !found {
+		r.reactions[pair] = slices.Insert(r.reactions[pair], i, *reaction)
+	}
+
+	return nil
+}
+
+func (r *memoryChat) RemoveReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pair := pairOf(reaction.UserID, peerID)
+
+	i, found := slices.BinarySearchFunc(r.reactions[pair], *reaction, r.compareReactions)
+	if found {
+		r.reactions[pair] = slices.Delete(r.reactions[pair], i, i+1)
+	}
+
+	return nil
+}
+
+// Same order as the reactions table: message, emoji and user
+func (r *memoryChat) compareReactions(a, b domain.Reaction) int {
+	return cmp.Or(
+		cmp.Compare(a.MessageID, b.MessageID),
+		cmp.Compare(a.Emoji, b.Emoji),
+		cmp.Compare(a.UserID, b.UserID),
+	)
+}
+
+func (r *memoryChat) InsertAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *attachment
+	stored.Thumbnails = slices.Clone(attachment.Thumbnails)
+
+	r.attachments[attachment.ID] = stored
+
+	return nil
+}
+
+func (r *memoryChat) UpdateAttachmentThumbnails(ctx context.Context, attachment *domain.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.attachments[attachment.ID]
+	if !ok {
+		return domain.ErrAttachmentNotFound
+	}
+
+	stored.Thumbnails = slices.Clone(attachment.Thumbnails)
+	stored.Placeholder = attachment.Placeholder
+
+	r.attachments[attachment.ID] = stored
+
+	return nil
+}
+
+func (r *memoryChat) GetAttachment(ctx context.Context, id uint64) (*domain.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attachment, ok := r.getAttachment(id)
+	if !ok {
+		return nil, domain.ErrAttachmentNotFound
+	}
+
+	return &attachment, nil
+}
+
+// Copy of the stored attachment with its urls
+func (r *memoryChat) getAttachment(id uint64) (domain.Attachment, bool) {
+	attachment, ok := r.attachments[id]
+	if !ok {
+		return attachment, false
+	}
+
+	attachment.Thumbnails = slices.Clone(attachment.Thumbnails)
+	attachment.SetURLs()
+
+	return attachment, true
+}
+
+func (r *memoryChat) messageAttachments(message *domain.Message) []domain.Attachment {
+	var attachments []domain.Attachment
+
+	for _, id := range message.AttachmentIDs {
+		if attachment, ok := r.getAttachment(id); ok {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments
+}
+
+func (r *memoryChat) copyMessage(message domain.Message) domain.Message {
+	message.AttachmentIDs = slices.Clone(message.AttachmentIDs)
+
+	return message
+}
+
+// Copies the messages, flagging the ones hidden by userID
+// and filling the aggregated data of the others
+func (r *memoryChat) decorateMessages(
+	userID uint64,
+	pair string,
+	messages []domain.Message,
+) []domain.Message {
+	hidden := r.hidden[r.hiddenKey(userID, pair)]
+
+	reactions := make(map[uint64][]domain.ReactionCount)
+
+	for _, reaction := range r.reactions[pair] {
+		countReaction(reactions, &reaction, userID)
+	}
+
+	var result []domain.Message
+
+	for _, message := range messages {
+		message = r.copyMessage(message)
+
+		if _, ok := hidden[message.ID]; ok {
+			message.Hidden = true
+			message.ReplyCount = 0
+		} else {
+			message.Reactions = reactions[message.ID]
+			message.Attachments = r.messageAttachments(&message)
+		}
+
+		result = append(result, message)
+	}
+
+	return result
+}
+
+func NewMemoryChat() *memoryChat {
+	return &memoryChat{
+		messages:    make(map[string][]domain.Message),
+		edits:       make(map[string][]domain.MessageEdit),
+		hidden:      make(map[string]map[uint64]struct{}),
+		reactions:   make(map[string][]domain.Reaction),
+		attachments: make(map[uint64]domain.Attachment),
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Counts of a single process, so nothing expires
+type memoryConnectionCount struct {
+	mu    sync.RWMutex
+	nodes map[uint16]map[uint64]int
+}
+
+func (r *memoryConnectionCount) ReportNode(ctx context.Context, nodeID uint16, counts map[uint64]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nodes[nodeID] = maps.Clone(counts)
+
+	return nil
+}
+
+func (r *memoryConnectionCount) RemoveNode(ctx context.Context, nodeID uint16) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.nodes, nodeID)
+
+	return nil
+}
+
+func (r *memoryConnectionCount) CountUser(ctx context.Context, userID uint64) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int
+
+	for _, counts := range r.nodes {
+		total += counts[userID]
+	}
+
+	return total, nil
+}
+
+func (r *memoryConnectionCount) Count(ctx context.Context) (domain.ConnectionCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := domain.ConnectionCount{
+		Nodes: len(r.nodes),
+	}
+
+	users := make(map[uint64]struct{})
+
+	for _, counts := range r.nodes {
+		for userID, n := range counts {
+			users[userID] = struct{}{}
+			count.Connections += n
+		}
+	}
+
+	count.Users = len(users)
+
+	return count, nil
+}
+
+func NewMemoryConnectionCount() *memoryConnectionCount {
+	return &memoryConnectionCount{
+		nodes: make(map[uint16]map[uint64]int),
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Conversations of a single process, lost on restart
+type memoryConversation struct {
+	mu sync.RWMutex
+	// conversations of each user by peer
+	conversations map[uint64]map[uint64]domain.Conversation
+}
+
+func (r *memoryConversation) UpdateLastMessage(ctx context.Context, message *domain.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, ids := range [][2]uint64{{message.FromID, message.ToID}, {message.ToID, message.FromID}} {
+		conversation := r.get(ids[0], ids[1])
+
+		if conversation.LastMessageID >= message.ID {
+			continue
+		}
+
+		createdAt := message.CreatedAt
+
+		conversation.LastMessageID = message.ID
+		conversation.LastMessageAt = &createdAt
+		conversation.Archived = false
+
+		r.conversations[ids[0]][ids[1]] = conversation
+	}
+
+	return nil
+}
+
+func (r *memoryConversation) GetConversation(ctx context.Context, userID, peerID uint64) (*domain.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversation, ok := r.conversations[userID][peerID]
+	if !ok {
+		conversation.PeerID = peerID
+	}
+
+	return &conversation, nil
+}
+
+func (r *memoryConversation) UpdateSettings(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	settings *domain.ConversationSettings,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation := r.get(userID, peerID)
+	conversation.ConversationSettings = *settings
+
+	r.conversations[userID][peerID] = conversation
+
+	return nil
+}
+
+func (r *memoryConversation) ListConversations(ctx context.Context, userID uint64) ([]domain.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var conversations []domain.Conversation
+
+	for _, conversation := range r.conversations[userID] {
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, nil
+}
+
+// Must be called with the lock held
+func (r *memoryConversation) get(userID, peerID uint64) domain.Conversation {
+	if r.conversations[userID] == nil {
+		r.conversations[userID] = make(map[uint64]domain.Conversation)
+	}
+
+	conversation, ok := r.conversations[userID][peerID]
+	if !ok {
+		conversation.PeerID = peerID
+	}
+
+	return conversation
+}
+
+func NewMemoryConversation() *memoryConversation {
+	return &memoryConversation{
+		conversations: make(map[uint64]map[uint64]domain.Conversation),
+	}
+}
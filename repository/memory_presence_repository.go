@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Presence of a single process, expirations are
+// notified like redis keyspace events
+type memoryPresence struct {
+	mu sync.Mutex
+	// expiration timer of each online user
+	online   map[uint64]*time.Timer
+	claims   map[uint64]time.Time
+	watchers map[chan<- uint64]context.Context
+}
+
+func (r *memoryPresence) SetKeyExpiration(ctx context.Context, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// like EXPIRE, missing keys are not created
+	if _, ok := r.online[userID]; ok {
+		r.arm(userID)
+	}
+
+	return nil
+}
+
+func (r *memoryPresence) UpdateUserStatus(ctx context.Context, userID uint64, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.arm(userID)
+
+	return nil
+}
+
+// Replaces the expiration timer of the user, a timer that
+// already fired is ignored once it sees it was replaced
+func (r *memoryPresence) arm(userID uint64) {
+	if timer, ok := r.online[userID]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(onlinePresenceDuration, func() {
+		r.expire(userID, timer)
+	})
+
+	r.online[userID] = timer
+}
+
+func (r *memoryPresence) DeleteUserStatus(ctx context.Context, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if timer, ok := r.online[userID]; ok {
+		timer.Stop()
+		delete(r.online, userID)
+	}
+
+	return nil
+}
+
+func (r *memoryPresence) IsOnline(ctx context.Context, userID uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.online[userID]
+
+	return ok, nil
+}
+
+func (r *memoryPresence) WatchExpiredUsers(ctx context.Context, expired chan<- uint64) error {
+	r.mu.Lock()
+	r.watchers[expired] = ctx
+	r.mu.Unlock()
+
+	<-ctx.Done()
+
+	r.mu.Lock()
+	delete(r.watchers, expired)
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *memoryPresence) ClaimExpiredUser(ctx context.Context, userID uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if claimedAt, ok := r.claims[userID]; ok && time.Since(claimedAt) < reaperClaimDuration {
+		return false, nil
+	}
+
+	r.claims[userID] = time.Now()
+
+	return true, nil
+}
+
+func (r *memoryPresence) expire(userID uint64, timer *time.Timer) {
+	r.mu.Lock()
+
+	// the presence was renewed or deleted in the meantime
+	if r.online[userID] != timer {
+		r.mu.Unlock()
+		return
+	}
+
+	delete(r.online, userID)
+
+	watchers := make(map[chan<- uint64]context.Context, len(r.watchers))
+
+	for expired, ctx := range r.watchers {
+		watchers[expired] = ctx
+	}
+
+	r.mu.Unlock()
+
+	for expired, ctx := range watchers {
+		select {
+		case expired <- userID:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func NewMemoryPresence() *memoryPresence {
+	return &memoryPresence{
+		online:   make(map[uint64]*time.Timer),
+		claims:   make(map[uint64]time.Time),
+		watchers: make(map[chan<- uint64]context.Context),
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type reportKey struct {
+	messageID  uint64
+	reporterID uint64
+}
+
+// Reports of a single process, lost on restart
+type memoryReport struct {
+	mu      sync.RWMutex
+	reports map[uint64]domain.Report
+	// ids by message and reporter
+	reported map[reportKey]uint64
+}
+
+func (r *memoryReport) AddReport(ctx context.Context, report *domain.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reportKey{report.MessageID, report.ReporterID}
+
+	if _, ok := r.reported[key]; ok {
+		return domain.ErrAlreadyReported
+	}
+
+	r.reported[key] = report.ID
+	r.reports[report.ID] = cloneReport(report)
+
+	return nil
+}
+
+func (r *memoryReport) GetReport(ctx context.Context, id uint64) (*domain.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report, ok := r.reports[id]
+	if !ok {
+		return nil, domain.ErrReportNotFound
+	}
+
+	report = cloneReport(&report)
+
+	return &report, nil
+}
+
+func (r *memoryReport) ListReports(ctx context.Context, status string, limit int) ([]domain.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var reports []domain.Report
+
+	for _, report := range r.reports {
+		if report.Status == status {
+			reports = append(reports, cloneReport(&report))
+		}
+	}
+
+	slices.SortFunc(reports, func(a, b domain.Report) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	if len(reports) > limit {
+		reports = reports[:limit]
+	}
+
+	return reports, nil
+}
+
+func (r *memoryReport) UpdateReport(ctx context.Context, report *domain.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.reports[report.ID]
+	if !ok {
+		return nil
+	}
+
+	stored.Status = report.Status
+	stored.Actions = slices.Clone(report.Actions)
+	stored.Note = report.Note
+	stored.ResolvedAt = report.ResolvedAt
+
+	r.reports[report.ID] = stored
+
+	return nil
+}
+
+// The callers may append to the actions
+func cloneReport(report *domain.Report) domain.Report {
+	c := *report
+	c.Actions = slices.Clone(report.Actions)
+	c.Context = slices.Clone(report.Context)
+
+	return c
+}
+
+func NewMemoryReport() *memoryReport {
+	return &memoryReport{
+		reports:  make(map[uint64]domain.Report),
+		reported: make(map[reportKey]uint64),
+	}
+}
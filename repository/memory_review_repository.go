@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Reviews of a single process, lost on restart
+type memoryReview struct {
+	mu sync.RWMutex
+	// by message
+	reviews map[uint64]domain.Review
+}
+
+func (r *memoryReview) AddReview(ctx context.Context, review *domain.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reviews[review.MessageID]; !ok {
+		r.reviews[review.MessageID] = *review
+	}
+
+	return nil
+}
+
+func (r *memoryReview) ListReviews(ctx context.Context, status string, limit int) ([]domain.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var reviews []domain.Review
+
+	for _, review := range r.reviews {
+		if review.Status == status {
+			reviews = append(reviews, review)
+		}
+	}
+
+	slices.SortFunc(reviews, func(a, b domain.Review) int {
+		if c := a.FlaggedAt.Compare(b.FlaggedAt); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.MessageID, b.MessageID)
+	})
+
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+	}
+
+	return reviews, nil
+}
+
+func (r *memoryReview) GetReview(ctx context.Context, messageID uint64) (*domain.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	review, ok := r.reviews[messageID]
+	if !ok {
+		return nil, domain.ErrReviewNotFound
+	}
+
+	return &review, nil
+}
+
+func (r *memoryReview) UpdateReview(ctx context.Context, review *domain.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.reviews[review.MessageID]
+	if !ok {
+		return nil
+	}
+
+	stored.Status = review.Status
+	stored.ReviewedAt = review.ReviewedAt
+
+	r.reviews[review.MessageID] = stored
+
+	return nil
+}
+
+func NewMemoryReview() *memoryReview {
+	return &memoryReview{
+		reviews: make(map[uint64]domain.Review),
+	}
+}
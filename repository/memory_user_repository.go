@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Users seen by a single process, lost on restart
+type memoryUser struct {
+	mu          sync.RWMutex
+	firstSeenAt map[uint64]time.Time
+	suspensions map[uint64]domain.Suspension
+	tiers       map[uint64]string
+}
+
+func (r *memoryUser) Register(ctx context.Context, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.firstSeenAt[userID]; !ok {
+		r.firstSeenAt[userID] = time.Now().UTC()
+	}
+
+	return nil
+}
+
+func (r *memoryUser) Exists(ctx context.Context, userID uint64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.firstSeenAt[userID]
+
+	return ok, nil
+}
+
+func (r *memoryUser) Suspend(ctx context.Context, suspension *domain.Suspension) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.suspensions[suspension.UserID] = *suspension
+
+	return nil
+}
+
+func (r *memoryUser) Unsuspend(ctx context.Context, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.suspensions, userID)
+
+	return nil
+}
+
+func (r *memoryUser) GetSuspension(ctx context.Context, userID uint64) (*domain.Suspension, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	suspension, ok := r.suspensions[userID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &suspension, nil
+}
+
+func (r *memoryUser) SetTier(ctx context.Context, userID uint64, tier string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tier == "" {
+		delete(r.tiers, userID)
+	} else {
+		r.tiers[userID] = tier
+	}
+
+	return nil
+}
+
+func (r *memoryUser) GetTier(ctx context.Context, userID uint64) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.tiers[userID], nil
+}
+
+func NewMemoryUser() *memoryUser {
+	return &memoryUser{
+		firstSeenAt: make(map[uint64]time.Time),
+		suspensions: make(map[uint64]domain.Suspension),
+		tiers:       make(map[uint64]string),
+	}
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/sony/sonyflake"
+)
+
+// Time span covered by each partition of a conversation.
+// Changing it would orphan the rows already written, so
+// existing data must be migrated again if it ever changes
+const messageBucketSize = 7 * 24 * time.Hour
+
+// Partition of the message, derived from the time
+// component of its sonyflake id
+func messageBucket(id uint64) int64 {
+	return int64(sonyflake.ElapsedTime(id) / messageBucketSize)
+}
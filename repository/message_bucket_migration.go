@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const migrationPageSize = 500
+
+type messageBucketMigration struct {
+	db *gocql.Session
+}
+
+// Copies every row of the legacy messages table, partitioned by
+// pair only, into messages_by_bucket. Rows are upserted by
+// primary key, so an interrupted run can simply be restarted.
+// The legacy table only has the columns of the first schema,
+// edits, deletions, threads and attachments came with the buckets
+func (m *messageBucketMigration) Run(ctx context.Context) (int, error) {
+	scanner := m.db.Query(
+		"SELECT pair, id, content, created_at, from_id, to_id FROM messages",
+	).WithContext(ctx).PageSize(migrationPageSize).Iter().Scanner()
+
+	// buckets already registered by this run
+	buckets := make(map[string]map[int64]struct{})
+
+	var count int
+
+	for scanner.Next() {
+		var (
+			pair    string
+			message domain.Message
+		)
+
+		if err := scanner.Scan(
+			&pair,
+			&message.ID,
+			&message.Content,
+			&message.CreatedAt,
+			&message.FromID,
+			&message.ToID,
+		); err != nil {
+			return count, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		bucket := messageBucket(message.ID)
+
+		if err := m.db.Query(
+			"INSERT INTO messages_by_bucket (pair, bucket, id, content, created_at, from_id, to_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			pair,
+			bucket,
+			message.ID,
+			message.Content,
+			message.CreatedAt,
+			message.FromID,
+			message.ToID,
+		).WithContext(ctx).Exec(); err != nil {
+			return count, fmt.Errorf("insert message %d: %w", message.ID, err)
+		}
+
+		if _, ok := buckets[pair][bucket]; !ok {
+			if err := m.db.Query(
+				"INSERT INTO message_buckets (pair, bucket) VALUES (?, ?)",
+				pair,
+				bucket,
+			).WithContext(ctx).Exec(); err != nil {
+				return count, fmt.Errorf("insert bucket %d of %s: %w", bucket, pair, err)
+			}
+
+			if buckets[pair] == nil {
+				buckets[pair] = make(map[int64]struct{})
+			}
+
+			buckets[pair][bucket] = struct{}{}
+		}
+
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	return count, nil
+}
+
+func NewMessageBucketMigration(session *gocql.Session) *messageBucketMigration {
+	return &messageBucketMigration{
+		db: session,
+	}
+}
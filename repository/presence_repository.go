@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,6 +18,15 @@ type presence struct {
 const onlineStatus = "online"
 const onlinePresenceDuration = 40 * time.Second
 
+const (
+	keyspaceEventsParameter = "notify-keyspace-events"
+	// E: keyevent notifications, x: expired events
+	expiredKeyspaceEvents = "Ex"
+	expiredEventChannel   = "__keyevent@%d__:expired"
+	reaperClaimKeyFormat  = "reaper.%d"
+	reaperClaimDuration   = 10 * time.Second
+)
+
 func (r *presence) SetKeyExpiration(ctx context.Context, userID uint64) error {
 	key := r.getKey(userID)
 
@@ -70,6 +81,74 @@ func (r *presence) GetInterestedUsers(ctx context.Context, userID uint64) ([]uin
 	return results, nil
 }
 
+func (r *presence) WatchExpiredUsers(ctx context.Context, expired chan<- uint64) error {
+	if err := r.enableExpiredEvents(ctx); err != nil {
+		// managed instances may not allow CONFIG SET, in which case
+		// notify-keyspace-events must be configured on the server
+		log.Printf("err: enable expired keyspace events: %s", err)
+	}
+
+	sub := r.db.Subscribe(ctx, fmt.Sprintf(expiredEventChannel, r.db.Options().DB))
+
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	messages := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			// keys that are not user ids, like the reaper claims, are ignored
+			userID, err := strconv.ParseUint(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			select {
+			case expired <- userID:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (r *presence) ClaimExpiredUser(ctx context.Context, userID uint64) (bool, error) {
+	return r.db.SetNX(
+		ctx,
+		fmt.Sprintf(reaperClaimKeyFormat, userID),
+		1,
+		reaperClaimDuration).Result()
+}
+
+func (r *presence) enableExpiredEvents(ctx context.Context) error {
+	config, err := r.db.ConfigGet(ctx, keyspaceEventsParameter).Result()
+	if err != nil {
+		return fmt.Errorf("get config: %w", err)
+	}
+
+	current := config[keyspaceEventsParameter]
+
+	if strings.Contains(current, "E") &&
+		(strings.Contains(current, "x") || strings.Contains(current, "A")) {
+		return nil
+	}
+
+	return r.db.ConfigSet(
+		ctx,
+		keyspaceEventsParameter,
+		current+expiredKeyspaceEvents).Err()
+}
+
 func (r *presence) getKey(userID uint64) string {
 	return fmt.Sprintf("%d", userID)
 }
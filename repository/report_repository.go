@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type report struct {
+	db *gocql.Session
+}
+
+func (r *report) AddReport(ctx context.Context, report *domain.Report) error {
+	reportContext, err := json.Marshal(report.Context)
+	if err != nil {
+		return fmt.Errorf("json encode context: %w", err)
+	}
+
+	// lightweight transaction, so each user reports a message once
+	applied, err := r.db.Query(
+		"INSERT INTO reports_by_message (message_id, reporter_id, report_id) VALUES (?, ?, ?) IF NOT EXISTS",
+		report.MessageID,
+		report.ReporterID,
+		report.ID,
+	).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		return fmt.Errorf("claim report: %w", err)
+	}
+
+	if !applied {
+		return domain.ErrAlreadyReported
+	}
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		`INSERT INTO reports
+			(id, message_id, reporter_id, reported_id, reason, details,
+			context, actions, status, note, created_at, resolved_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.ID,
+		report.MessageID,
+		report.ReporterID,
+		report.ReportedID,
+		report.Reason,
+		report.Details,
+		string(reportContext),
+		report.Actions,
+		report.Status,
+		report.Note,
+		report.CreatedAt,
+		report.ResolvedAt,
+	)
+
+	batch.Query(
+		"INSERT INTO reports_by_status (status, created_at, id) VALUES (?, ?, ?)",
+		report.Status,
+		report.CreatedAt,
+		report.ID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *report) GetReport(ctx context.Context, id uint64) (*domain.Report, error) {
+	var (
+		report        domain.Report
+		reportContext string
+	)
+
+	err := r.db.Query(
+		`SELECT
+			id, message_id, reporter_id, reported_id, reason, details,
+			context, actions, status, note, created_at, resolved_at
+		FROM
+			reports
+		WHERE
+			id = ?`,
+		id,
+	).WithContext(ctx).Scan(
+		&report.ID,
+		&report.MessageID,
+		&report.ReporterID,
+		&report.ReportedID,
+		&report.Reason,
+		&report.Details,
+		&reportContext,
+		&report.Actions,
+		&report.Status,
+		&report.Note,
+		&report.CreatedAt,
+		&report.ResolvedAt,
+	)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, domain.ErrReportNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal([]byte(reportContext), &report.Context); err != nil {
+		return nil, fmt.Errorf("json decode context: %w", err)
+	}
+
+	return &report, nil
+}
+
+func (r *report) ListReports(ctx context.Context, status string, limit int) ([]domain.Report, error) {
+	scanner := r.db.Query(
+		"SELECT id FROM reports_by_status WHERE status = ? LIMIT ?",
+		status,
+		limit,
+	).WithContext(ctx).Iter().Scanner()
+
+	var (
+		ids []uint64
+		err error
+	)
+
+	for scanner.Next() {
+		var id uint64
+
+		if err = scanner.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	reports := make([]domain.Report, 0, len(ids))
+
+	for _, id := range ids {
+		report, err := r.GetReport(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("get report %d: %w", id, err)
+		}
+
+		reports = append(reports, *report)
+	}
+
+	return reports, nil
+}
+
+// Moves the report to the queue of its new status
+func (r *report) UpdateReport(ctx context.Context, report *domain.Report) error {
+	previous, err := r.GetReport(ctx, report.ID)
+	if err != nil {
+		return fmt.Errorf("get report: %w", err)
+	}
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"UPDATE reports SET status = ?, actions = ?, note = ?, resolved_at = ? WHERE id = ?",
+		report.Status,
+		report.Actions,
+		report.Note,
+		report.ResolvedAt,
+		report.ID,
+	)
+
+	if previous.Status != report.Status {
+		batch.Query(
+			"DELETE FROM reports_by_status WHERE status = ? AND created_at = ? AND id = ?",
+			previous.Status,
+			previous.CreatedAt,
+			report.ID,
+		)
+
+		batch.Query(
+			"INSERT INTO reports_by_status (status, created_at, id) VALUES (?, ?, ?)",
+			report.Status,
+			previous.CreatedAt,
+			report.ID,
+		)
+	}
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func NewReport(session *gocql.Session) *report {
+	return &report{
+		db: session,
+	}
+}
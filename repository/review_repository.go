@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type review struct {
+	db *gocql.Session
+}
+
+func (r *review) AddReview(ctx context.Context, review *domain.Review) error {
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		`INSERT INTO reviews
+			(message_id, from_id, to_id, content, reason, status, flagged_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?)`,
+		review.MessageID,
+		review.FromID,
+		review.ToID,
+		review.Content,
+		review.Reason,
+		review.Status,
+		review.FlaggedAt,
+	)
+
+	batch.Query(
+		"INSERT INTO reviews_by_status (status, flagged_at, message_id) VALUES (?, ?, ?)",
+		review.Status,
+		review.FlaggedAt,
+		review.MessageID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func (r *review) ListReviews(ctx context.Context, status string, limit int) ([]domain.Review, error) {
+	scanner := r.db.Query(
+		"SELECT message_id FROM reviews_by_status WHERE status = ? LIMIT ?",
+		status,
+		limit,
+	).WithContext(ctx).Iter().Scanner()
+
+	var (
+		ids []uint64
+		err error
+	)
+
+	for scanner.Next() {
+		var id uint64
+
+		if err = scanner.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	reviews := make([]domain.Review, 0, len(ids))
+
+	for _, id := range ids {
+		review, err := r.GetReview(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("get review %d: %w", id, err)
+		}
+
+		reviews = append(reviews, *review)
+	}
+
+	return reviews, nil
+}
+
+func (r *review) GetReview(ctx context.Context, messageID uint64) (*domain.Review, error) {
+	var review domain.Review
+
+	err := r.db.Query(
+		`SELECT
+			message_id, from_id, to_id, content, reason, status, flagged_at, reviewed_at
+		FROM
+			reviews
+		WHERE
+			message_id = ?`,
+		messageID,
+	).WithContext(ctx).Scan(
+		&review.MessageID,
+		&review.FromID,
+		&review.ToID,
+		&review.Content,
+		&review.Reason,
+		&review.Status,
+		&review.FlaggedAt,
+		&review.ReviewedAt,
+	)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, domain.ErrReviewNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// Moves the review to the queue of its new status
+func (r *review) UpdateReview(ctx context.Context, review *domain.Review) error {
+	previous, err := r.GetReview(ctx, review.MessageID)
+	if err != nil {
+		return fmt.Errorf("get review: %w", err)
+	}
+
+	batch := r.db.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(
+		"UPDATE reviews SET status = ?, reviewed_at = ? WHERE message_id = ?",
+		review.Status,
+		review.ReviewedAt,
+		review.MessageID,
+	)
+
+	batch.Query(
+		"DELETE FROM reviews_by_status WHERE status = ? AND flagged_at = ? AND message_id = ?",
+		previous.Status,
+		previous.FlaggedAt,
+		review.MessageID,
+	)
+
+	batch.Query(
+		"INSERT INTO reviews_by_status (status, flagged_at, message_id) VALUES (?, ?, ?)",
+		review.Status,
+		previous.FlaggedAt,
+		review.MessageID,
+	)
+
+	return r.db.ExecuteBatch(batch)
+}
+
+func NewReview(session *gocql.Session) *review {
+	return &review{
+		db: session,
+	}
+}
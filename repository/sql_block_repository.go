@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type sqlBlock struct {
+	db *sql.DB
+}
+
+func (r *sqlBlock) Block(ctx context.Context, userID, blockedID uint64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO blocks
+			(user_id, blocked_id, created_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		userID,
+		blockedID,
+		time.Now().UTC(),
+	)
+
+	return err
+}
+
+func (r *sqlBlock) Unblock(ctx context.Context, userID, blockedID uint64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM blocks WHERE user_id = $1 AND blocked_id = $2",
+		userID,
+		blockedID,
+	)
+
+	return err
+}
+
+func (r *sqlBlock) ListBlocked(ctx context.Context, userID uint64) ([]domain.Block, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			blocked_id, created_at
+		FROM
+			blocks
+		WHERE
+			user_id = $1
+		ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blocks []domain.Block
+
+	for rows.Next() {
+		var block domain.Block
+
+		if err = rows.Scan(&block.UserID, &block.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+func (r *sqlBlock) IsBlocked(ctx context.Context, userID, peerID uint64) (bool, error) {
+	var count int
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT
+			COUNT(*)
+		FROM
+			blocks
+		WHERE
+			(user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)`,
+		userID,
+		peerID,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *sqlBlock) ListBlockedPeers(ctx context.Context, userID uint64) ([]uint64, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT blocked_id FROM blocks WHERE user_id = $1
+		UNION
+		SELECT user_id FROM blocks WHERE blocked_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var peers []uint64
+
+	for rows.Next() {
+		var id uint64
+
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		peers = append(peers, id)
+	}
+
+	return peers, rows.Err()
+}
+
+func NewSQLBlock(db *sql.DB) *sqlBlock {
+	return &sqlBlock{
+		db: db,
+	}
+}
@@ -0,0 +1,863 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// The statements only use syntax shared by PostgreSQL and
+// SQLite ($n placeholders, ON CONFLICT), so both databases
+// are served by the same implementation
+type sqlChat struct {
+	db *sql.DB
+}
+
+const sqlScanPageSize = 500
+
+const sqlMessageColumns = "id, content, created_at, from_id, to_id, edited_at, deleted_at, reply_to, last_reply_at, reply_count, attachment_ids"
+
+const sqlAttachmentColumns = "id, uploader_id, peer_id, message_id, name, mime_type, size, checksum, width, height, blob_key, created_at, thumbnails, placeholder"
+
+// Stored form of domain.Thumbnail, which hides the blob key from json
+type sqlThumbnail struct {
+	Size     int    `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	MIMEType string `json:"mimeType"`
+	BlobKey  string `json:"blobKey"`
+}
+
+func (r *sqlChat) InsertMessage(ctx context.Context, message *domain.Message) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	attachmentIDs, err := r.encodeIDs(message.AttachmentIDs)
+	if err != nil {
+		return fmt.Errorf("encode attachment ids: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO messages
+			(pair, id, content, from_id, to_id, created_at, reply_to, attachment_ids)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)`,
+		pair,
+		message.ID,
+		message.Content,
+		message.FromID,
+		message.ToID,
+		message.CreatedAt,
+		message.ReplyToID,
+		attachmentIDs,
+	); err != nil {
+		return fmt.Errorf("insert message: %w", err)
+	}
+
+	// attachments taken by a concurrent send meanwhile
+	// roll the message back
+	for _, attachmentID := range message.AttachmentIDs {
+		res, err := tx.ExecContext(
+			ctx,
+			"UPDATE attachments SET message_id = $1 WHERE id = $2 AND message_id IS NULL",
+			message.ID,
+			attachmentID,
+		)
+		if err != nil {
+			return fmt.Errorf("update attachment %d: %w", attachmentID, err)
+		}
+
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("update attachment %d: %w", attachmentID, err)
+		}
+
+		if updated != 1 {
+			return domain.ErrAttachmentNotFound
+		}
+	}
+
+	if message.ReplyToID != nil {
+		if _, err = tx.ExecContext(
+			ctx,
+			`UPDATE
+				messages
+			SET
+				last_reply_at = $1, reply_count = reply_count + 1
+			WHERE
+				pair = $2 AND id = $3`,
+			message.CreatedAt,
+			pair,
+			message.ReplyToID,
+		); err != nil {
+			return fmt.Errorf("update thread root: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlChat) ListMessages(
+	ctx context.Context,
+	fromID,
+	toID uint64,
+	messageRange *domain.MessageRange,
+) ([]domain.Message, error) {
+	query := `SELECT
+			%s
+		FROM
+			messages
+		WHERE
+			pair = $1
+			%s
+		ORDER BY id %s LIMIT %s`
+
+	values := []any{pairOf(fromID, toID)}
+
+	var conditions string
+
+	if messageRange.BeforeID != nil {
+		values = append(values, messageRange.BeforeID)
+		conditions += fmt.Sprintf(" AND id < $%d", len(values))
+	}
+
+	if messageRange.AfterID != nil {
+		values = append(values, messageRange.AfterID)
+		conditions += fmt.Sprintf(" AND id > $%d", len(values))
+	}
+
+	order := "ASC"
+	if messageRange.Descending {
+		order = "DESC"
+	}
+
+	values = append(values, messageRange.Limit)
+
+	messages, err := r.queryMessages(
+		ctx,
+		fmt.Sprintf(query, sqlMessageColumns, conditions, order, fmt.Sprintf("$%d", len(values))),
+		values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.decorateMessages(ctx, fromID, toID, messages)
+}
+
+func (r *sqlChat) ListThread(
+	ctx context.Context,
+	fromID,
+	toID,
+	rootID uint64,
+	afterID *uint64,
+	limit int,
+) ([]domain.Message, error) {
+	query := `SELECT
+			%s
+		FROM
+			messages
+		WHERE
+			pair = $1 AND reply_to = $2
+			%s
+		ORDER BY id ASC LIMIT %s`
+
+	values := []any{pairOf(fromID, toID), rootID}
+
+	var afterCondition string
+
+	if afterID != nil {
+		values = append(values, afterID)
+		afterCondition = fmt.Sprintf("AND id > $%d", len(values))
+	}
+
+	values = append(values, limit)
+
+	messages, err := r.queryMessages(
+		ctx,
+		fmt.Sprintf(query, sqlMessageColumns, afterCondition, fmt.Sprintf("$%d", len(values))),
+		values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err = r.decorateMessages(ctx, fromID, toID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.VisibleMessages(messages), nil
+}
+
+func (r *sqlChat) CountReplies(ctx context.Context, fromID, toID, rootID uint64) (int, error) {
+	var count int
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT reply_count FROM messages WHERE pair = $1 AND id = $2",
+		pairOf(fromID, toID),
+		rootID,
+	).Scan(&count)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *sqlChat) GetMessage(ctx context.Context, fromID, toID, id uint64) (*domain.Message, error) {
+	messages, err := r.queryMessages(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM messages WHERE pair = $1 AND id = $2",
+			sqlMessageColumns,
+		),
+		pairOf(fromID, toID),
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(messages) == 0 {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	message := &messages[0]
+
+	if len(message.AttachmentIDs) > 0 {
+		attachments, err := r.listAttachments(ctx, message.AttachmentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("list attachments: %w", err)
+		}
+
+		message.Attachments = messageAttachments(message, attachments)
+	}
+
+	return message, nil
+}
+
+// Reads by pages so the connection isn't held while handling
+// the messages, SQLite only has one
+func (r *sqlChat) ScanMessages(ctx context.Context, handle func(*domain.Message) error) error {
+	var (
+		lastPair string
+		lastID   uint64
+	)
+
+	for {
+		messages, err := r.queryMessages(
+			ctx,
+			fmt.Sprintf(
+				`SELECT %s FROM messages
+				WHERE pair > $1 OR (pair = $1 AND id > $2)
+				ORDER BY pair, id
+				LIMIT $3`,
+				sqlMessageColumns,
+			),
+			lastPair,
+			lastID,
+			sqlScanPageSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		for i := range messages {
+			if err = handle(&messages[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < sqlScanPageSize {
+			return nil
+		}
+
+		last := messages[len(messages)-1]
+
+		lastPair, lastID = pairOf(last.FromID, last.ToID), last.ID
+	}
+}
+
+func (r *sqlChat) UpdateMessageContent(
+	ctx context.Context,
+	message *domain.Message,
+	previousContent string,
+) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO message_edits (pair, message_id, edited_at, content) VALUES ($1, $2, $3, $4)",
+		pair,
+		message.ID,
+		message.EditedAt,
+		previousContent,
+	); err != nil {
+		return fmt.Errorf("insert edit: %w", err)
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"UPDATE messages SET content = $1, edited_at = $2 WHERE pair = $3 AND id = $4",
+		message.Content,
+		message.EditedAt,
+		pair,
+		message.ID,
+	); err != nil {
+		return fmt.Errorf("update message: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlChat) ListMessageEdits(
+	ctx context.Context,
+	fromID,
+	toID,
+	messageID uint64,
+) ([]domain.MessageEdit, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			message_id, content, edited_at
+		FROM
+			message_edits
+		WHERE
+			pair = $1 AND message_id = $2
+		ORDER BY edited_at ASC`,
+		pairOf(fromID, toID),
+		messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var edits []domain.MessageEdit
+
+	for rows.Next() {
+		var edit domain.MessageEdit
+
+		if err = rows.Scan(&edit.MessageID, &edit.Content, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		edits = append(edits, edit)
+	}
+
+	return edits, rows.Err()
+}
+
+func (r *sqlChat) DeleteMessage(ctx context.Context, message *domain.Message) error {
+	pair := pairOf(message.FromID, message.ToID)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"UPDATE messages SET content = $1, deleted_at = $2, attachment_ids = $3 WHERE pair = $4 AND id = $5",
+		message.Content,
+		message.DeletedAt,
+		"[]",
+		pair,
+		message.ID,
+	); err != nil {
+		return fmt.Errorf("update message: %w", err)
+	}
+
+	for _, attachmentID := range message.AttachmentIDs {
+		if _, err = tx.ExecContext(
+			ctx,
+			"DELETE FROM attachments WHERE id = $1",
+			attachmentID,
+		); err != nil {
+			return fmt.Errorf("delete attachment %d: %w", attachmentID, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"DELETE FROM message_edits WHERE pair = $1 AND message_id = $2",
+		pair,
+		message.ID,
+	); err != nil {
+		return fmt.Errorf("delete edits: %w", err)
+	}
+
+	if message.ReplyToID != nil {
+		if _, err = tx.ExecContext(
+			ctx,
+			"UPDATE messages SET reply_count = reply_count - 1 WHERE pair = $1 AND id = $2 AND reply_count > 0",
+			pair,
+			message.ReplyToID,
+		); err != nil {
+			return fmt.Errorf("update thread root: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlChat) HideMessage(ctx context.Context, userID, peerID, messageID uint64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO hidden_messages
+			(user_id, pair, message_id)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		userID,
+		pairOf(userID, peerID),
+		messageID,
+	)
+
+	return err
+}
+
+func (r *sqlChat) AddReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO reactions
+			(pair, message_id, emoji, user_id)
+		VALUES
+			($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`,
+		pairOf(reaction.UserID, peerID),
+		reaction.MessageID,
+		reaction.Emoji,
+		reaction.UserID,
+	)
+
+	return err
+}
+
+func (r *sqlChat) RemoveReaction(ctx context.Context, peerID uint64, reaction *domain.Reaction) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM reactions WHERE pair = $1 AND message_id = $2 AND emoji = $3 AND user_id = $4",
+		pairOf(reaction.UserID, peerID),
+		reaction.MessageID,
+		reaction.Emoji,
+		reaction.UserID,
+	)
+
+	return err
+}
+
+func (r *sqlChat) InsertAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	thumbnails, err := r.encodeThumbnails(attachment.Thumbnails)
+	if err != nil {
+		return fmt.Errorf("encode thumbnails: %w", err)
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"INSERT INTO attachments (%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+			sqlAttachmentColumns,
+		),
+		attachment.ID,
+		attachment.UploaderID,
+		attachment.PeerID,
+		attachment.MessageID,
+		attachment.Name,
+		attachment.MIMEType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.Width,
+		attachment.Height,
+		attachment.BlobKey,
+		attachment.CreatedAt,
+		thumbnails,
+		attachment.Placeholder,
+	)
+
+	return err
+}
+
+func (r *sqlChat) UpdateAttachmentThumbnails(ctx context.Context, attachment *domain.Attachment) error {
+	thumbnails, err := r.encodeThumbnails(attachment.Thumbnails)
+	if err != nil {
+		return fmt.Errorf("encode thumbnails: %w", err)
+	}
+
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE attachments SET thumbnails = $1, placeholder = $2 WHERE id = $3",
+		thumbnails,
+		attachment.Placeholder,
+		attachment.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (r *sqlChat) GetAttachment(ctx context.Context, id uint64) (*domain.Attachment, error) {
+	attachments, err := r.listAttachments(ctx, []uint64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, ok := attachments[id]
+	if !ok {
+		return nil, domain.ErrAttachmentNotFound
+	}
+
+	return &attachment, nil
+}
+
+func (r *sqlChat) listAttachments(ctx context.Context, ids []uint64) (map[uint64]domain.Attachment, error) {
+	attachments := make(map[uint64]domain.Attachment)
+
+	if len(ids) == 0 {
+		return attachments, nil
+	}
+
+	placeholders, values := r.inList(ids)
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM attachments WHERE id IN (%s)",
+			sqlAttachmentColumns,
+			placeholders,
+		),
+		values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			attachment domain.Attachment
+			thumbnails string
+		)
+
+		if err = rows.Scan(
+			&attachment.ID,
+			&attachment.UploaderID,
+			&attachment.PeerID,
+			&attachment.MessageID,
+			&attachment.Name,
+			&attachment.MIMEType,
+			&attachment.Size,
+			&attachment.Checksum,
+			&attachment.Width,
+			&attachment.Height,
+			&attachment.BlobKey,
+			&attachment.CreatedAt,
+			&thumbnails,
+			&attachment.Placeholder,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		attachment.Thumbnails, err = r.decodeThumbnails(thumbnails)
+		if err != nil {
+			return nil, fmt.Errorf("decode thumbnails of %d: %w", attachment.ID, err)
+		}
+
+		attachment.SetURLs()
+
+		attachments[attachment.ID] = attachment
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *sqlChat) queryMessages(ctx context.Context, query string, values ...any) ([]domain.Message, error) {
+	rows, err := r.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var messages []domain.Message
+
+	for rows.Next() {
+		var (
+			message       domain.Message
+			attachmentIDs string
+		)
+
+		if err = rows.Scan(
+			&message.ID,
+			&message.Content,
+			&message.CreatedAt,
+			&message.FromID,
+			&message.ToID,
+			&message.EditedAt,
+			&message.DeletedAt,
+			&message.ReplyToID,
+			&message.LastReplyAt,
+			&message.ReplyCount,
+			&attachmentIDs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		if err = json.Unmarshal([]byte(attachmentIDs), &message.AttachmentIDs); err != nil {
+			return nil, fmt.Errorf("decode attachment ids of %d: %w", message.ID, err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// Flags the messages hidden by userID and fills the
+// aggregated data of the others
+func (r *sqlChat) decorateMessages(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	messages []domain.Message,
+) ([]domain.Message, error) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	firstID, lastID := messages[0].ID, messages[len(messages)-1].ID
+
+	if firstID > lastID {
+		firstID, lastID = lastID, firstID
+	}
+
+	hidden, err := r.ListHiddenMessageIDs(ctx, userID, peerID, firstID, lastID)
+	if err != nil {
+		return nil, fmt.Errorf("list hidden messages: %w", err)
+	}
+
+	reactions, err := r.reactionCounts(ctx, userID, peerID, firstID, lastID)
+	if err != nil {
+		return nil, fmt.Errorf("list reactions: %w", err)
+	}
+
+	var attachmentIDs []uint64
+
+	for _, message := range messages {
+		attachmentIDs = append(attachmentIDs, message.AttachmentIDs...)
+	}
+
+	attachments, err := r.listAttachments(ctx, attachmentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+
+	for i := range messages {
+		message := &messages[i]
+
+		if _, ok := hidden[message.ID]; ok {
+			message.Hidden = true
+			message.ReplyCount = 0
+			continue
+		}
+
+		message.Reactions = reactions[message.ID]
+		message.Attachments = messageAttachments(message, attachments)
+	}
+
+	return messages, nil
+}
+
+// Ids between firstID and lastID hidden by userID
+func (r *sqlChat) ListHiddenMessageIDs(
+	ctx context.Context,
+	userID,
+	peerID,
+	firstID,
+	lastID uint64,
+) (map[uint64]struct{}, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			message_id
+		FROM
+			hidden_messages
+		WHERE
+			user_id = $1 AND pair = $2 AND message_id >= $3 AND message_id <= $4`,
+		userID,
+		pairOf(userID, peerID),
+		firstID,
+		lastID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hidden := make(map[uint64]struct{})
+
+	for rows.Next() {
+		var id uint64
+
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		hidden[id] = struct{}{}
+	}
+
+	return hidden, rows.Err()
+}
+
+// Reactions between firstID and lastID aggregated by message id
+func (r *sqlChat) reactionCounts(
+	ctx context.Context,
+	userID,
+	peerID,
+	firstID,
+	lastID uint64,
+) (map[uint64][]domain.ReactionCount, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			message_id, emoji, user_id
+		FROM
+			reactions
+		WHERE
+			pair = $1 AND message_id >= $2 AND message_id <= $3
+		ORDER BY message_id, emoji, user_id`,
+		pairOf(userID, peerID),
+		firstID,
+		lastID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := make(map[uint64][]domain.ReactionCount)
+
+	for rows.Next() {
+		var reaction domain.Reaction
+
+		if err = rows.Scan(
+			&reaction.MessageID,
+			&reaction.Emoji,
+			&reaction.UserID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		countReaction(counts, &reaction, userID)
+	}
+
+	return counts, rows.Err()
+}
+
+// Placeholders and values of an IN clause
+func (r *sqlChat) inList(ids []uint64) (string, []any) {
+	placeholders := make([]string, len(ids))
+	values := make([]any, len(ids))
+
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		values[i] = id
+	}
+
+	return strings.Join(placeholders, ", "), values
+}
+
+func (r *sqlChat) encodeIDs(ids []uint64) (string, error) {
+	if ids == nil {
+		ids = []uint64{}
+	}
+
+	data, err := json.Marshal(ids)
+
+	return string(data), err
+}
+
+func (r *sqlChat) encodeThumbnails(thumbnails []domain.Thumbnail) (string, error) {
+	stored := make([]sqlThumbnail, len(thumbnails))
+
+	for i, thumbnail := range thumbnails {
+		stored[i] = sqlThumbnail{
+			Size:     thumbnail.Size,
+			Width:    thumbnail.Width,
+			Height:   thumbnail.Height,
+			MIMEType: thumbnail.MIMEType,
+			BlobKey:  thumbnail.BlobKey,
+		}
+	}
+
+	data, err := json.Marshal(stored)
+
+	return string(data), err
+}
+
+func (r *sqlChat) decodeThumbnails(data string) ([]domain.Thumbnail, error) {
+	var stored []sqlThumbnail
+
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, err
+	}
+
+	var thumbnails []domain.Thumbnail
+
+	for _, thumbnail := range stored {
+		thumbnails = append(thumbnails, domain.Thumbnail{
+			Size:     thumbnail.Size,
+			Width:    thumbnail.Width,
+			Height:   thumbnail.Height,
+			MIMEType: thumbnail.MIMEType,
+			BlobKey:  thumbnail.BlobKey,
+		})
+	}
+
+	return thumbnails, nil
+}
+
+// Works with PostgreSQL and SQLite databases
+func NewSQLChat(db *sql.DB) *sqlChat {
+	return &sqlChat{
+		db: db,
+	}
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type sqlConversation struct {
+	db *sql.DB
+}
+
+func (r *sqlConversation) UpdateLastMessage(ctx context.Context, message *domain.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	// a single row when messaging themselves
+	userIDs := slices.Compact([]uint64{message.FromID, message.ToID})
+
+	for i, userID := range userIDs {
+		peerID := userIDs[len(userIDs)-1-i]
+
+		if _, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO conversations
+				(user_id, peer_id, last_message_id, last_message_at)
+			VALUES
+				($1, $2, $3, $4)
+			ON CONFLICT (user_id, peer_id) DO UPDATE SET
+				last_message_id = excluded.last_message_id,
+				last_message_at = excluded.last_message_at,
+				archived = FALSE
+			WHERE
+				conversations.last_message_id IS NULL OR
+				conversations.last_message_id < excluded.last_message_id`,
+			userID,
+			peerID,
+			message.ID,
+			message.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("upsert conversation of %d: %w", userID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlConversation) GetConversation(ctx context.Context, userID, peerID uint64) (*domain.Conversation, error) {
+	conversation := domain.Conversation{
+		PeerID: peerID,
+	}
+
+	var lastMessageID *uint64
+
+	err := r.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM conversations WHERE user_id = $1 AND peer_id = $2",
+			conversationColumns,
+		),
+		userID,
+		peerID,
+	).Scan(r.fields(&conversation, &lastMessageID)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &conversation, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lastMessageID != nil {
+		conversation.LastMessageID = *lastMessageID
+	}
+
+	return &conversation, nil
+}
+
+func (r *sqlConversation) UpdateSettings(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	settings *domain.ConversationSettings,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO conversations
+			(user_id, peer_id, muted, muted_until, archived, pinned_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, peer_id) DO UPDATE SET
+			muted = excluded.muted,
+			muted_until = excluded.muted_until,
+			archived = excluded.archived,
+			pinned_at = excluded.pinned_at`,
+		userID,
+		peerID,
+		settings.Muted,
+		settings.MutedUntil,
+		settings.Archived,
+		settings.PinnedAt,
+	)
+
+	return err
+}
+
+func (r *sqlConversation) ListConversations(ctx context.Context, userID uint64) ([]domain.Conversation, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM conversations WHERE user_id = $1", conversationColumns),
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var conversations []domain.Conversation
+
+	for rows.Next() {
+		var (
+			conversation  domain.Conversation
+			lastMessageID *uint64
+		)
+
+		if err = rows.Scan(r.fields(&conversation, &lastMessageID)...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		if lastMessageID != nil {
+			conversation.LastMessageID = *lastMessageID
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, rows.Err()
+}
+
+// last_message_id is null until the first message
+func (r *sqlConversation) fields(conversation *domain.Conversation, lastMessageID **uint64) []any {
+	return []any{
+		&conversation.PeerID,
+		lastMessageID,
+		&conversation.LastMessageAt,
+		&conversation.Muted,
+		&conversation.MutedUntil,
+		&conversation.Archived,
+		&conversation.PinnedAt,
+	}
+}
+
+func NewSQLConversation(db *sql.DB) *sqlConversation {
+	return &sqlConversation{
+		db: db,
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const sqlReportColumns = `id, message_id, reporter_id, reported_id, reason, details,
+	context, actions, status, note, created_at, resolved_at`
+
+type sqlReport struct {
+	db *sql.DB
+}
+
+func (r *sqlReport) AddReport(ctx context.Context, report *domain.Report) error {
+	reportContext, err := json.Marshal(report.Context)
+	if err != nil {
+		return fmt.Errorf("json encode context: %w", err)
+	}
+
+	actions, err := json.Marshal(report.Actions)
+	if err != nil {
+		return fmt.Errorf("json encode actions: %w", err)
+	}
+
+	res, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO reports
+			(`+sqlReportColumns+`)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (message_id, reporter_id) DO NOTHING`,
+		report.ID,
+		report.MessageID,
+		report.ReporterID,
+		report.ReportedID,
+		report.Reason,
+		report.Details,
+		string(reportContext),
+		string(actions),
+		report.Status,
+		report.Note,
+		report.CreatedAt,
+		report.ResolvedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if inserted == 0 {
+		return domain.ErrAlreadyReported
+	}
+
+	return nil
+}
+
+func (r *sqlReport) GetReport(ctx context.Context, id uint64) (*domain.Report, error) {
+	report, err := scanReport(r.db.QueryRowContext(
+		ctx,
+		"SELECT "+sqlReportColumns+" FROM reports WHERE id = $1",
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrReportNotFound
+	}
+
+	return report, err
+}
+
+func (r *sqlReport) ListReports(ctx context.Context, status string, limit int) ([]domain.Report, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			`+sqlReportColumns+`
+		FROM
+			reports
+		WHERE
+			status = $1
+		ORDER BY created_at, id
+		LIMIT $2`,
+		status,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var reports []domain.Report
+
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		reports = append(reports, *report)
+	}
+
+	return reports, rows.Err()
+}
+
+func (r *sqlReport) UpdateReport(ctx context.Context, report *domain.Report) error {
+	actions, err := json.Marshal(report.Actions)
+	if err != nil {
+		return fmt.Errorf("json encode actions: %w", err)
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`UPDATE reports SET
+			status = $1, actions = $2, note = $3, resolved_at = $4
+		WHERE
+			id = $5`,
+		report.Status,
+		string(actions),
+		report.Note,
+		report.ResolvedAt,
+		report.ID,
+	)
+
+	return err
+}
+
+func scanReport(row interface{ Scan(...any) error }) (*domain.Report, error) {
+	var (
+		report                 domain.Report
+		reportContext, actions string
+	)
+
+	err := row.Scan(
+		&report.ID,
+		&report.MessageID,
+		&report.ReporterID,
+		&report.ReportedID,
+		&report.Reason,
+		&report.Details,
+		&reportContext,
+		&actions,
+		&report.Status,
+		&report.Note,
+		&report.CreatedAt,
+		&report.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal([]byte(reportContext), &report.Context); err != nil {
+		return nil, fmt.Errorf("json decode context: %w", err)
+	}
+
+	if err = json.Unmarshal([]byte(actions), &report.Actions); err != nil {
+		return nil, fmt.Errorf("json decode actions: %w", err)
+	}
+
+	return &report, nil
+}
+
+func NewSQLReport(db *sql.DB) *sqlReport {
+	return &sqlReport{
+		db: db,
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type sqlReview struct {
+	db *sql.DB
+}
+
+func (r *sqlReview) AddReview(ctx context.Context, review *domain.Review) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO reviews
+			(message_id, from_id, to_id, content, reason, status, flagged_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING`,
+		review.MessageID,
+		review.FromID,
+		review.ToID,
+		review.Content,
+		review.Reason,
+		review.Status,
+		review.FlaggedAt,
+	)
+
+	return err
+}
+
+func (r *sqlReview) ListReviews(ctx context.Context, status string, limit int) ([]domain.Review, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT
+			message_id, from_id, to_id, content, reason, status, flagged_at, reviewed_at
+		FROM
+			reviews
+		WHERE
+			status = $1
+		ORDER BY flagged_at, message_id
+		LIMIT $2`,
+		status,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var reviews []domain.Review
+
+	for rows.Next() {
+		var review domain.Review
+
+		if err = rows.Scan(
+			&review.MessageID,
+			&review.FromID,
+			&review.ToID,
+			&review.Content,
+			&review.Reason,
+			&review.Status,
+			&review.FlaggedAt,
+			&review.ReviewedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+func (r *sqlReview) GetReview(ctx context.Context, messageID uint64) (*domain.Review, error) {
+	var review domain.Review
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT
+			message_id, from_id, to_id, content, reason, status, flagged_at, reviewed_at
+		FROM
+			reviews
+		WHERE
+			message_id = $1`,
+		messageID,
+	).Scan(
+		&review.MessageID,
+		&review.FromID,
+		&review.ToID,
+		&review.Content,
+		&review.Reason,
+		&review.Status,
+		&review.FlaggedAt,
+		&review.ReviewedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrReviewNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+func (r *sqlReview) UpdateReview(ctx context.Context, review *domain.Review) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"UPDATE reviews SET status = $1, reviewed_at = $2 WHERE message_id = $3",
+		review.Status,
+		review.ReviewedAt,
+		review.MessageID,
+	)
+
+	return err
+}
+
+func NewSQLReview(db *sql.DB) *sqlReview {
+	return &sqlReview{
+		db: db,
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type sqlUser struct {
+	db *sql.DB
+}
+
+func (r *sqlUser) Register(ctx context.Context, userID uint64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO users (id, first_seen_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID,
+		time.Now().UTC(),
+	)
+
+	return err
+}
+
+func (r *sqlUser) Exists(ctx context.Context, userID uint64) (bool, error) {
+	var count int
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM users WHERE id = $1",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *sqlUser) Suspend(ctx context.Context, suspension *domain.Suspension) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO suspensions
+			(user_id, reason, suspended_at, suspended_until)
+		VALUES
+			($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			reason = excluded.reason,
+			suspended_at = excluded.suspended_at,
+			suspended_until = excluded.suspended_until`,
+		suspension.UserID,
+		suspension.Reason,
+		suspension.SuspendedAt,
+		suspension.Until,
+	)
+
+	return err
+}
+
+func (r *sqlUser) Unsuspend(ctx context.Context, userID uint64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM suspensions WHERE user_id = $1",
+		userID,
+	)
+
+	return err
+}
+
+func (r *sqlUser) GetSuspension(ctx context.Context, userID uint64) (*domain.Suspension, error) {
+	var suspension domain.Suspension
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT user_id, reason, suspended_at, suspended_until FROM suspensions WHERE user_id = $1",
+		userID,
+	).Scan(
+		&suspension.UserID,
+		&suspension.Reason,
+		&suspension.SuspendedAt,
+		&suspension.Until,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &suspension, nil
+}
+
+func (r *sqlUser) SetTier(ctx context.Context, userID uint64, tier string) error {
+	if tier == "" {
+		_, err := r.db.ExecContext(
+			ctx,
+			"DELETE FROM user_tiers WHERE user_id = $1",
+			userID,
+		)
+
+		return err
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO user_tiers (user_id, tier) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET tier = excluded.tier`,
+		userID,
+		tier,
+	)
+
+	return err
+}
+
+func (r *sqlUser) GetTier(ctx context.Context, userID uint64) (string, error) {
+	var tier string
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT tier FROM user_tiers WHERE user_id = $1",
+		userID,
+	).Scan(&tier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return tier, err
+}
+
+func NewSQLUser(db *sql.DB) *sqlUser {
+	return &sqlUser{
+		db: db,
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+type userBackfill struct {
+	db *gocql.Session
+}
+
+// Registers the participants of the messages sent before the users
+// table existed, which Cassandra can't do from a migration. Users
+// first seen by a connection get the date of their first message,
+// so the run can be repeated
+func (b *userBackfill) Run(ctx context.Context) (int, error) {
+	scanner := b.db.Query(
+		"SELECT from_id, to_id, created_at FROM messages_by_bucket",
+	).WithContext(ctx).PageSize(migrationPageSize).Iter().Scanner()
+
+	firstSeen := make(map[uint64]time.Time)
+
+	for scanner.Next() {
+		var (
+			fromID, toID uint64
+			createdAt    time.Time
+		)
+
+		if err := scanner.Scan(&fromID, &toID, &createdAt); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		for _, userID := range []uint64{fromID, toID} {
+			if seen, ok := firstSeen[userID]; !ok || createdAt.Before(seen) {
+				firstSeen[userID] = createdAt
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to close scanner: %s", err)
+	}
+
+	var count int
+
+	for userID, seenAt := range firstSeen {
+		if err := b.register(ctx, userID, seenAt); err != nil {
+			return count, fmt.Errorf("register user %d: %w", userID, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (b *userBackfill) register(ctx context.Context, userID uint64, seenAt time.Time) error {
+	applied, err := b.db.Query(
+		"INSERT INTO users (id, first_seen_at) VALUES (?, ?) IF NOT EXISTS",
+		userID,
+		seenAt,
+	).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil || applied {
+		return err
+	}
+
+	// the condition fails when it was already first seen earlier
+	_, err = b.db.Query(
+		"UPDATE users SET first_seen_at = ? WHERE id = ? IF first_seen_at > ?",
+		seenAt,
+		userID,
+		seenAt,
+	).WithContext(ctx).MapScanCAS(map[string]any{})
+
+	return err
+}
+
+func NewUserBackfill(session *gocql.Session) *userBackfill {
+	return &userBackfill{
+		db: session,
+	}
+}
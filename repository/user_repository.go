@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type user struct {
+	db *gocql.Session
+}
+
+func (r *user) Register(ctx context.Context, userID uint64) error {
+	// read before writing, so reconnecting doesn't
+	// overwrite when the user was first seen
+	exists, err := r.Exists(ctx, userID)
+	if err != nil || exists {
+		return err
+	}
+
+	return r.db.Query(
+		"INSERT INTO users (id, first_seen_at) VALUES (?, ?)",
+		userID,
+		time.Now().UTC(),
+	).WithContext(ctx).Exec()
+}
+
+func (r *user) Exists(ctx context.Context, userID uint64) (bool, error) {
+	var count int
+
+	err := r.db.Query(
+		"SELECT COUNT(*) FROM users WHERE id = ?",
+		userID,
+	).WithContext(ctx).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *user) Suspend(ctx context.Context, suspension *domain.Suspension) error {
+	return r.db.Query(
+		"INSERT INTO suspensions (user_id, reason, suspended_at, suspended_until) VALUES (?, ?, ?, ?)",
+		suspension.UserID,
+		suspension.Reason,
+		suspension.SuspendedAt,
+		suspension.Until,
+	).WithContext(ctx).Exec()
+}
+
+func (r *user) Unsuspend(ctx context.Context, userID uint64) error {
+	return r.db.Query(
+		"DELETE FROM suspensions WHERE user_id = ?",
+		userID,
+	).WithContext(ctx).Exec()
+}
+
+func (r *user) GetSuspension(ctx context.Context, userID uint64) (*domain.Suspension, error) {
+	var suspension domain.Suspension
+
+	err := r.db.Query(
+		"SELECT user_id, reason, suspended_at, suspended_until FROM suspensions WHERE user_id = ?",
+		userID,
+	).WithContext(ctx).Scan(
+		&suspension.UserID,
+		&suspension.Reason,
+		&suspension.SuspendedAt,
+		&suspension.Until,
+	)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &suspension, nil
+}
+
+func (r *user) SetTier(ctx context.Context, userID uint64, tier string) error {
+	if tier == "" {
+		return r.db.Query(
+			"DELETE FROM user_tiers WHERE user_id = ?",
+			userID,
+		).WithContext(ctx).Exec()
+	}
+
+	return r.db.Query(
+		"INSERT INTO user_tiers (user_id, tier) VALUES (?, ?)",
+		userID,
+		tier,
+	).WithContext(ctx).Exec()
+}
+
+func (r *user) GetTier(ctx context.Context, userID uint64) (string, error) {
+	var tier string
+
+	err := r.db.Query(
+		"SELECT tier FROM user_tiers WHERE user_id = ?",
+		userID,
+	).WithContext(ctx).Scan(&tier)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return "", nil
+	}
+
+	return tier, err
+}
+
+func NewUser(session *gocql.Session) *user {
+	return &user{
+		db: session,
+	}
+}
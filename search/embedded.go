@@ -0,0 +1,285 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const (
+	logFileName         = "index.log"
+	snapshotFileName    = "index.log.tmp"
+	maxLogLineSizeBytes = 1 << 20
+)
+
+// Entry of the append only log the index is rebuilt from
+type operation struct {
+	Document  *domain.SearchDocument `json:"document,omitempty"`
+	RemovedID uint64                 `json:"removedId,omitempty"`
+}
+
+// Inverted index kept in memory and persisted in an append only
+// log, which is compacted every time the index is opened
+type embedded struct {
+	mu        sync.RWMutex
+	file      *os.File
+	encoder   *json.Encoder
+	documents map[uint64]*domain.SearchDocument
+	// document ids by term
+	postings map[string]map[uint64]struct{}
+}
+
+func (s *embedded) Index(ctx context.Context, document *domain.SearchDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(operation{Document: document}); err != nil {
+		return fmt.Errorf("write log: %w", err)
+	}
+
+	s.index(document)
+
+	return nil
+}
+
+func (s *embedded) Remove(ctx context.Context, messageID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.documents[messageID]; !ok {
+		return nil
+	}
+
+	if err := s.encoder.Encode(operation{RemovedID: messageID}); err != nil {
+		return fmt.Errorf("write log: %w", err)
+	}
+
+	s.remove(messageID)
+
+	return nil
+}
+
+func (s *embedded) Search(
+	ctx context.Context,
+	userID uint64,
+	query string,
+	offset,
+	limit int,
+) ([]domain.SearchHit, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// intersecting from the rarest term
+	slices.SortFunc(terms, func(a, b string) int {
+		return len(s.postings[a]) - len(s.postings[b])
+	})
+
+	var matches []*domain.SearchDocument
+
+	for id := range s.postings[terms[0]] {
+		document := s.documents[id]
+
+		if document.FromID != userID && document.ToID != userID {
+			continue
+		}
+
+		if s.containsAll(id, terms[1:]) {
+			matches = append(matches, document)
+		}
+	}
+
+	if offset >= len(matches) {
+		return nil, nil
+	}
+
+	// ids are ordered by time
+	slices.SortFunc(matches, func(a, b *domain.SearchDocument) int {
+		if a.MessageID > b.MessageID {
+			return -1
+		}
+
+		return 1
+	})
+
+	matches = matches[offset:min(offset+limit, len(matches))]
+
+	hits := make([]domain.SearchHit, len(matches))
+
+	for i, document := range matches {
+		hits[i] = domain.SearchHit{
+			MessageID: document.MessageID,
+			FromID:    document.FromID,
+			ToID:      document.ToID,
+			CreatedAt: document.CreatedAt,
+			Highlight: highlight(document.Content, terms),
+		}
+	}
+
+	return hits, nil
+}
+
+func (s *embedded) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.documents), nil
+}
+
+func (s *embedded) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *embedded) containsAll(id uint64, terms []string) bool {
+	for _, term := range terms {
+		if _, ok := s.postings[term][id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *embedded) index(document *domain.SearchDocument) {
+	s.remove(document.MessageID)
+
+	s.documents[document.MessageID] = document
+
+	for _, term := range tokenize(document.Content) {
+		ids, ok := s.postings[term]
+		if !ok {
+			ids = make(map[uint64]struct{})
+			s.postings[term] = ids
+		}
+
+		ids[document.MessageID] = struct{}{}
+	}
+}
+
+func (s *embedded) remove(id uint64) {
+	document, ok := s.documents[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range tokenize(document.Content) {
+		delete(s.postings[term], id)
+
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+
+	delete(s.documents, id)
+}
+
+func (s *embedded) replay(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLogLineSizeBytes)
+
+	for scanner.Scan() {
+		var op operation
+
+		// a crash may leave the last line incomplete
+		if err = json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			continue
+		}
+
+		if op.Document != nil {
+			s.index(op.Document)
+		} else {
+			s.remove(op.RemovedID)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Rewrites the log with only the documents left
+func (s *embedded) compact(directory string) error {
+	path := filepath.Join(directory, snapshotFileName)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	for _, document := range s.documents {
+		if err = encoder.Encode(operation{Document: document}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err = writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(path, filepath.Join(directory, logFileName))
+}
+
+func NewEmbedded(directory string) (*embedded, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	index := &embedded{
+		documents: make(map[uint64]*domain.SearchDocument),
+		postings:  make(map[string]map[uint64]struct{}),
+	}
+
+	path := filepath.Join(directory, logFileName)
+
+	if err := index.replay(path); err != nil {
+		return nil, fmt.Errorf("replay log: %w", err)
+	}
+
+	if err := index.compact(directory); err != nil {
+		return nil, fmt.Errorf("compact log: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+
+	index.file = file
+	index.encoder = json.NewEncoder(file)
+
+	return index, nil
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// Unique lower case words of the text
+func tokenize(text string) []string {
+	var terms []string
+
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		if !slices.Contains(terms, field) {
+			terms = append(terms, field)
+		}
+	}
+
+	return terms
+}
+
+// Escapes the content and wraps the words matching the terms
+func highlight(content string, terms []string) string {
+	var (
+		builder strings.Builder
+		start   = -1
+	)
+
+	flush := func(end int) {
+		word := content[start:end]
+
+		if slices.Contains(terms, strings.ToLower(word)) {
+			builder.WriteString(highlightStart)
+			builder.WriteString(html.EscapeString(word))
+			builder.WriteString(highlightEnd)
+		} else {
+			builder.WriteString(html.EscapeString(word))
+		}
+
+		start = -1
+	}
+
+	for i, r := range content {
+		if isSeparator(r) {
+			if start >= 0 {
+				flush(i)
+			}
+
+			builder.WriteString(html.EscapeString(string(r)))
+		} else if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		flush(len(content))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type connectionRegistry struct {
+	mu sync.RWMutex
+	// by user, a user may have many devices connected
+	connections map[uint64]map[*domain.Connection]struct{}
+}
+
+func (r *connectionRegistry) Add(userID uint64, conn domain.Connection) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the pointer tells apart equal connections
+	key := &conn
+
+	if r.connections[userID] == nil {
+		r.connections[userID] = make(map[*domain.Connection]struct{})
+	}
+
+	r.connections[userID][key] = struct{}{}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.connections[userID], key)
+
+		if len(r.connections[userID]) == 0 {
+			delete(r.connections, userID)
+		}
+	}
+}
+
+func (r *connectionRegistry) Disconnect(userID uint64, code int, reason string) int {
+	conns := r.userConnections(userID)
+
+	// they remove themselves once closed
+	for _, conn := range conns {
+		conn.Disconnect(code, reason)
+	}
+
+	return len(conns)
+}
+
+func (r *connectionRegistry) Send(userID uint64, event json.RawMessage) int {
+	conns := r.userConnections(userID)
+
+	for _, conn := range conns {
+		conn.Send(event)
+	}
+
+	return len(conns)
+}
+
+// Copied, so they are used without holding the lock
+func (r *connectionRegistry) userConnections(userID uint64) []domain.Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conns := make([]domain.Connection, 0, len(r.connections[userID]))
+
+	for conn := range r.connections[userID] {
+		conns = append(conns, *conn)
+	}
+
+	return conns
+}
+
+func (r *connectionRegistry) Snapshot() map[uint64]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[uint64]int, len(r.connections))
+
+	for userID, conns := range r.connections {
+		counts[userID] = len(conns)
+	}
+
+	return counts
+}
+
+func NewConnectionRegistry() *connectionRegistry {
+	return &connectionRegistry{
+		connections: make(map[uint64]map[*domain.Connection]struct{}),
+	}
+}
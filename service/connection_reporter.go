@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+)
+
+// Shorter than the expiration of the reported counts
+const connectionReportInterval = 10 * time.Second
+
+// Reports the connections open on this node, so the
+// counts cover the whole cluster
+type connectionReporter struct {
+	repository  domain.ConnectionCountRepository
+	connections domain.ConnectionRegistry
+	nodeID      uint16
+}
+
+func (s *connectionReporter) Run(ctx context.Context) error {
+	defer internal.LogGoroutineClosed("ConnectionReporter.Run")
+
+	ticker := time.NewTicker(connectionReportInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.repository.ReportNode(ctx, s.nodeID, s.connections.Snapshot())
+		if err != nil {
+			log.Printf("err: report connections: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			// the connections are closed along with the node
+			if err = s.repository.RemoveNode(context.Background(), s.nodeID); err != nil {
+				log.Printf("err: remove node connections: %s", err)
+			}
+
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewConnectionReporter(
+	repository domain.ConnectionCountRepository,
+	connections domain.ConnectionRegistry,
+	nodeID uint16,
+) *connectionReporter {
+	return &connectionReporter{
+		repository:  repository,
+		connections: connections,
+		nodeID:      nodeID,
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+)
+
+// Applies the commands of the control plane to the
+// connections open on this node
+type controlPlaneListener struct {
+	controlPlane domain.ControlPlane
+	connections  domain.ConnectionRegistry
+}
+
+func (s *controlPlaneListener) Run(ctx context.Context) error {
+	defer internal.LogGoroutineClosed("ControlPlaneListener.Run")
+
+	err := s.controlPlane.Subscribe(ctx, func(command *domain.ControlCommand) {
+		switch command.Type {
+		case domain.ControlCommandDisconnect:
+			disconnected := s.connections.Disconnect(command.UserID, command.Code, command.Reason)
+
+			if disconnected > 0 {
+				log.Printf("Closed %d connections of user %d: %s", disconnected, command.UserID, command.Reason)
+			}
+		case domain.ControlCommandEvent:
+			s.connections.Send(command.UserID, command.Event)
+		default:
+			log.Printf("err: unknown control command %q", command.Type)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	return nil
+}
+
+func NewControlPlaneListener(
+	controlPlane domain.ControlPlane,
+	connections domain.ConnectionRegistry,
+) *controlPlaneListener {
+	return &controlPlaneListener{
+		controlPlane: controlPlane,
+		connections:  connections,
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+)
+
+const alertWebhookTimeout = 10 * time.Second
+
+type deadLetterAlert struct {
+	Count    int `json:"count"`
+	Previous int `json:"previous"`
+}
+
+// Alerts when the dead letter queue grows past the threshold,
+// through the logs and the webhook when one is configured
+type deadLetterMonitor struct {
+	queue      domain.DeadLetterQueue
+	interval   time.Duration
+	threshold  int
+	webhookURL string
+	client     *http.Client
+}
+
+func (s *deadLetterMonitor) Run(ctx context.Context) error {
+	defer internal.LogGoroutineClosed("DeadLetterMonitor.Run")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	previous, err := s.queue.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		count, err := s.queue.Count(ctx)
+		if err != nil {
+			log.Printf("err: count dead letters: %s", err)
+			continue
+		}
+
+		if count > previous && count >= s.threshold {
+			if err = s.alert(ctx, count, previous); err != nil {
+				log.Printf("err: send dead letter alert: %s", err)
+			}
+		}
+
+		previous = count
+	}
+}
+
+func (s *deadLetterMonitor) alert(ctx context.Context, count, previous int) error {
+	log.Printf("alert: dead letter queue grew from %d to %d messages", previous, count)
+
+	if s.webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(deadLetterAlert{
+		Count:    count,
+		Previous: previous,
+	})
+	if err != nil {
+		return fmt.Errorf("json encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+
+	return nil
+}
+
+func NewDeadLetterMonitor(
+	queue domain.DeadLetterQueue,
+	interval time.Duration,
+	threshold int,
+	webhookURL string,
+) *deadLetterMonitor {
+	return &deadLetterMonitor{
+		queue:      queue,
+		interval:   interval,
+		threshold:  threshold,
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: alertWebhookTimeout,
+		},
+	}
+}
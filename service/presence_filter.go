@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Blocks made on other nodes reach the open
+// connections after at most this long
+const blockedPeersRefreshInterval = 30 * time.Second
+
+// Drops the presence of the users blocked by userID and of the
+// ones that blocked userID before it is written to the buffer
+type presenceFilter struct {
+	domain.WebsocketWriteBuffer
+	repository domain.BlockRepository
+	userID     uint64
+	peers      map[uint64]struct{}
+	loadedAt   time.Time
+}
+
+func (f *presenceFilter) Write(body any) {
+	if presence, ok := body.(*domain.Presence); ok && f.isBlocked(presence.UserID) {
+		return
+	}
+
+	f.WebsocketWriteBuffer.Write(body)
+}
+
+func (f *presenceFilter) isBlocked(peerID uint64) bool {
+	if time.Since(f.loadedAt) >= blockedPeersRefreshInterval {
+		f.load()
+	}
+
+	_, ok := f.peers[peerID]
+
+	return ok
+}
+
+// Keeps the previous peers if they can't be loaded
+func (f *presenceFilter) load() {
+	peers, err := f.repository.ListBlockedPeers(context.Background(), f.userID)
+	if err != nil {
+		log.Printf("err: list blocked peers of %d: %s", f.userID, err)
+		return
+	}
+
+	f.peers = make(map[uint64]struct{}, len(peers))
+
+	for _, id := range peers {
+		f.peers[id] = struct{}{}
+	}
+
+	f.loadedAt = time.Now()
+}
+
+func newPresenceFilter(
+	buff domain.WebsocketWriteBuffer,
+	repository domain.BlockRepository,
+	userID uint64,
+) *presenceFilter {
+	return &presenceFilter{
+		WebsocketWriteBuffer: buff,
+		repository:           repository,
+		userID:               userID,
+	}
+}
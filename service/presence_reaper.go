@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+)
+
+type presenceReaper struct {
+	repository     domain.PresenceRepository
+	channelFactory domain.ChannelFactory
+}
+
+func (s *presenceReaper) Run(ctx context.Context) error {
+	channel, err := s.channelFactory.NewChannel()
+	if err != nil {
+		return fmt.Errorf("new channel: %w", err)
+	}
+
+	defer channel.Close()
+
+	defer internal.LogGoroutineClosed("PresenceReaper.Run")
+
+	expired := make(chan uint64)
+	watchErr := make(chan error, 1)
+
+	go func() {
+		watchErr <- s.repository.WatchExpiredUsers(ctx, expired)
+	}()
+
+	for {
+		select {
+		case err = <-watchErr:
+			if err != nil {
+				return fmt.Errorf("watch expired users: %w", err)
+			}
+
+			return nil
+		case userID := <-expired:
+			if err = s.reap(ctx, channel, userID); err != nil {
+				log.Printf("err: reap user %d: %s", userID, err)
+			}
+		}
+	}
+}
+
+func (s *presenceReaper) reap(
+	ctx context.Context,
+	channel domain.StreamChannel,
+	userID uint64,
+) error {
+	claimed, err := s.repository.ClaimExpiredUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("claim: %w", err)
+	}
+
+	// another node is already publishing it
+	if !claimed {
+		return nil
+	}
+
+	// the user may have reconnected after the key expired
+	online, err := s.repository.IsOnline(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("check online: %w", err)
+	}
+
+	if online {
+		return nil
+	}
+
+	body := domain.Presence{
+		Status: domain.PresenceStatusOffline,
+		UserID: userID,
+	}
+
+	if err = channel.Publish(
+		domain.ChannelExchangePresence,
+		"",
+		body,
+	); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+func NewPresenceReaper(
+	repository domain.PresenceRepository,
+	channelFactory domain.ChannelFactory,
+) *presenceReaper {
+	return &presenceReaper{
+		repository:     repository,
+		channelFactory: channelFactory,
+	}
+}
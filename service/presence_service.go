@@ -8,8 +8,9 @@ import (
 )
 
 type presenceService struct {
-	repository domain.PresenceRepository
-	channel    domain.StreamChannel
+	repository      domain.PresenceRepository
+	blockRepository domain.BlockRepository
+	channel         domain.StreamChannel
 }
 
 func (s *presenceService) SetChannel(channel domain.StreamChannel) {
@@ -42,8 +43,8 @@ func (s *presenceService) RefreshUserPresence(ctx context.Context, userID uint64
 	return s.repository.SetKeyExpiration(ctx, userID)
 }
 
-func (s *presenceService) SubscribeUserPresenceUpdate(buff domain.WebsocketWriteBuffer) error {
-	return s.channel.Subscribe(buff)
+func (s *presenceService) SubscribeUserPresenceUpdate(userID uint64, buff domain.WebsocketWriteBuffer) error {
+	return s.channel.Subscribe(newPresenceFilter(buff, s.blockRepository, userID))
 }
 
 func (s *presenceService) SetUserOffline(ctx context.Context, userID uint64) error {
@@ -70,8 +71,12 @@ func (s *presenceService) SetUserOffline(ctx context.Context, userID uint64) err
 	return nil
 }
 
-func NewPresence(repository domain.PresenceRepository) *presenceService {
+func NewPresence(
+	repository domain.PresenceRepository,
+	blockRepository domain.BlockRepository,
+) *presenceService {
 	return &presenceService{
-		repository: repository,
+		repository:      repository,
+		blockRepository: blockRepository,
 	}
 }
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const rateLimitKeyPrefix = "rate-limit"
+
+type rateLimitService struct {
+	limiter        domain.RateLimiter
+	policy         *domain.RateLimitPolicy
+	userRepository domain.UserRepository
+}
+
+func (s *rateLimitService) Allow(ctx context.Context, client *domain.Client, action string) error {
+	if limit, ok := s.policy.UserLimits[action]; ok && limit.Enabled() {
+		if factor, ok := s.tierFactor(ctx, client.UserID); ok {
+			limit = limit.Scale(factor)
+		}
+
+		key := fmt.Sprintf("%s:%s:user:%d", rateLimitKeyPrefix, action, client.UserID)
+
+		if err := s.take(ctx, key, limit, action); err != nil {
+			return err
+		}
+	}
+
+	if limit, ok := s.policy.IPLimits[action]; ok && limit.Enabled() && client.IP != "" {
+		key := fmt.Sprintf("%s:%s:ip:%s", rateLimitKeyPrefix, action, client.IP)
+
+		if err := s.take(ctx, key, limit, action); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The tier is set by the administrators, never by the client. When
+// it can't be read the limits are applied as they are
+func (s *rateLimitService) tierFactor(ctx context.Context, userID uint64) (float64, bool) {
+	if len(s.policy.Tiers) == 0 {
+		return 0, false
+	}
+
+	tier, err := s.userRepository.GetTier(ctx, userID)
+	if err != nil {
+		log.Printf("err: get tier of %d: %s", userID, err)
+		return 0, false
+	}
+
+	factor, ok := s.policy.Tiers[tier]
+
+	return factor, ok
+}
+
+// Lets the request through when the limiter fails, an unavailable
+// redis shouldn't stop the chat
+func (s *rateLimitService) take(ctx context.Context, key string, limit domain.RateLimit, action string) error {
+	retryAfter, err := s.limiter.Take(ctx, key, limit)
+	if err != nil {
+		log.Printf("err: take token of %s: %s", key, err)
+		return nil
+	}
+
+	if retryAfter > 0 {
+		return &domain.RateLimitError{
+			Action:     action,
+			RetryAfter: retryAfter,
+		}
+	}
+
+	return nil
+}
+
+func NewRateLimit(
+	limiter domain.RateLimiter,
+	policy *domain.RateLimitPolicy,
+	userRepository domain.UserRepository,
+) *rateLimitService {
+	return &rateLimitService{
+		limiter:        limiter,
+		policy:         policy,
+		userRepository: userRepository,
+	}
+}
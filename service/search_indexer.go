@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type searchIndexer struct {
+	queue          domain.MessageEventQueue
+	index          domain.SearchIndex
+	chatRepository domain.ChatRepository
+}
+
+// The queue is declared before the backfill, so the events of the
+// messages sent meanwhile are applied after it
+func (s *searchIndexer) Run(ctx context.Context) error {
+	if err := s.backfill(ctx); err != nil {
+		return fmt.Errorf("backfill: %w", err)
+	}
+
+	return s.queue.Consume(ctx, s.handle)
+}
+
+// A new node only receives the messages sent after its queue was
+// declared, so an empty index is filled from the history
+func (s *searchIndexer) backfill(ctx context.Context) error {
+	count, err := s.index.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("count documents: %w", err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	var indexed int
+
+	err = s.chatRepository.ScanMessages(ctx, func(message *domain.Message) error {
+		if message.DeletedAt != nil {
+			return nil
+		}
+
+		if err := s.index.Index(ctx, searchDocument(message)); err != nil {
+			return fmt.Errorf("index message %d: %w", message.ID, err)
+		}
+
+		indexed++
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scan messages: %w", err)
+	}
+
+	log.Printf("Search index backfilled with %d messages", indexed)
+
+	return nil
+}
+
+func (s *searchIndexer) handle(ctx context.Context, event *domain.MessageEvent) error {
+	switch event.Type {
+	case domain.EventTypeMessageCreated, domain.EventTypeMessageEdited:
+		if event.Message == nil || event.Message.DeletedAt != nil {
+			return nil
+		}
+
+		if err := s.index.Index(ctx, searchDocument(event.Message)); err != nil {
+			return fmt.Errorf("index message: %w", err)
+		}
+	case domain.EventTypeMessageDeleted:
+		if event.Deletion == nil || event.Deletion.Scope != domain.DeleteScopeEveryone {
+			return nil
+		}
+
+		if err := s.index.Remove(ctx, event.Deletion.ID); err != nil {
+			return fmt.Errorf("remove message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func searchDocument(message *domain.Message) *domain.SearchDocument {
+	return &domain.SearchDocument{
+		MessageID: message.ID,
+		FromID:    message.FromID,
+		ToID:      message.ToID,
+		Content:   message.Content,
+		CreatedAt: message.CreatedAt,
+	}
+}
+
+func NewSearchIndexer(
+	queue domain.MessageEventQueue,
+	index domain.SearchIndex,
+	chatRepository domain.ChatRepository,
+) *searchIndexer {
+	return &searchIndexer{
+		queue:          queue,
+		index:          index,
+		chatRepository: chatRepository,
+	}
+}
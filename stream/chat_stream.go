@@ -1,14 +1,22 @@
 package stream
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// Fanout exchange of the message stream, every consumer
+// declares its own queue bound to it
+const MessageEventsExchange = "message.events"
+
 type Chat struct {
 	ch *amqp.Channel
 	id string
@@ -25,14 +33,15 @@ func NewChat(conn *amqp.Connection, userID uint64) (*Chat, error) {
 		id: fmt.Sprintf("%d", userID),
 	}
 
-	_, err = ch.QueueDeclare(
-		chat.id, // name
-		true,    // durable
-		false,   // delete when unused
-		false,   // exclusive
-		false,   // no-wait
-		nil,     // arguments
-	)
+	if err = declareMessageEventsExchange(ch); err != nil {
+		return nil, fmt.Errorf("setup exchange: %w", err)
+	}
+
+	if err = DeclareDeadLetters(ch); err != nil {
+		return nil, fmt.Errorf("setup dead letters: %w", err)
+	}
+
+	chat.ch, err = declareUserQueue(conn, ch, chat.id)
 	if err != nil {
 		return nil, fmt.Errorf("setup queue: %w", err)
 	}
@@ -40,8 +49,37 @@ func NewChat(conn *amqp.Connection, userID uint64) (*Chat, error) {
 	return chat, nil
 }
 
+// Opens a stream that only dispatches, for processes that
+// don't consume the queue of any user
+func NewChatDispatcher(conn *amqp.Connection) (*Chat, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	if err = declareMessageEventsExchange(ch); err != nil {
+		return nil, fmt.Errorf("setup exchange: %w", err)
+	}
+
+	return &Chat{
+		ch: ch,
+	}, nil
+}
+
+func declareMessageEventsExchange(ch *amqp.Channel) error {
+	return ch.ExchangeDeclare(
+		MessageEventsExchange, // name
+		"fanout",              // type
+		true,                  // durable
+		false,                 // auto-delete
+		false,                 // internal
+		false,                 // no-wait
+		nil,                   // args
+	)
+}
+
 func (s *Chat) DispatchMessage(msg *domain.Message) error {
-	err := s.publish(fmt.Sprintf("%d", msg.ToID), *msg)
+	err := s.publish(fmt.Sprintf("%d", msg.ToID), *msg, "")
 	if err != nil {
 		return fmt.Errorf("publish message: %w", err)
 	}
@@ -49,7 +87,53 @@ func (s *Chat) DispatchMessage(msg *domain.Message) error {
 	return nil
 }
 
-func (s *Chat) publish(key string, decodedBody any) error {
+func (s *Chat) DispatchEvent(toID uint64, event *domain.Event) error {
+	err := s.publish(fmt.Sprintf("%d", toID), event, "")
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Chat) DispatchEphemeralEvent(toID uint64, event *domain.Event, ttl time.Duration) error {
+	err := s.publish(
+		fmt.Sprintf("%d", toID),
+		event,
+		strconv.FormatInt(ttl.Milliseconds(), 10),
+	)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Chat) PublishMessageEvent(event *domain.MessageEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	err = s.ch.Publish(
+		MessageEventsExchange, // exchange
+		"",                    // routing key (ignored by fanout)
+		false,                 // mandatory
+		false,                 // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
+		})
+	if err != nil {
+		return fmt.Errorf("publish message event: %w", err)
+	}
+
+	return nil
+}
+
+// An empty expiration keeps the message in the queue until it is consumed
+func (s *Chat) publish(key string, decodedBody any, expiration string) error {
 	body, err := json.Marshal(decodedBody)
 	if err != nil {
 		return fmt.Errorf("json encode body: %w", err)
@@ -63,6 +147,7 @@ func (s *Chat) publish(key string, decodedBody any) error {
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        body,
+			Expiration:  expiration,
 		})
 	if err != nil {
 		return fmt.Errorf("send message: %w", err)
@@ -72,7 +157,7 @@ func (s *Chat) publish(key string, decodedBody any) error {
 }
 
 // Call Close to stop consuming
-func (s *Chat) ConsumeMessages(conn domain.WebsocketConnection) error {
+func (s *Chat) ConsumeMessages(buff domain.WebsocketWriteBuffer) error {
 	msgs, err := s.ch.Consume(
 		s.id,  // queue
 		"",    // consumer
@@ -86,21 +171,19 @@ func (s *Chat) ConsumeMessages(conn domain.WebsocketConnection) error {
 		return err
 	}
 
-	for d := range msgs {
-		var msg domain.MessageReceivedResponse
+	defer internal.LogGoroutineClosed("Chat.ConsumeMessages")
 
-		if err = json.Unmarshal(d.Body, &msg); err != nil {
+	for d := range msgs {
+		body, err := decodeDelivery(d.Body)
+		if err != nil {
 			log.Printf("err: json decode: %s", err)
 
-			d.Reject(false)
+			RejectDelivery(s.ch, &d, s.id, fmt.Errorf("json decode: %w", err))
 
 			continue
 		}
 
-		if err = conn.WriteJSON(msg); err != nil {
-			log.Printf("err: write json: %s", err)
-			continue
-		}
+		buff.Write(body)
 
 		if err = d.Ack(false); err != nil {
 			log.Printf("err: ack: %s", err)
@@ -111,6 +194,32 @@ func (s *Chat) ConsumeMessages(conn domain.WebsocketConnection) error {
 	return nil
 }
 
+// Events are delivered as they were published, anything
+// without a type is a chat message
+func decodeDelivery(body []byte) (any, error) {
+	var event domain.Event
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// ids in the payloads don't fit in a float64
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&event); err != nil {
+		return nil, err
+	}
+
+	if event.Type != "" {
+		return event, nil
+	}
+
+	var msg domain.MessageReceivedResponse
+
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
 func (q *Chat) Close() {
 	q.ch.Close()
 }
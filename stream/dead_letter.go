@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// Receives what the user queues dead letter themselves,
+	// only rejections are routed to the dead letter queue
+	DeadLetterExchange = "chat.dead-letters"
+	DeadLetterQueue    = "chat.dead-letters"
+
+	RejectionReasonHeader = "x-rejection-reason"
+	RejectedAtHeader      = "x-rejected-at"
+	SourceHeader          = "x-source"
+)
+
+// Declares the exchange and the queue of the dead letters,
+// expired ephemeral events are not kept
+func DeclareDeadLetters(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(
+		DeadLetterExchange, // name
+		"headers",          // type
+		true,               // durable
+		false,              // auto-delete
+		false,              // internal
+		false,              // no-wait
+		nil,                // args
+	)
+	if err != nil {
+		return fmt.Errorf("setup exchange: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(
+		DeadLetterQueue, // name
+		true,            // durable
+		false,           // delete when unused
+		false,           // exclusive
+		false,           // no-wait
+		nil,             // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("setup queue: %w", err)
+	}
+
+	err = ch.QueueBind(
+		DeadLetterQueue,    // queue
+		"",                 // routing key (ignored by headers)
+		DeadLetterExchange, // exchange
+		false,              // no-wait
+		amqp.Table{
+			// the x- headers are only matched with all-with-x
+			"x-match":              "all-with-x",
+			"x-first-death-reason": "rejected",
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("bind queue: %w", err)
+	}
+
+	return nil
+}
+
+// The limits and the dead letter exchange come from the
+// UserQueuePolicyName policy. Queues declared with arguments,
+// before it existed, can't be redeclared without them, so they
+// are used as they are; the policy still applies to them where
+// their arguments don't
+func declareUserQueue(conn *amqp.Connection, ch *amqp.Channel, name string) (*amqp.Channel, error) {
+	_, err := ch.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+
+	var amqpErr *amqp.Error
+
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.PreconditionFailed {
+		return ch, err
+	}
+
+	// the failed declaration closed the channel
+	ch, err = conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+
+	if _, err = ch.QueueDeclarePassive(name, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// Moves the delivery to the dead letter queue recording why it was
+// rejected. If that fails it is rejected, which dead letters it
+// without the reason when the queue has a dead letter exchange
+func RejectDelivery(ch *amqp.Channel, d *amqp.Delivery, source string, reason error) {
+	err := ch.Publish(
+		"",              // exchange
+		DeadLetterQueue, // routing key
+		false,           // mandatory
+		false,           // immediate
+		amqp.Publishing{
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			Body:         d.Body,
+			Headers: amqp.Table{
+				RejectionReasonHeader: reason.Error(),
+				RejectedAtHeader:      time.Now().UTC(),
+				SourceHeader:          source,
+			},
+		})
+	if err != nil {
+		log.Printf("err: publish dead letter: %s", err)
+
+		d.Reject(false)
+
+		return
+	}
+
+	if err = d.Ack(false); err != nil {
+		log.Printf("err: ack: %s", err)
+	}
+}
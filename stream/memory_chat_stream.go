@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/internal/memqueue"
+)
+
+// Chat stream of a single process, bodies are encoded as
+// on RabbitMQ so both are decoded the same way
+type MemoryChat struct {
+	broker *memqueue.Broker
+	name   string
+	queue  *memqueue.Queue
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewMemoryChat(broker *memqueue.Broker, userID uint64) *MemoryChat {
+	chat := NewMemoryChatDispatcher(broker)
+
+	chat.name = fmt.Sprintf("%d", userID)
+	chat.queue = broker.Queue(chat.name)
+
+	return chat
+}
+
+func NewMemoryChatDispatcher(broker *memqueue.Broker) *MemoryChat {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &MemoryChat{
+		broker: broker,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *MemoryChat) DispatchMessage(msg *domain.Message) error {
+	err := s.publish("", fmt.Sprintf("%d", msg.ToID), *msg, 0)
+	if err != nil {
+		return fmt.Errorf("publish message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryChat) DispatchEvent(toID uint64, event *domain.Event) error {
+	err := s.publish("", fmt.Sprintf("%d", toID), event, 0)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryChat) DispatchEphemeralEvent(toID uint64, event *domain.Event, ttl time.Duration) error {
+	err := s.publish("", fmt.Sprintf("%d", toID), event, ttl)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryChat) PublishMessageEvent(event *domain.MessageEvent) error {
+	err := s.publish(MessageEventsExchange, "", event, 0)
+	if err != nil {
+		return fmt.Errorf("publish message event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryChat) publish(exchange, key string, decodedBody any, ttl time.Duration) error {
+	body, err := json.Marshal(decodedBody)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	s.broker.Publish(exchange, key, body, ttl)
+
+	return nil
+}
+
+// Call Close to stop consuming
+func (s *MemoryChat) ConsumeMessages(buff domain.WebsocketWriteBuffer) error {
+	if s.queue == nil {
+		return fmt.Errorf("dispatchers can't consume")
+	}
+
+	defer internal.LogGoroutineClosed("MemoryChat.ConsumeMessages")
+
+	for {
+		data, ok := s.queue.Get(s.ctx)
+		if !ok {
+			return nil
+		}
+
+		body, err := decodeDelivery(data)
+		if err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			s.broker.DeadLetters().Add(s.name, data, fmt.Errorf("json decode: %w", err))
+
+			continue
+		}
+
+		buff.Write(body)
+	}
+}
+
+func (s *MemoryChat) Close() {
+	s.cancel()
+}
@@ -0,0 +1,290 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// Work queue stream holding the queue of every user,
+	// a message is removed once acknowledged
+	NATSUsersStream  = "CHAT_USERS"
+	NATSUsersSubject = "chat.user.*"
+	// Every consumer of the message stream has its own
+	// durable consumer and receives all the events
+	NATSMessageEventsStream  = "MESSAGE_EVENTS"
+	NATSMessageEventsSubject = "message.events"
+
+	// Kept until replayed, see DeadLetterQueue
+	NATSDeadLettersStream     = "DEAD_LETTERS"
+	NATSDeadLettersSubject    = "chat.dead-letters"
+	RejectionReasonNATSHeader = "Chat-Rejection-Reason"
+	RejectedAtNATSHeader      = "Chat-Rejected-At"
+	SourceNATSHeader          = "Chat-Source"
+
+	natsUserSubjectFormat  = "chat.user.%d"
+	natsUserConsumerFormat = "user-%d"
+	// Unix milliseconds after which an ephemeral event is dropped
+	expiresAtHeader    = "Chat-Expires-At"
+	natsPublishTimeout = 5 * time.Second
+	// Before a rejected message is redelivered, when it
+	// couldn't be moved to the dead letters
+	natsDeadLetterRetryDelay = 30 * time.Second
+)
+
+type NATSChat struct {
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+
+	mu       sync.Mutex
+	messages jetstream.MessagesContext
+	closed   bool
+}
+
+// The durable consumer of the user is shared by all its connections,
+// like the queue of RabbitMQ, and is deleted after the expiry of the
+// policy. The other limits are applied to the stream, see NATSUsersStreamConfig
+func NewNATSChat(js jetstream.JetStream, userID uint64, policy *domain.UserQueuePolicy) (*NATSChat, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsPublishTimeout)
+	defer cancel()
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, NATSUsersStream, jetstream.ConsumerConfig{
+		Durable:       fmt.Sprintf(natsUserConsumerFormat, userID),
+		FilterSubject: fmt.Sprintf(natsUserSubjectFormat, userID),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		// zero never deletes it
+		InactiveThreshold: policy.Expiry,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup consumer: %w", err)
+	}
+
+	return &NATSChat{
+		js:       js,
+		consumer: consumer,
+	}, nil
+}
+
+func NewNATSChatDispatcher(js jetstream.JetStream) *NATSChat {
+	return &NATSChat{
+		js: js,
+	}
+}
+
+func (s *NATSChat) DispatchMessage(msg *domain.Message) error {
+	err := s.publish(fmt.Sprintf(natsUserSubjectFormat, msg.ToID), *msg, 0)
+	if err != nil {
+		return fmt.Errorf("publish message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *NATSChat) DispatchEvent(toID uint64, event *domain.Event) error {
+	err := s.publish(fmt.Sprintf(natsUserSubjectFormat, toID), event, 0)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *NATSChat) DispatchEphemeralEvent(toID uint64, event *domain.Event, ttl time.Duration) error {
+	err := s.publish(fmt.Sprintf(natsUserSubjectFormat, toID), event, ttl)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *NATSChat) PublishMessageEvent(event *domain.MessageEvent) error {
+	err := s.publish(NATSMessageEventsSubject, event, 0)
+	if err != nil {
+		return fmt.Errorf("publish message event: %w", err)
+	}
+
+	return nil
+}
+
+// A ttl of zero keeps the message until it is consumed
+func (s *NATSChat) publish(subject string, decodedBody any, ttl time.Duration) error {
+	body, err := json.Marshal(decodedBody)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = body
+
+	if ttl > 0 {
+		msg.Header.Set(
+			expiresAtHeader,
+			strconv.FormatInt(time.Now().Add(ttl).UnixMilli(), 10),
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsPublishTimeout)
+	defer cancel()
+
+	if _, err = s.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	return nil
+}
+
+// Call Close to stop consuming
+func (s *NATSChat) ConsumeMessages(buff domain.WebsocketWriteBuffer) error {
+	if s.consumer == nil {
+		return fmt.Errorf("dispatchers can't consume")
+	}
+
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	messages, err := s.consumer.Messages()
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	s.messages = messages
+
+	s.mu.Unlock()
+
+	defer internal.LogGoroutineClosed("NATSChat.ConsumeMessages")
+
+	for {
+		msg, err := messages.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("next message: %w", err)
+		}
+
+		if IsExpired(msg.Headers().Get(expiresAtHeader)) {
+			msg.Ack()
+			continue
+		}
+
+		body, err := decodeDelivery(msg.Data())
+		if err != nil {
+			log.Printf("err: json decode: %s", err)
+
+			RejectNATSMessage(s.js, msg, fmt.Errorf("json decode: %w", err))
+
+			continue
+		}
+
+		buff.Write(body)
+
+		if err = msg.Ack(); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	}
+}
+
+func (s *NATSChat) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	if s.messages != nil {
+		s.messages.Stop()
+	}
+}
+
+// Each subject of the stream is the queue of a user, so
+// the limits of the policy are applied per subject
+func NATSUsersStreamConfig(policy *domain.UserQueuePolicy) jetstream.StreamConfig {
+	config := jetstream.StreamConfig{
+		Name:              NATSUsersStream,
+		Subjects:          []string{NATSUsersSubject},
+		Retention:         jetstream.WorkQueuePolicy,
+		MaxAge:            policy.MessageTTL,
+		MaxMsgsPerSubject: -1,
+	}
+
+	if policy.MaxLength > 0 {
+		config.MaxMsgsPerSubject = int64(policy.MaxLength)
+
+		if policy.Overflow == domain.UserQueueOverflowRejectPublish {
+			config.Discard = jetstream.DiscardNew
+			config.DiscardNewPerSubject = true
+		}
+	}
+
+	return config
+}
+
+// Publishes the data to the dead letters recording why it was
+// rejected, the source is the subject it is replayed to
+func PublishNATSDeadLetter(js jetstream.JetStream, source string, data []byte, reason error) error {
+	msg := nats.NewMsg(NATSDeadLettersSubject)
+	msg.Data = data
+
+	msg.Header.Set(RejectionReasonNATSHeader, reason.Error())
+	msg.Header.Set(RejectedAtNATSHeader, time.Now().UTC().Format(time.RFC3339Nano))
+	msg.Header.Set(SourceNATSHeader, source)
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsPublishTimeout)
+	defer cancel()
+
+	if _, err := js.PublishMsg(ctx, msg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Moves the message to the dead letters and terminates it. If
+// it can't be moved it is redelivered later instead of lost
+func RejectNATSMessage(js jetstream.JetStream, msg jetstream.Msg, reason error) {
+	if err := PublishNATSDeadLetter(js, msg.Subject(), msg.Data(), reason); err != nil {
+		log.Printf("err: publish dead letter: %s", err)
+
+		if err = msg.NakWithDelay(natsDeadLetterRetryDelay); err != nil {
+			log.Printf("err: nak message: %s", err)
+		}
+
+		return
+	}
+
+	if err := msg.Term(); err != nil {
+		log.Printf("err: term message: %s", err)
+	}
+}
+
+// Whether an expiration in unix milliseconds has passed,
+// an empty one never expires
+func IsExpired(expiresAt string) bool {
+	if expiresAt == "" {
+		return false
+	}
+
+	milliseconds, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().UnixMilli() > milliseconds
+}
@@ -0,0 +1,249 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/internal"
+	"github.com/lam0glia/chat-system/internal/redisstream"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Stream of every published MessageEvent, each consumer
+	// reads it with its own group
+	RedisMessageEventsStream = "message.events"
+	// Roughly how many message events are kept for consumers
+	// that are down
+	RedisMessageEventsMaxLen = 100000
+
+	// Kept until replayed, see DeadLetterQueue
+	RedisDeadLettersStream = "chat:dead-letters"
+	RedisBodyField         = "body"
+	RedisReasonField       = "reason"
+	RedisSourceField       = "source"
+	// Unix milliseconds
+	RedisRejectedAtField = "rejected_at"
+
+	redisUserStreamFormat = "chat:user:%d"
+	// All the connections of a user share the group and the
+	// consumer, so a reconnection reads what was left pending
+	redisChatGroup = "chat"
+	// Unix milliseconds after which an ephemeral event is dropped
+	redisExpiresAtField = "expires_at"
+)
+
+type RedisChat struct {
+	client *redis.Client
+	policy *domain.UserQueuePolicy
+	// stream of the user, empty for dispatchers
+	key    string
+	reader *redisstream.Reader
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRedisChat(client *redis.Client, userID uint64, policy *domain.UserQueuePolicy) (*RedisChat, error) {
+	chat := NewRedisChatDispatcher(client, policy)
+
+	chat.key = fmt.Sprintf(redisUserStreamFormat, userID)
+
+	err := redisstream.EnsureGroup(chat.ctx, client, chat.key, redisChatGroup, "0")
+	if err != nil {
+		return nil, fmt.Errorf("setup group: %w", err)
+	}
+
+	chat.reader = redisstream.NewReader(
+		client,
+		chat.key,
+		redisChatGroup,
+		strconv.FormatUint(userID, 10),
+	)
+
+	chat.reader.KeepAlive(policy.Expiry)
+
+	return chat, nil
+}
+
+func NewRedisChatDispatcher(client *redis.Client, policy *domain.UserQueuePolicy) *RedisChat {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &RedisChat{
+		client: client,
+		policy: policy,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *RedisChat) DispatchMessage(msg *domain.Message) error {
+	err := s.publish(fmt.Sprintf(redisUserStreamFormat, msg.ToID), *msg, 0)
+	if err != nil {
+		return fmt.Errorf("publish message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisChat) DispatchEvent(toID uint64, event *domain.Event) error {
+	err := s.publish(fmt.Sprintf(redisUserStreamFormat, toID), event, 0)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisChat) DispatchEphemeralEvent(toID uint64, event *domain.Event, ttl time.Duration) error {
+	err := s.publish(fmt.Sprintf(redisUserStreamFormat, toID), event, ttl)
+	if err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisChat) PublishMessageEvent(event *domain.MessageEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	err = s.client.XAdd(s.ctx, &redis.XAddArgs{
+		Stream: RedisMessageEventsStream,
+		MaxLen: RedisMessageEventsMaxLen,
+		Approx: true,
+		Values: map[string]any{RedisBodyField: body},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("publish message event: %w", err)
+	}
+
+	return nil
+}
+
+// A ttl of zero keeps the message until it is consumed or
+// discarded by the policy
+func (s *RedisChat) publish(key string, decodedBody any, ttl time.Duration) error {
+	body, err := json.Marshal(decodedBody)
+	if err != nil {
+		return fmt.Errorf("json encode body: %w", err)
+	}
+
+	if ttl <= 0 || (s.policy.MessageTTL > 0 && s.policy.MessageTTL < ttl) {
+		ttl = s.policy.MessageTTL
+	}
+
+	values := map[string]any{RedisBodyField: body}
+
+	if ttl > 0 {
+		values[redisExpiresAtField] = time.Now().Add(ttl).UnixMilli()
+	}
+
+	args := &redis.XAddArgs{
+		Stream: key,
+		Values: values,
+	}
+
+	if s.policy.MaxLength > 0 {
+		if s.policy.Overflow == domain.UserQueueOverflowRejectPublish {
+			length, err := s.client.XLen(s.ctx, key).Result()
+			if err != nil {
+				return fmt.Errorf("queue length: %w", err)
+			}
+
+			// discarded like a publish to a full queue of RabbitMQ
+			if length >= int64(s.policy.MaxLength) {
+				return nil
+			}
+		} else {
+			args.MaxLen = int64(s.policy.MaxLength)
+		}
+	} else if s.policy.MessageTTL > 0 {
+		// only one trimming strategy can be used, without a
+		// maximum length the expired entries are trimmed
+		args.MinID = strconv.FormatInt(time.Now().Add(-s.policy.MessageTTL).UnixMilli(), 10)
+	}
+
+	pipe := s.client.TxPipeline()
+
+	pipe.XAdd(s.ctx, args)
+
+	if s.policy.Expiry > 0 {
+		pipe.PExpire(s.ctx, key, s.policy.Expiry)
+	}
+
+	if _, err = pipe.Exec(s.ctx); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	return nil
+}
+
+// Call Close to stop consuming
+func (s *RedisChat) ConsumeMessages(buff domain.WebsocketWriteBuffer) error {
+	if s.reader == nil {
+		return fmt.Errorf("dispatchers can't consume")
+	}
+
+	defer internal.LogGoroutineClosed("RedisChat.ConsumeMessages")
+
+	for {
+		entry, err := s.reader.Next(s.ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			return fmt.Errorf("next message: %w", err)
+		}
+
+		expiresAt, _ := entry.Values[redisExpiresAtField].(string)
+		data, _ := entry.Values[RedisBodyField].(string)
+
+		if !IsExpired(expiresAt) {
+			body, err := decodeDelivery([]byte(data))
+			if err != nil {
+				log.Printf("err: json decode: %s", err)
+
+				err = AddRedisDeadLetter(s.client, s.key, []byte(data), fmt.Errorf("json decode: %w", err))
+				if err != nil {
+					// not acknowledged, so it is delivered
+					// again to the next connection
+					log.Printf("err: add dead letter, leaving %s pending: %s", entry.ID, err)
+					continue
+				}
+			} else {
+				buff.Write(body)
+			}
+		}
+
+		if err = s.reader.Done(context.Background(), entry.ID); err != nil {
+			log.Printf("err: ack: %s", err)
+		}
+	}
+}
+
+func (s *RedisChat) Close() {
+	s.cancel()
+}
+
+// Adds the body to the dead letters recording why it was rejected,
+// the source is the stream or channel it is replayed to
+func AddRedisDeadLetter(client *redis.Client, source string, body []byte, reason error) error {
+	return client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: RedisDeadLettersStream,
+		Values: map[string]any{
+			RedisBodyField:       body,
+			RedisReasonField:     reason.Error(),
+			RedisSourceField:     source,
+			RedisRejectedAtField: time.Now().UnixMilli(),
+		},
+	}).Err()
+}
@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type writeBufferFunc func(body any)
+
+func (f writeBufferFunc) DeliveryToClient() {}
+
+func (f writeBufferFunc) Write(body any) {
+	f(body)
+}
+
+func TestRedisChatKeepsEntryWhenDeadLetterFails(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() { client.Close() })
+
+	chat, err := NewRedisChat(client, 1, &domain.UserQueuePolicy{})
+	if err != nil {
+		t.Fatalf("new chat: %s", err)
+	}
+
+	// XADD to a key of another type fails
+	if err = server.Set(RedisDeadLettersStream, "not a stream"); err != nil {
+		t.Fatalf("set: %s", err)
+	}
+
+	key := fmt.Sprintf(redisUserStreamFormat, 1)
+
+	var poisonID string
+
+	for _, body := range []string{"{", `{"id":2,"from":3,"content":"hi"}`} {
+		id, err := client.XAdd(context.Background(), &redis.XAddArgs{
+			Stream: key,
+			Values: map[string]any{RedisBodyField: body},
+		}).Result()
+		if err != nil {
+			t.Fatalf("add entry: %s", err)
+		}
+
+		if poisonID == "" {
+			poisonID = id
+		}
+	}
+
+	// entries are delivered in order, so the poison one
+	// was rejected once the next one is written
+	written := make(chan any, 1)
+
+	consumed := make(chan error, 1)
+
+	go func() {
+		consumed <- chat.ConsumeMessages(writeBufferFunc(func(body any) {
+			written <- body
+		}))
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the valid message was not written")
+	}
+
+	chat.Close()
+
+	if err = <-consumed; err != nil {
+		t.Fatalf("consume: %s", err)
+	}
+
+	pending, err := client.XPending(context.Background(), key, redisChatGroup).Result()
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+
+	if pending.Count != 1 || pending.Lower != poisonID {
+		t.Fatalf("got pending %+v, want only %s", pending, poisonID)
+	}
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const (
+	UserQueuePolicyName = "chat-user-queues"
+	// The queues of the users are named by their ids
+	UserQueuePattern = "^[0-9]+$"
+
+	managementTimeout = 10 * time.Second
+)
+
+type managementPolicy struct {
+	Pattern    string         `json:"pattern"`
+	Definition map[string]any `json:"definition"`
+	ApplyTo    string         `json:"apply-to"`
+	Priority   int            `json:"priority"`
+}
+
+// Keys of the RabbitMQ policy of the user queues. Unlike
+// queue arguments, a policy also applies to the queues that
+// already exist and can be changed at any time
+func UserQueuePolicyDefinition(policy *domain.UserQueuePolicy) map[string]any {
+	definition := map[string]any{
+		"dead-letter-exchange": DeadLetterExchange,
+	}
+
+	if policy.MessageTTL > 0 {
+		definition["message-ttl"] = policy.MessageTTL.Milliseconds()
+	}
+
+	if policy.MaxLength > 0 {
+		definition["max-length"] = policy.MaxLength
+		definition["overflow"] = policy.Overflow
+	}
+
+	if policy.Expiry > 0 {
+		definition["expires"] = policy.Expiry.Milliseconds()
+	}
+
+	return definition
+}
+
+// Sets the policy of the user queues of vhost through the
+// management API, replacing the one set before
+func ApplyUserQueuePolicy(
+	ctx context.Context,
+	managementURL string,
+	vhost string,
+	policy *domain.UserQueuePolicy,
+) error {
+	body, err := json.Marshal(managementPolicy{
+		Pattern:    UserQueuePattern,
+		Definition: UserQueuePolicyDefinition(policy),
+		ApplyTo:    "queues",
+	})
+	if err != nil {
+		return fmt.Errorf("json encode policy: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, managementTimeout)
+	defer cancel()
+
+	endpoint := fmt.Sprintf(
+		"%s/api/policies/%s/%s",
+		strings.TrimSuffix(managementURL, "/"),
+		url.PathEscape(vhost),
+		UserQueuePolicyName,
+	)
+
+	// the credentials of the url are sent as basic auth
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("put: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("management api responded %s", res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// ErrBlocked when any of them blocked the other
+func checkBlock(ctx context.Context, blockRepository domain.BlockRepository, fromID, toID uint64) error {
+	blocked, err := blockRepository.IsBlocked(ctx, fromID, toID)
+	if err != nil {
+		return fmt.Errorf("check block: %w", err)
+	}
+
+	if blocked {
+		return domain.ErrBlocked
+	}
+
+	return nil
+}
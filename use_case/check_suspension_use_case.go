@@ -0,0 +1,32 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type checkSuspension struct {
+	userRepository domain.UserRepository
+}
+
+func (uc *checkSuspension) Execute(ctx context.Context, userID uint64) error {
+	suspension, err := uc.userRepository.GetSuspension(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("get suspension: %w", err)
+	}
+
+	if suspension != nil && suspension.Active(time.Now()) {
+		return domain.ErrUserSuspended
+	}
+
+	return nil
+}
+
+func NewCheckSuspension(userRepository domain.UserRepository) *checkSuspension {
+	return &checkSuspension{
+		userRepository: userRepository,
+	}
+}
@@ -0,0 +1,134 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type deleteMessage struct {
+	chatStream     domain.ChatStream
+	chatRepository domain.ChatRepository
+	blobStore      domain.BlobStore
+}
+
+func (uc *deleteMessage) Execute(ctx context.Context, request *domain.DeleteMessageRequest) error {
+	message, err := uc.chatRepository.GetMessage(ctx, request.From, request.To, request.ID)
+	if err != nil {
+		return fmt.Errorf("get message: %w", err)
+	}
+
+	switch request.Scope {
+	case domain.DeleteScopeEveryone:
+		if message.FromID != request.From {
+			return domain.ErrNotMessageSender
+		}
+
+		return deleteForEveryone(ctx, uc.chatStream, uc.chatRepository, uc.blobStore, message)
+	case domain.DeleteScopeMe:
+		if err = uc.chatRepository.HideMessage(ctx, request.From, request.To, message.ID); err != nil {
+			return fmt.Errorf("hide message: %w", err)
+		}
+
+		// only the devices of who deleted it are notified
+		dispatchDeletion(uc.chatStream, domain.MessageDeletion{
+			ID:        message.ID,
+			FromID:    message.FromID,
+			ToID:      message.ToID,
+			Scope:     request.Scope,
+			DeletedAt: time.Now(),
+		}, request.From)
+
+		return nil
+	default:
+		return domain.ErrInvalidDeleteScope
+	}
+}
+
+// Also used by the moderators, deleting it again does nothing.
+// The attachments go with the message
+func deleteForEveryone(
+	ctx context.Context,
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	message *domain.Message,
+) error {
+	if message.DeletedAt != nil {
+		return nil
+	}
+
+	deletion := domain.MessageDeletion{
+		ID:        message.ID,
+		FromID:    message.FromID,
+		ToID:      message.ToID,
+		Scope:     domain.DeleteScopeEveryone,
+		DeletedAt: time.Now(),
+	}
+
+	message.Content = ""
+	message.DeletedAt = &deletion.DeletedAt
+
+	if err := chatRepository.DeleteMessage(ctx, message); err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	deleteAttachmentBlobs(ctx, blobStore, message.Attachments)
+
+	message.AttachmentIDs = nil
+	message.Attachments = nil
+
+	if err := chatStream.PublishMessageEvent(&domain.MessageEvent{
+		Type:     domain.EventTypeMessageDeleted,
+		Deletion: &deletion,
+	}); err != nil {
+		log.Printf("err: publish message event: %s", err)
+	}
+
+	dispatchDeletion(chatStream, deletion, message.ToID, message.FromID)
+
+	return nil
+}
+
+// The attachments are already gone, a blob left behind is
+// only logged
+func deleteAttachmentBlobs(ctx context.Context, blobStore domain.BlobStore, attachments []domain.Attachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.BlobKey}
+
+		for _, thumbnail := range attachment.Thumbnails {
+			keys = append(keys, thumbnail.BlobKey)
+		}
+
+		for _, key := range keys {
+			if err := blobStore.Delete(ctx, key); err != nil {
+				log.Printf("err: delete blob %s of attachment %d: %s", key, attachment.ID, err)
+			}
+		}
+	}
+}
+
+func dispatchDeletion(chatStream domain.ChatStream, deletion domain.MessageDeletion, recipients ...uint64) {
+	event := domain.NewEvent(domain.EventTypeMessageDeleted, deletion)
+
+	for _, userID := range recipients {
+		if err := chatStream.DispatchEvent(userID, event); err != nil {
+			log.Printf("err: dispatch deleted message to %d: %s", userID, err)
+		}
+	}
+}
+
+func NewDeleteMessage(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+) *deleteMessage {
+	return &deleteMessage{
+		chatStream:     chatStream,
+		chatRepository: chatRepository,
+		blobStore:      blobStore,
+	}
+}
@@ -0,0 +1,34 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type disconnectUser struct {
+	controlPlane domain.ControlPlane
+}
+
+// Every node closes the connections it holds, as
+// they receive the command
+func (uc *disconnectUser) Execute(ctx context.Context, userID uint64, code int, reason string) error {
+	err := uc.controlPlane.Publish(ctx, &domain.ControlCommand{
+		Type:   domain.ControlCommandDisconnect,
+		UserID: userID,
+		Code:   code,
+		Reason: reason,
+	})
+	if err != nil {
+		return fmt.Errorf("publish disconnect: %w", err)
+	}
+
+	return nil
+}
+
+func NewDisconnectUser(controlPlane domain.ControlPlane) *disconnectUser {
+	return &disconnectUser{
+		controlPlane: controlPlane,
+	}
+}
@@ -0,0 +1,113 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type editMessage struct {
+	chatStream       domain.ChatStream
+	chatRepository   domain.ChatRepository
+	blockRepository  domain.BlockRepository
+	moderator        domain.Moderator
+	reviewRepository domain.ReviewRepository
+	editWindow       time.Duration
+	// In runes
+	maxLength int
+}
+
+func (uc *editMessage) Execute(ctx context.Context, request *domain.EditMessageRequest) (*domain.Message, error) {
+	content, err := sanitizeContent(request.Content, uc.maxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return nil, domain.ErrEmptyMessage
+	}
+
+	message, err := uc.chatRepository.GetMessage(ctx, request.From, request.To, request.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get message: %w", err)
+	}
+
+	if message.DeletedAt != nil {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	if message.FromID != request.From {
+		return nil, domain.ErrNotMessageSender
+	}
+
+	if time.Since(message.CreatedAt) > uc.editWindow {
+		return nil, domain.ErrEditWindowExpired
+	}
+
+	if err = checkBlock(ctx, uc.blockRepository, request.From, request.To); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	previousContent := message.Content
+
+	message.Content = content
+	message.EditedAt = &now
+
+	// otherwise a clean message could be edited into anything
+	verdict, err := moderateMessage(ctx, uc.moderator, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = uc.chatRepository.UpdateMessageContent(ctx, message, previousContent); err != nil {
+		return nil, fmt.Errorf("update message content: %w", err)
+	}
+
+	if err = uc.chatStream.PublishMessageEvent(&domain.MessageEvent{
+		Type:    domain.EventTypeMessageEdited,
+		Message: message,
+	}); err != nil {
+		log.Printf("err: publish message event: %s", err)
+	}
+
+	event := domain.NewEvent(domain.EventTypeMessageEdited, message)
+
+	// the sender also receives it to sync its other devices
+	for _, userID := range []uint64{message.ToID, message.FromID} {
+		if err = uc.chatStream.DispatchEvent(userID, event); err != nil {
+			log.Printf("err: dispatch edited message to %d: %s", userID, err)
+		}
+	}
+
+	if verdict.Action == domain.ModerationActionFlag {
+		flagMessage(ctx, uc.reviewRepository, message, verdict.Reason, now)
+	}
+
+	return message, nil
+}
+
+func NewEditMessage(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blockRepository domain.BlockRepository,
+	moderator domain.Moderator,
+	reviewRepository domain.ReviewRepository,
+	editWindow time.Duration,
+	maxLength int,
+) *editMessage {
+	return &editMessage{
+		chatStream:       chatStream,
+		chatRepository:   chatRepository,
+		blockRepository:  blockRepository,
+		moderator:        moderator,
+		reviewRepository: reviewRepository,
+		editWindow:       editWindow,
+		maxLength:        maxLength,
+	}
+}
@@ -0,0 +1,236 @@
+package use_case
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/lam0glia/chat-system/domain"
+	"golang.org/x/image/draw"
+)
+
+const (
+	thumbnailBlobKeyFormat = "attachments/%d/thumbnail-%d"
+	thumbnailJPEGQuality   = 80
+	// BlurHash components and the size of the image it is computed
+	// from, the hash is the same for any larger image
+	placeholderXComponents = 4
+	placeholderYComponents = 3
+	placeholderSourceSize  = 32
+)
+
+// Bounds of the longest side of the generated thumbnails
+var thumbnailSizes = []int{160, 480}
+
+type generateThumbnails struct {
+	chatStream     domain.ChatStream
+	chatRepository domain.ChatRepository
+	blobStore      domain.BlobStore
+	maxPixels      int
+}
+
+func (uc *generateThumbnails) Execute(ctx context.Context, job *domain.ThumbnailJob) error {
+	attachment, err := uc.chatRepository.GetAttachment(ctx, job.AttachmentID)
+	if err != nil {
+		// deleted with its message before the job ran
+		if errors.Is(err, domain.ErrAttachmentNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("get attachment: %w", err)
+	}
+
+	content, err := uc.blobStore.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		return fmt.Errorf("get blob: %w", err)
+	}
+
+	defer content.Close()
+
+	// bounded by ATTACHMENT_MAX_SIZE
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("read blob: %w", err)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image config: %w", err)
+	}
+
+	// checked again, the limit may have been lowered since the
+	// upload. Retrying wouldn't help, so the job is dropped
+	if exceedsPixels(config, uc.maxPixels) {
+		log.Printf("err: attachment %d has %dx%d pixels, skipping thumbnails", attachment.ID, config.Width, config.Height)
+		return nil
+	}
+
+	original, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	attachment.Thumbnails = nil
+
+	for _, size := range thumbnailSizes {
+		thumbnail, err := uc.storeThumbnail(ctx, attachment, original, size)
+		if err != nil {
+			return fmt.Errorf("store %d thumbnail: %w", size, err)
+		}
+
+		attachment.Thumbnails = append(attachment.Thumbnails, *thumbnail)
+	}
+
+	attachment.Placeholder, err = blurhash.Encode(
+		placeholderXComponents,
+		placeholderYComponents,
+		resize(original, placeholderSourceSize),
+	)
+	if err != nil {
+		return fmt.Errorf("encode placeholder: %w", err)
+	}
+
+	if err = uc.chatRepository.UpdateAttachmentThumbnails(ctx, attachment); err != nil {
+		// deleted while the thumbnails were generated, its
+		// deletion didn't know about them
+		if errors.Is(err, domain.ErrAttachmentNotFound) {
+			uc.deleteThumbnails(ctx, attachment)
+			return nil
+		}
+
+		return fmt.Errorf("update attachment: %w", err)
+	}
+
+	// attachments not sent yet will have the thumbnails when the
+	// message is loaded
+	if attachment.MessageID != nil {
+		uc.dispatchMessageUpdate(ctx, attachment)
+	}
+
+	return nil
+}
+
+func (uc *generateThumbnails) storeThumbnail(
+	ctx context.Context,
+	attachment *domain.Attachment,
+	original image.Image,
+	size int,
+) (*domain.Thumbnail, error) {
+	resized := resize(original, size)
+
+	var (
+		buff     bytes.Buffer
+		mimeType string
+		err      error
+	)
+
+	// keeping the transparency of the formats that support it
+	if attachment.MIMEType == "image/jpeg" {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buff, resized, &jpeg.Options{Quality: thumbnailJPEGQuality})
+	} else {
+		mimeType = "image/png"
+		err = png.Encode(&buff, resized)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	thumbnail := domain.Thumbnail{
+		Size:     size,
+		Width:    resized.Bounds().Dx(),
+		Height:   resized.Bounds().Dy(),
+		MIMEType: mimeType,
+		BlobKey:  fmt.Sprintf(thumbnailBlobKeyFormat, attachment.ID, size),
+	}
+
+	err = uc.blobStore.Put(
+		ctx,
+		thumbnail.BlobKey,
+		&buff,
+		int64(buff.Len()),
+		thumbnail.MIMEType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("put blob: %w", err)
+	}
+
+	return &thumbnail, nil
+}
+
+func (uc *generateThumbnails) deleteThumbnails(ctx context.Context, attachment *domain.Attachment) {
+	for _, thumbnail := range attachment.Thumbnails {
+		if err := uc.blobStore.Delete(ctx, thumbnail.BlobKey); err != nil {
+			log.Printf("err: delete blob %s of attachment %d: %s", thumbnail.BlobKey, attachment.ID, err)
+		}
+	}
+}
+
+func (uc *generateThumbnails) dispatchMessageUpdate(ctx context.Context, attachment *domain.Attachment) {
+	message, err := uc.chatRepository.GetMessage(
+		ctx,
+		attachment.UploaderID,
+		attachment.PeerID,
+		*attachment.MessageID,
+	)
+	if err != nil {
+		log.Printf("err: get message of attachment %d: %s", attachment.ID, err)
+		return
+	}
+
+	event := domain.NewEvent(domain.EventTypeMessageUpdated, message)
+
+	for _, userID := range []uint64{message.ToID, message.FromID} {
+		if err = uc.chatStream.DispatchEvent(userID, event); err != nil {
+			log.Printf("err: dispatch updated message to %d: %s", userID, err)
+		}
+	}
+}
+
+// Scales the image down so its longest side fits size,
+// smaller images are kept as they are
+func resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width > height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Over, nil)
+
+	return resized
+}
+
+func NewGenerateThumbnails(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	maxPixels int,
+) *generateThumbnails {
+	return &generateThumbnails{
+		chatStream:     chatStream,
+		chatRepository: chatRepository,
+		blobStore:      blobStore,
+		maxPixels:      maxPixels,
+	}
+}
@@ -0,0 +1,81 @@
+package use_case
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type listConversations struct {
+	conversationRepository domain.ConversationRepository
+}
+
+// Pinned conversations come first, the most recently pinned on top,
+// followed by the others from the latest message to the oldest
+func (uc *listConversations) Execute(
+	ctx context.Context,
+	userID uint64,
+	request *domain.ListConversationsRequest,
+) ([]domain.Conversation, error) {
+	conversations, err := uc.conversationRepository.ListConversations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list conversations: %w", err)
+	}
+
+	now := time.Now()
+
+	inbox := conversations[:0]
+
+	for _, conversation := range conversations {
+		// only settings were changed, there is nothing to list
+		if conversation.LastMessageAt == nil || conversation.Archived != request.Archived {
+			continue
+		}
+
+		// expired mutes are kept until the settings change
+		if !conversation.IsMuted(now) {
+			conversation.Muted = false
+			conversation.MutedUntil = nil
+		}
+
+		inbox = append(inbox, conversation)
+	}
+
+	slices.SortFunc(inbox, func(a, b domain.Conversation) int {
+		if c := compareTimes(b.PinnedAt, a.PinnedAt); c != 0 {
+			return c
+		}
+
+		if c := compareTimes(b.LastMessageAt, a.LastMessageAt); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.PeerID, b.PeerID)
+	})
+
+	return inbox, nil
+}
+
+// Empty times are the oldest
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	default:
+		return a.Compare(*b)
+	}
+}
+
+func NewListConversations(conversationRepository domain.ConversationRepository) *listConversations {
+	return &listConversations{
+		conversationRepository: conversationRepository,
+	}
+}
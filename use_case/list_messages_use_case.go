@@ -0,0 +1,187 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type listMessages struct {
+	chatRepository domain.ChatRepository
+}
+
+func (uc *listMessages) Execute(
+	ctx context.Context,
+	userID uint64,
+	request *domain.ListMessageRequest,
+) (*domain.MessagePage, error) {
+	// there is no message 0 to center on, and the id
+	// before it would wrap around
+	if request.AroundID != nil && *request.AroundID == 0 {
+		request.AroundID = nil
+	}
+
+	cursors := 0
+	for _, cursor := range []*uint64{request.BeforeID, request.AfterID, request.AroundID} {
+		if cursor != nil {
+			cursors++
+		}
+	}
+
+	if cursors > 1 {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = domain.DefaultMessagesLimit
+	}
+
+	limit = min(limit, domain.MaxMessagesLimit)
+
+	var (
+		page domain.MessagePage
+		err  error
+	)
+
+	switch {
+	case request.AfterID != nil:
+		var newer []domain.Message
+
+		newer, page.NextCursor, err = uc.newer(ctx, userID, request.To, request.AfterID, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Messages = newer
+		page.PrevCursor = firstID(newer)
+	case request.AroundID != nil:
+		// includes the message of the cursor
+		afterID := *request.AroundID - 1
+
+		var older, newer []domain.Message
+
+		older, page.PrevCursor, err = uc.older(ctx, userID, request.To, request.AroundID, limit/2)
+		if err != nil {
+			return nil, err
+		}
+
+		newer, page.NextCursor, err = uc.newer(ctx, userID, request.To, &afterID, limit-limit/2)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Messages = append(older, newer...)
+	default:
+		var older []domain.Message
+
+		older, page.PrevCursor, err = uc.older(ctx, userID, request.To, request.BeforeID, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Messages = older
+
+		// the latest messages have nothing newer yet
+		if request.BeforeID != nil {
+			page.NextCursor = lastID(older)
+		}
+	}
+
+	if !request.IncludeHidden {
+		page.Messages = domain.VisibleMessages(page.Messages)
+	}
+
+	if page.Messages == nil {
+		page.Messages = []domain.Message{}
+	}
+
+	return &page, nil
+}
+
+// Messages before beforeID, or the latest ones if it is nil,
+// in ascending order and the cursor to the ones before them
+func (uc *listMessages) older(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	beforeID *uint64,
+	limit int,
+) ([]domain.Message, *uint64, error) {
+	if limit == 0 {
+		return nil, beforeID, nil
+	}
+
+	// one more message tells if there are others
+	messages, err := uc.chatRepository.ListMessages(ctx, userID, peerID, &domain.MessageRange{
+		BeforeID:   beforeID,
+		Descending: true,
+		Limit:      limit + 1,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list messages: %w", err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	slices.Reverse(messages)
+
+	if !hasMore {
+		return messages, nil, nil
+	}
+
+	return messages, firstID(messages), nil
+}
+
+// Messages after afterID in ascending order and the cursor
+// to the ones after them
+func (uc *listMessages) newer(
+	ctx context.Context,
+	userID,
+	peerID uint64,
+	afterID *uint64,
+	limit int,
+) ([]domain.Message, *uint64, error) {
+	messages, err := uc.chatRepository.ListMessages(ctx, userID, peerID, &domain.MessageRange{
+		AfterID: afterID,
+		Limit:   limit + 1,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list messages: %w", err)
+	}
+
+	if len(messages) <= limit {
+		return messages, nil, nil
+	}
+
+	messages = messages[:limit]
+
+	return messages, lastID(messages), nil
+}
+
+func firstID(messages []domain.Message) *uint64 {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return &messages[0].ID
+}
+
+func lastID(messages []domain.Message) *uint64 {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return &messages[len(messages)-1].ID
+}
+
+func NewListMessages(chatRepository domain.ChatRepository) *listMessages {
+	return &listMessages{
+		chatRepository: chatRepository,
+	}
+}
@@ -0,0 +1,31 @@
+package use_case
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Removes the control characters but line breaks and tabs,
+// and checks the length of what is left
+func sanitizeContent(content string, maxLength int) (string, error) {
+	if !utf8.ValidString(content) {
+		return "", domain.ErrInvalidEncoding
+	}
+
+	content = strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, content)
+
+	if utf8.RuneCountInString(content) > maxLength {
+		return "", domain.ErrMessageTooLong
+	}
+
+	return content, nil
+}
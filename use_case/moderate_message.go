@@ -0,0 +1,57 @@
+package use_case
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Replaces the content by the moderated one. When the moderator
+// fails the message goes through and is flagged, instead of being lost
+func moderateMessage(
+	ctx context.Context,
+	moderator domain.Moderator,
+	message *domain.Message,
+) (*domain.ModerationVerdict, error) {
+	verdict, err := moderator.Moderate(ctx, message)
+	if err != nil {
+		log.Printf("err: moderate message %d: %s", message.ID, err)
+
+		verdict = &domain.ModerationVerdict{
+			Action:  domain.ModerationActionFlag,
+			Content: message.Content,
+			Reason:  "moderation unavailable",
+		}
+	}
+
+	if verdict.Action == domain.ModerationActionReject {
+		return nil, domain.ErrMessageRejected
+	}
+
+	message.Content = verdict.Content
+
+	return verdict, nil
+}
+
+func flagMessage(
+	ctx context.Context,
+	reviewRepository domain.ReviewRepository,
+	message *domain.Message,
+	reason string,
+	flaggedAt time.Time,
+) {
+	err := reviewRepository.AddReview(ctx, &domain.Review{
+		MessageID: message.ID,
+		FromID:    message.FromID,
+		ToID:      message.ToID,
+		Content:   message.Content,
+		Reason:    reason,
+		Status:    domain.ReviewStatusPending,
+		FlaggedAt: flaggedAt,
+	})
+	if err != nil {
+		log.Printf("err: flag message %d: %s", message.ID, err)
+	}
+}
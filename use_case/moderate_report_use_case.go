@@ -0,0 +1,104 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type moderateReport struct {
+	chatStream       domain.ChatStream
+	chatRepository   domain.ChatRepository
+	blobStore        domain.BlobStore
+	reportRepository domain.ReportRepository
+	userRepository   domain.UserRepository
+	controlPlane     domain.ControlPlane
+}
+
+func (uc *moderateReport) Triage(ctx context.Context, request *domain.TriageReportRequest) (*domain.Report, error) {
+	report, err := uc.reportRepository.GetReport(ctx, request.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get report: %w", err)
+	}
+
+	if report.Status == domain.ReportStatusResolved {
+		return nil, domain.ErrReportResolved
+	}
+
+	report.Status = request.Status
+
+	if request.Note != "" {
+		report.Note = request.Note
+	}
+
+	if err = uc.reportRepository.UpdateReport(ctx, report); err != nil {
+		return nil, fmt.Errorf("update report: %w", err)
+	}
+
+	return report, nil
+}
+
+// Resolves the report, more actions can still be taken after it
+func (uc *moderateReport) Act(ctx context.Context, request *domain.ReportActionRequest) (*domain.Report, error) {
+	report, err := uc.reportRepository.GetReport(ctx, request.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get report: %w", err)
+	}
+
+	switch request.Action {
+	case domain.ReportActionDeleteMessage:
+		message, err := uc.chatRepository.GetMessage(ctx, report.ReporterID, report.ReportedID, report.MessageID)
+		if err != nil {
+			return nil, fmt.Errorf("get message: %w", err)
+		}
+
+		if err = deleteForEveryone(ctx, uc.chatStream, uc.chatRepository, uc.blobStore, message); err != nil {
+			return nil, err
+		}
+	case domain.ReportActionSuspendUser:
+		_, err = NewSuspendUser(uc.userRepository, uc.controlPlane).Execute(ctx, &domain.SuspendUserRequest{
+			UserID:   report.ReportedID,
+			Reason:   fmt.Sprintf("report %d: %s", report.ID, report.Reason),
+			Duration: request.Duration,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+
+	report.Status = domain.ReportStatusResolved
+	report.Actions = append(report.Actions, request.Action)
+	report.ResolvedAt = &now
+
+	if request.Note != "" {
+		report.Note = request.Note
+	}
+
+	if err = uc.reportRepository.UpdateReport(ctx, report); err != nil {
+		return nil, fmt.Errorf("update report: %w", err)
+	}
+
+	return report, nil
+}
+
+func NewModerateReport(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	reportRepository domain.ReportRepository,
+	userRepository domain.UserRepository,
+	controlPlane domain.ControlPlane,
+) *moderateReport {
+	return &moderateReport{
+		chatStream:       chatStream,
+		chatRepository:   chatRepository,
+		blobStore:        blobStore,
+		reportRepository: reportRepository,
+		userRepository:   userRepository,
+		controlPlane:     controlPlane,
+	}
+}
@@ -0,0 +1,105 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type reaction struct {
+	chatStream      domain.ChatStream
+	chatRepository  domain.ChatRepository
+	blockRepository domain.BlockRepository
+}
+
+func (uc *reaction) Add(ctx context.Context, request *domain.ReactionRequest) error {
+	message, err := uc.getMessage(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	reaction := uc.newReaction(request)
+
+	if err = uc.chatRepository.AddReaction(ctx, request.To, reaction); err != nil {
+		return fmt.Errorf("add reaction: %w", err)
+	}
+
+	uc.dispatch(message, domain.EventTypeReactionAdded, reaction)
+
+	return nil
+}
+
+func (uc *reaction) Remove(ctx context.Context, request *domain.ReactionRequest) error {
+	message, err := uc.getMessage(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	reaction := uc.newReaction(request)
+
+	if err = uc.chatRepository.RemoveReaction(ctx, request.To, reaction); err != nil {
+		return fmt.Errorf("remove reaction: %w", err)
+	}
+
+	uc.dispatch(message, domain.EventTypeReactionRemoved, reaction)
+
+	return nil
+}
+
+// Also ensures the user is a participant of the message conversation
+func (uc *reaction) getMessage(ctx context.Context, request *domain.ReactionRequest) (*domain.Message, error) {
+	if request.Emoji == "" ||
+		!utf8.ValidString(request.Emoji) ||
+		utf8.RuneCountInString(request.Emoji) > domain.MaxReactionLength {
+		return nil, domain.ErrInvalidReaction
+	}
+
+	message, err := uc.chatRepository.GetMessage(ctx, request.From, request.To, request.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("get message: %w", err)
+	}
+
+	if message.DeletedAt != nil {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	// the reactions are dispatched to both of them
+	if err = checkBlock(ctx, uc.blockRepository, request.From, request.To); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (uc *reaction) newReaction(request *domain.ReactionRequest) *domain.Reaction {
+	return &domain.Reaction{
+		MessageID: request.MessageID,
+		UserID:    request.From,
+		Emoji:     request.Emoji,
+	}
+}
+
+func (uc *reaction) dispatch(message *domain.Message, eventType string, reaction *domain.Reaction) {
+	event := domain.NewEvent(eventType, reaction)
+
+	for _, userID := range []uint64{message.ToID, message.FromID} {
+		if err := uc.chatStream.DispatchEvent(userID, event); err != nil {
+			log.Printf("err: dispatch %s to %d: %s", eventType, userID, err)
+		}
+	}
+}
+
+func NewReaction(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blockRepository domain.BlockRepository,
+) *reaction {
+	return &reaction{
+		chatStream:      chatStream,
+		chatRepository:  chatRepository,
+		blockRepository: blockRepository,
+	}
+}
@@ -0,0 +1,80 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type reportMessage struct {
+	chatRepository   domain.ChatRepository
+	reportRepository domain.ReportRepository
+	uidGenerator     domain.UIDGenerator
+}
+
+// Only messages received by the reporter can be reported
+func (uc *reportMessage) Execute(ctx context.Context, request *domain.ReportMessageRequest) (*domain.Report, error) {
+	details, err := sanitizeContent(request.Details, domain.ReportDetailsMaxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := uc.chatRepository.GetMessage(ctx, request.From, request.To, request.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("get message: %w", err)
+	}
+
+	if message.DeletedAt != nil {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	if message.FromID == request.From {
+		return nil, domain.ErrCannotReportOwn
+	}
+
+	page, err := NewListMessages(uc.chatRepository).Execute(ctx, request.From, &domain.ListMessageRequest{
+		To:       request.To,
+		AroundID: &message.ID,
+		Limit:    domain.ReportContextSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list context: %w", err)
+	}
+
+	id, err := uc.uidGenerator.NextID()
+	if err != nil {
+		return nil, fmt.Errorf("generate new unique id: %w", err)
+	}
+
+	report := domain.Report{
+		ID:         id,
+		MessageID:  message.ID,
+		ReporterID: request.From,
+		ReportedID: message.FromID,
+		Reason:     request.Reason,
+		Details:    details,
+		Context:    page.Messages,
+		Status:     domain.ReportStatusOpen,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err = uc.reportRepository.AddReport(ctx, &report); err != nil {
+		return nil, fmt.Errorf("add report: %w", err)
+	}
+
+	return &report, nil
+}
+
+func NewReportMessage(
+	chatRepository domain.ChatRepository,
+	reportRepository domain.ReportRepository,
+	uidGenerator domain.UIDGenerator,
+) *reportMessage {
+	return &reportMessage{
+		chatRepository:   chatRepository,
+		reportRepository: reportRepository,
+		uidGenerator:     uidGenerator,
+	}
+}
@@ -0,0 +1,65 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type review struct {
+	chatStream       domain.ChatStream
+	chatRepository   domain.ChatRepository
+	blobStore        domain.BlobStore
+	reviewRepository domain.ReviewRepository
+}
+
+// Approving keeps the message as delivered, removing
+// deletes it for everyone
+func (uc *review) Decide(ctx context.Context, request *domain.ReviewDecisionRequest) (*domain.Review, error) {
+	review, err := uc.reviewRepository.GetReview(ctx, request.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("get review: %w", err)
+	}
+
+	if review.Status != domain.ReviewStatusPending {
+		return nil, domain.ErrReviewDecided
+	}
+
+	if request.Decision == domain.ReviewStatusRemoved {
+		message, err := uc.chatRepository.GetMessage(ctx, review.FromID, review.ToID, review.MessageID)
+		if err != nil {
+			return nil, fmt.Errorf("get message: %w", err)
+		}
+
+		if err = deleteForEveryone(ctx, uc.chatStream, uc.chatRepository, uc.blobStore, message); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+
+	review.Status = request.Decision
+	review.ReviewedAt = &now
+
+	if err = uc.reviewRepository.UpdateReview(ctx, review); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+
+	return review, nil
+}
+
+func NewReview(
+	chatStream domain.ChatStream,
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	reviewRepository domain.ReviewRepository,
+) *review {
+	return &review{
+		chatStream:       chatStream,
+		chatRepository:   chatRepository,
+		blobStore:        blobStore,
+		reviewRepository: reviewRepository,
+	}
+}
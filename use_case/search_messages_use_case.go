@@ -0,0 +1,135 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type searchMessages struct {
+	index          domain.SearchIndex
+	chatRepository domain.ChatRepository
+}
+
+// The index doesn't know the messages each user deleted only for
+// themselves, so the hits are filtered and the index is read until
+// the page is full. Offsets are positions in the index
+func (uc *searchMessages) Execute(
+	ctx context.Context,
+	userID uint64,
+	request *domain.SearchRequest,
+) (*domain.SearchResponse, error) {
+	limit := request.Limit
+	if limit == 0 || limit > domain.MaxSearchLimit {
+		limit = domain.DefaultSearchLimit
+	}
+
+	var (
+		hits []domain.SearchHit
+		// index offset of each hit
+		offsets []int
+		offset  = request.Offset
+	)
+
+	// one more hit tells if there is a next page
+	for len(hits) <= limit {
+		batch, err := uc.index.Search(ctx, userID, request.Query, offset, limit+1)
+		if err != nil {
+			return nil, fmt.Errorf("search index: %w", err)
+		}
+
+		visible, err := uc.visible(ctx, userID, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, hit := range batch {
+			if _, ok := visible[hit.MessageID]; ok {
+				hits = append(hits, hit)
+				offsets = append(offsets, offset+i)
+			}
+		}
+
+		offset += len(batch)
+
+		if len(batch) <= limit {
+			break
+		}
+	}
+
+	response := domain.SearchResponse{
+		Hits: hits,
+	}
+
+	if len(hits) > limit {
+		response.Hits = hits[:limit]
+		response.NextOffset = &offsets[limit]
+	}
+
+	if response.Hits == nil {
+		response.Hits = []domain.SearchHit{}
+	}
+
+	return &response, nil
+}
+
+// Ids of the hits not hidden by userID
+func (uc *searchMessages) visible(
+	ctx context.Context,
+	userID uint64,
+	hits []domain.SearchHit,
+) (map[uint64]struct{}, error) {
+	type idRange struct {
+		first, last uint64
+	}
+
+	ranges := make(map[uint64]*idRange)
+
+	for _, hit := range hits {
+		peerID := hit.ToID
+		if peerID == userID {
+			peerID = hit.FromID
+		}
+
+		if r, ok := ranges[peerID]; ok {
+			r.first = min(r.first, hit.MessageID)
+			r.last = max(r.last, hit.MessageID)
+		} else {
+			ranges[peerID] = &idRange{hit.MessageID, hit.MessageID}
+		}
+	}
+
+	hidden := make(map[uint64]struct{})
+
+	for peerID, r := range ranges {
+		ids, err := uc.chatRepository.ListHiddenMessageIDs(ctx, userID, peerID, r.first, r.last)
+		if err != nil {
+			return nil, fmt.Errorf("list hidden messages: %w", err)
+		}
+
+		for id := range ids {
+			hidden[id] = struct{}{}
+		}
+	}
+
+	visible := make(map[uint64]struct{})
+
+	for _, hit := range hits {
+		if _, ok := hidden[hit.MessageID]; !ok {
+			visible[hit.MessageID] = struct{}{}
+		}
+	}
+
+	return visible, nil
+}
+
+func NewSearchMessages(
+	index domain.SearchIndex,
+	chatRepository domain.ChatRepository,
+) *searchMessages {
+	return &searchMessages{
+		index:          index,
+		chatRepository: chatRepository,
+	}
+}
@@ -0,0 +1,86 @@
+package use_case_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+	"github.com/lam0glia/chat-system/repository"
+	"github.com/lam0glia/chat-system/search"
+	"github.com/lam0glia/chat-system/use_case"
+)
+
+func TestSearchMessagesSkipsHiddenMessages(t *testing.T) {
+	ctx := context.Background()
+
+	index, err := search.NewEmbedded(t.TempDir())
+	if err != nil {
+		t.Fatalf("new index: %s", err)
+	}
+
+	t.Cleanup(func() { index.Close() })
+
+	chatRepository := repository.NewMemoryChat()
+
+	const userID, peerID = 1, 2
+
+	for id := uint64(10); id <= 14; id++ {
+		err = index.Index(ctx, &domain.SearchDocument{
+			MessageID: id,
+			FromID:    peerID,
+			ToID:      userID,
+			Content:   "hello there",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("index: %s", err)
+		}
+	}
+
+	// deleted only for the user
+	for _, id := range []uint64{13, 12} {
+		if err = chatRepository.HideMessage(ctx, userID, peerID, id); err != nil {
+			t.Fatalf("hide message: %s", err)
+		}
+	}
+
+	uc := use_case.NewSearchMessages(index, chatRepository)
+
+	var (
+		got     []uint64
+		request = domain.SearchRequest{Query: "hello", Limit: 1}
+	)
+
+	for {
+		response, err := uc.Execute(ctx, userID, &request)
+		if err != nil {
+			t.Fatalf("search: %s", err)
+		}
+
+		for _, hit := range response.Hits {
+			got = append(got, hit.MessageID)
+		}
+
+		if response.NextOffset == nil {
+			break
+		}
+
+		request.Offset = *response.NextOffset
+	}
+
+	if want := []uint64{14, 11, 10}; !slices.Equal(got, want) {
+		t.Fatalf("got hits %v, want %v", got, want)
+	}
+
+	// the peer didn't hide them
+	response, err := uc.Execute(ctx, peerID, &domain.SearchRequest{Query: "hello"})
+	if err != nil {
+		t.Fatalf("search: %s", err)
+	}
+
+	if len(response.Hits) != 5 {
+		t.Fatalf("got %d hits for the peer, want 5", len(response.Hits))
+	}
+}
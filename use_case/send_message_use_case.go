@@ -3,17 +3,80 @@ package use_case
 import (
 	"context"
 	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/lam0glia/chat-system/domain"
 )
 
 type sendMessage struct {
-	chatStreamDispatcher domain.ChatStream
-	chatRepositoryWriter domain.ChatRepository
-	uidGenerator         domain.UIDGenerator
+	chatStreamDispatcher   domain.ChatStream
+	chatRepositoryWriter   domain.ChatRepository
+	uidGenerator           domain.UIDGenerator
+	blockRepository        domain.BlockRepository
+	conversationRepository domain.ConversationRepository
+	rateLimitService       domain.RateLimitService
+	userRepository         domain.UserRepository
+	moderator              domain.Moderator
+	reviewRepository       domain.ReviewRepository
+	// In runes
+	maxLength int
 }
 
 func (uc *sendMessage) Execute(ctx context.Context, messageRequest *domain.SendMessageRequest) error {
+	if err := uc.validate(messageRequest); err != nil {
+		return err
+	}
+
+	// suspended users are disconnected, but a send may
+	// already be on its way
+	err := NewCheckSuspension(uc.userRepository).Execute(ctx, messageRequest.From)
+	if err != nil {
+		return err
+	}
+
+	if messageRequest.Client != nil {
+		err = uc.rateLimitService.Allow(ctx, messageRequest.Client, domain.RateLimitActionMessage)
+		if err != nil {
+			return err
+		}
+	}
+
+	exists, err := uc.userRepository.Exists(ctx, messageRequest.To)
+	if err != nil {
+		return fmt.Errorf("check recipient: %w", err)
+	}
+
+	if !exists {
+		return domain.ErrRecipientNotFound
+	}
+
+	if err = checkBlock(ctx, uc.blockRepository, messageRequest.From, messageRequest.To); err != nil {
+		return err
+	}
+
+	if messageRequest.Client != nil {
+		if err = uc.limitConversationStart(ctx, messageRequest); err != nil {
+			return err
+		}
+	}
+
+	var root *domain.Message
+
+	if messageRequest.ReplyTo != nil {
+		root, err = uc.threadRoot(ctx, messageRequest)
+		if err != nil {
+			return fmt.Errorf("get thread root: %w", err)
+		}
+	}
+
+	attachments, err := uc.attachments(ctx, messageRequest)
+	if err != nil {
+		return fmt.Errorf("get attachments: %w", err)
+	}
+
 	id, err := uc.uidGenerator.NextID()
 	if err != nil {
 		return fmt.Errorf("generate new unique id: %w", err)
@@ -26,25 +89,214 @@ func (uc *sendMessage) Execute(ctx context.Context, messageRequest *domain.SendM
 		messageRequest.Content,
 	)
 
+	if root != nil {
+		message.ReplyToID = &root.ID
+	}
+
+	for _, attachment := range attachments {
+		message.AttachmentIDs = append(message.AttachmentIDs, attachment.ID)
+	}
+
+	message.Attachments = attachments
+
+	verdict, err := moderateMessage(ctx, uc.moderator, message)
+	if err != nil {
+		return err
+	}
+
 	if err = uc.chatRepositoryWriter.InsertMessage(ctx, message); err != nil {
 		return fmt.Errorf("insert message: %w", err)
 	}
 
-	if err = uc.chatStreamDispatcher.DispatchMessage(message); err != nil {
+	if err = uc.conversationRepository.UpdateLastMessage(ctx, message); err != nil {
+		log.Printf("err: update conversations: %s", err)
+	}
+
+	if err = uc.chatStreamDispatcher.DispatchMessage(uc.delivery(ctx, message)); err != nil {
 		return fmt.Errorf("publish event: %w", err)
 	}
 
+	if err = uc.chatStreamDispatcher.PublishMessageEvent(&domain.MessageEvent{
+		Type:    domain.EventTypeMessageCreated,
+		Message: message,
+	}); err != nil {
+		log.Printf("err: publish message event: %s", err)
+	}
+
+	if root != nil {
+		uc.dispatchThreadUpdate(ctx, root, message)
+	}
+
+	if verdict.Action == domain.ModerationActionFlag {
+		flagMessage(ctx, uc.reviewRepository, message, verdict.Reason, message.CreatedAt)
+	}
+
+	return nil
+}
+
+// Strips the control characters of the content
+func (uc *sendMessage) validate(messageRequest *domain.SendMessageRequest) error {
+	if messageRequest.To == 0 || messageRequest.To == messageRequest.From {
+		return domain.ErrInvalidRecipient
+	}
+
+	content, err := sanitizeContent(messageRequest.Content, uc.maxLength)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(content) == "" && len(messageRequest.AttachmentIDs) == 0 {
+		return domain.ErrEmptyMessage
+	}
+
+	messageRequest.Content = content
+
 	return nil
 }
 
+// Starting conversations has its own limit, so a client can't
+// spread the messages it is allowed to send over many users
+func (uc *sendMessage) limitConversationStart(ctx context.Context, messageRequest *domain.SendMessageRequest) error {
+	conversation, err := uc.conversationRepository.GetConversation(ctx, messageRequest.From, messageRequest.To)
+	if err != nil {
+		return fmt.Errorf("get conversation: %w", err)
+	}
+
+	if conversation.LastMessageAt != nil {
+		return nil
+	}
+
+	return uc.rateLimitService.Allow(ctx, messageRequest.Client, domain.RateLimitActionConversation)
+}
+
+// The message flagged as muted when the recipient muted the
+// conversation, it is still delivered
+func (uc *sendMessage) delivery(ctx context.Context, message *domain.Message) *domain.Message {
+	conversation, err := uc.conversationRepository.GetConversation(ctx, message.ToID, message.FromID)
+	if err != nil {
+		log.Printf("err: get conversation of the recipient: %s", err)
+		return message
+	}
+
+	if !conversation.IsMuted(time.Now()) {
+		return message
+	}
+
+	muted := *message
+	muted.Muted = true
+
+	return &muted
+}
+
+// Only attachments uploaded by the sender to this conversation
+// and not sent yet can be attached
+func (uc *sendMessage) attachments(ctx context.Context, messageRequest *domain.SendMessageRequest) ([]domain.Attachment, error) {
+	if len(messageRequest.AttachmentIDs) > domain.MaxAttachmentsPerMessage {
+		return nil, domain.ErrTooManyAttachments
+	}
+
+	var attachments []domain.Attachment
+
+	for i, id := range messageRequest.AttachmentIDs {
+		if slices.Contains(messageRequest.AttachmentIDs[:i], id) {
+			continue
+		}
+
+		attachment, err := uc.chatRepositoryWriter.GetAttachment(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if attachment.UploaderID != messageRequest.From ||
+			attachment.PeerID != messageRequest.To ||
+			attachment.MessageID != nil {
+			return nil, domain.ErrAttachmentNotFound
+		}
+
+		attachments = append(attachments, *attachment)
+	}
+
+	return attachments, nil
+}
+
+// Replies to a reply are added to the thread of its root
+func (uc *sendMessage) threadRoot(ctx context.Context, messageRequest *domain.SendMessageRequest) (*domain.Message, error) {
+	message, err := uc.chatRepositoryWriter.GetMessage(
+		ctx,
+		messageRequest.From,
+		messageRequest.To,
+		*messageRequest.ReplyTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	if message.ReplyToID != nil {
+		message, err = uc.chatRepositoryWriter.GetMessage(
+			ctx,
+			messageRequest.From,
+			messageRequest.To,
+			*message.ReplyToID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if message.DeletedAt != nil {
+			return nil, domain.ErrMessageNotFound
+		}
+	}
+
+	return message, nil
+}
+
+func (uc *sendMessage) dispatchThreadUpdate(ctx context.Context, root, reply *domain.Message) {
+	count, err := uc.chatRepositoryWriter.CountReplies(ctx, reply.FromID, reply.ToID, root.ID)
+	if err != nil {
+		log.Printf("err: count replies: %s", err)
+		return
+	}
+
+	event := domain.NewEvent(domain.EventTypeThreadUpdated, domain.ThreadUpdate{
+		RootID:      root.ID,
+		ReplyID:     reply.ID,
+		ReplyCount:  count,
+		LastReplyAt: reply.CreatedAt,
+	})
+
+	for _, userID := range []uint64{reply.ToID, reply.FromID} {
+		if err = uc.chatStreamDispatcher.DispatchEvent(userID, event); err != nil {
+			log.Printf("err: dispatch thread update to %d: %s", userID, err)
+		}
+	}
+}
+
 func NewSendMessage(
 	chatStreamDispatcher domain.ChatStream,
 	chatRepositoryWriter domain.ChatRepository,
 	uidGenerator domain.UIDGenerator,
+	blockRepository domain.BlockRepository,
+	conversationRepository domain.ConversationRepository,
+	rateLimitService domain.RateLimitService,
+	userRepository domain.UserRepository,
+	moderator domain.Moderator,
+	reviewRepository domain.ReviewRepository,
+	maxLength int,
 ) *sendMessage {
 	return &sendMessage{
-		chatStreamDispatcher: chatStreamDispatcher,
-		chatRepositoryWriter: chatRepositoryWriter,
-		uidGenerator:         uidGenerator,
+		chatStreamDispatcher:   chatStreamDispatcher,
+		chatRepositoryWriter:   chatRepositoryWriter,
+		uidGenerator:           uidGenerator,
+		blockRepository:        blockRepository,
+		conversationRepository: conversationRepository,
+		rateLimitService:       rateLimitService,
+		userRepository:         userRepository,
+		moderator:              moderator,
+		reviewRepository:       reviewRepository,
+		maxLength:              maxLength,
 	}
 }
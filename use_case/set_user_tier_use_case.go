@@ -0,0 +1,36 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type setUserTier struct {
+	userRepository domain.UserRepository
+	policy         *domain.RateLimitPolicy
+}
+
+// Only the tiers of RATE_LIMIT_TIERS can be set
+func (uc *setUserTier) Execute(ctx context.Context, request *domain.SetUserTierRequest) error {
+	if _, ok := uc.policy.Tiers[request.Tier]; request.Tier != "" && !ok {
+		return domain.ErrUnknownTier
+	}
+
+	if err := uc.userRepository.SetTier(ctx, request.UserID, request.Tier); err != nil {
+		return fmt.Errorf("set tier: %w", err)
+	}
+
+	return nil
+}
+
+func NewSetUserTier(
+	userRepository domain.UserRepository,
+	policy *domain.RateLimitPolicy,
+) *setUserTier {
+	return &setUserTier{
+		userRepository: userRepository,
+		policy:         policy,
+	}
+}
@@ -0,0 +1,62 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type suspendUser struct {
+	userRepository domain.UserRepository
+	controlPlane   domain.ControlPlane
+}
+
+// Replaces the current suspension of the user and closes
+// their connections on every node
+func (uc *suspendUser) Execute(ctx context.Context, request *domain.SuspendUserRequest) (*domain.Suspension, error) {
+	now := time.Now().UTC()
+
+	until, err := domain.SuspensionEnd(request.Duration, now)
+	if err != nil {
+		return nil, err
+	}
+
+	suspension := domain.Suspension{
+		UserID:      request.UserID,
+		Reason:      request.Reason,
+		SuspendedAt: now,
+		Until:       until,
+	}
+
+	if err = uc.userRepository.Suspend(ctx, &suspension); err != nil {
+		return nil, fmt.Errorf("suspend user: %w", err)
+	}
+
+	// reconnecting is refused from now on. The suspension is
+	// already saved, so the open connections are only logged
+	// when they can't be closed
+	err = NewDisconnectUser(uc.controlPlane).Execute(
+		ctx,
+		request.UserID,
+		domain.CloseCodeSuspended,
+		"suspended",
+	)
+	if err != nil {
+		log.Printf("err: disconnect suspended user %d: %s", request.UserID, err)
+	}
+
+	return &suspension, nil
+}
+
+func NewSuspendUser(
+	userRepository domain.UserRepository,
+	controlPlane domain.ControlPlane,
+) *suspendUser {
+	return &suspendUser{
+		userRepository: userRepository,
+		controlPlane:   controlPlane,
+	}
+}
@@ -0,0 +1,121 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+// Time after which an indicator is stopped if the client
+// doesn't send "typing.stop" nor "typing.start" again
+const typingExpiration = 6 * time.Second
+
+type typing struct {
+	chatStream      domain.ChatStream
+	blockRepository domain.BlockRepository
+	fromID          uint64
+
+	mu sync.Mutex
+	// active indicators by recipient id
+	timers map[uint64]*time.Timer
+}
+
+func (uc *typing) Start(ctx context.Context, request *domain.TypingRequest) error {
+	if err := checkBlock(ctx, uc.blockRepository, uc.fromID, request.To); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if timer, ok := uc.timers[request.To]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(typingExpiration, func() {
+		uc.expire(request.To, timer)
+	})
+
+	uc.timers[request.To] = timer
+
+	expiresAt := time.Now().Add(typingExpiration)
+
+	return uc.dispatch(domain.EventTypeTypingStart, request.To, &expiresAt)
+}
+
+func (uc *typing) Stop(ctx context.Context, request *domain.TypingRequest) error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	timer, ok := uc.timers[request.To]
+	if !ok {
+		return nil
+	}
+
+	timer.Stop()
+	delete(uc.timers, request.To)
+
+	return uc.dispatch(domain.EventTypeTypingStop, request.To, nil)
+}
+
+func (uc *typing) Close() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	for toID, timer := range uc.timers {
+		timer.Stop()
+		delete(uc.timers, toID)
+
+		if err := uc.dispatch(domain.EventTypeTypingStop, toID, nil); err != nil {
+			log.Printf("err: stop typing: %s", err)
+		}
+	}
+}
+
+func (uc *typing) expire(toID uint64, timer *time.Timer) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	// the indicator was restarted or stopped in the meantime
+	if uc.timers[toID] != timer {
+		return
+	}
+
+	delete(uc.timers, toID)
+
+	if err := uc.dispatch(domain.EventTypeTypingStop, toID, nil); err != nil {
+		log.Printf("err: expire typing: %s", err)
+	}
+}
+
+func (uc *typing) dispatch(eventType string, toID uint64, expiresAt *time.Time) error {
+	event := domain.NewEvent(eventType, domain.Typing{
+		FromID:    uc.fromID,
+		ExpiresAt: expiresAt,
+	})
+
+	if err := uc.chatStream.DispatchEphemeralEvent(toID, event, typingExpiration); err != nil {
+		return fmt.Errorf("dispatch event: %w", err)
+	}
+
+	return nil
+}
+
+func NewTyping(
+	chatStream domain.ChatStream,
+	blockRepository domain.BlockRepository,
+	fromID uint64,
+) *typing {
+	return &typing{
+		chatStream:      chatStream,
+		blockRepository: blockRepository,
+		fromID:          fromID,
+		timers:          make(map[uint64]*time.Timer),
+	}
+}
@@ -0,0 +1,98 @@
+package use_case
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+type updateConversation struct {
+	controlPlane           domain.ControlPlane
+	conversationRepository domain.ConversationRepository
+	userRepository         domain.UserRepository
+}
+
+func (uc *updateConversation) Execute(
+	ctx context.Context,
+	request *domain.UpdateConversationRequest,
+) (*domain.Conversation, error) {
+	now := time.Now()
+
+	if request.MutedUntil != nil &&
+		(request.Muted == nil || !*request.Muted || !request.MutedUntil.After(now)) {
+		return nil, domain.ErrInvalidMuteExpiry
+	}
+
+	// settings saved for any id would list a
+	// conversation with a user that doesn't exist
+	exists, err := uc.userRepository.Exists(ctx, request.PeerID)
+	if err != nil {
+		return nil, fmt.Errorf("check peer: %w", err)
+	}
+
+	if !exists {
+		return nil, domain.ErrRecipientNotFound
+	}
+
+	conversation, err := uc.conversationRepository.GetConversation(ctx, request.From, request.PeerID)
+	if err != nil {
+		return nil, fmt.Errorf("get conversation: %w", err)
+	}
+
+	settings := &conversation.ConversationSettings
+
+	if request.Muted != nil {
+		settings.Muted = *request.Muted
+		settings.MutedUntil = request.MutedUntil
+	}
+
+	if request.Archived != nil {
+		settings.Archived = *request.Archived
+	}
+
+	if request.Pinned != nil {
+		if !*request.Pinned {
+			settings.PinnedAt = nil
+		} else if settings.PinnedAt == nil {
+			settings.PinnedAt = &now
+		}
+	}
+
+	if err = uc.conversationRepository.UpdateSettings(ctx, request.From, request.PeerID, settings); err != nil {
+		return nil, fmt.Errorf("update settings: %w", err)
+	}
+
+	// synced to every device of the user, the user queue
+	// would hand it to only one of them
+	event, err := json.Marshal(domain.NewEvent(domain.EventTypeConversationUpdated, conversation))
+	if err != nil {
+		return nil, fmt.Errorf("json encode event: %w", err)
+	}
+
+	err = uc.controlPlane.Publish(ctx, &domain.ControlCommand{
+		Type:   domain.ControlCommandEvent,
+		UserID: request.From,
+		Event:  event,
+	})
+	if err != nil {
+		log.Printf("err: publish conversation update: %s", err)
+	}
+
+	return conversation, nil
+}
+
+func NewUpdateConversation(
+	controlPlane domain.ControlPlane,
+	conversationRepository domain.ConversationRepository,
+	userRepository domain.UserRepository,
+) *updateConversation {
+	return &updateConversation{
+		controlPlane:           controlPlane,
+		conversationRepository: conversationRepository,
+		userRepository:         userRepository,
+	}
+}
@@ -0,0 +1,149 @@
+package use_case
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	// image decoders used to read the dimensions
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/lam0glia/chat-system/domain"
+)
+
+const attachmentBlobKeyFormat = "attachments/%d/original"
+
+type uploadAttachment struct {
+	chatRepository domain.ChatRepository
+	blobStore      domain.BlobStore
+	uidGenerator   domain.UIDGenerator
+	thumbnailQueue domain.ThumbnailQueue
+	maxSize        int64
+	allowedTypes   []string
+	maxPixels      int
+}
+
+func (uc *uploadAttachment) Execute(ctx context.Context, request *domain.UploadAttachmentRequest) (*domain.Attachment, error) {
+	if request.Size > uc.maxSize {
+		return nil, domain.ErrAttachmentTooLarge
+	}
+
+	// the declared size can't be trusted
+	content, err := io.ReadAll(io.LimitReader(request.Content, uc.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read content: %w", err)
+	}
+
+	if int64(len(content)) > uc.maxSize {
+		return nil, domain.ErrAttachmentTooLarge
+	}
+
+	// the type is detected from the content, never from the client
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil || !slices.Contains(uc.allowedTypes, mimeType) {
+		return nil, domain.ErrAttachmentTypeNotAllowed
+	}
+
+	id, err := uc.uidGenerator.NextID()
+	if err != nil {
+		return nil, fmt.Errorf("generate new unique id: %w", err)
+	}
+
+	checksum := sha256.Sum256(content)
+
+	attachment := &domain.Attachment{
+		ID:         id,
+		UploaderID: request.From,
+		PeerID:     request.To,
+		Name:       filepath.Base(filepath.Clean("/" + request.Name)),
+		MIMEType:   mimeType,
+		Size:       int64(len(content)),
+		Checksum:   hex.EncodeToString(checksum[:]),
+		BlobKey:    fmt.Sprintf(attachmentBlobKeyFormat, id),
+		CreatedAt:  time.Now(),
+	}
+
+	attachment.SetURLs()
+
+	if strings.HasPrefix(mimeType, "image/") {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+			// a small file can decode into gigabytes
+			if exceedsPixels(config, uc.maxPixels) {
+				return nil, domain.ErrImageTooLarge
+			}
+
+			attachment.Width = config.Width
+			attachment.Height = config.Height
+		}
+	}
+
+	err = uc.blobStore.Put(
+		ctx,
+		attachment.BlobKey,
+		bytes.NewReader(content),
+		attachment.Size,
+		attachment.MIMEType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("put blob: %w", err)
+	}
+
+	if err = uc.chatRepository.InsertAttachment(ctx, attachment); err != nil {
+		if err := uc.blobStore.Delete(ctx, attachment.BlobKey); err != nil {
+			log.Printf("err: delete orphan blob: %s", err)
+		}
+
+		return nil, fmt.Errorf("insert attachment: %w", err)
+	}
+
+	// only images that could be decoded have dimensions
+	if attachment.Width > 0 {
+		job := domain.ThumbnailJob{AttachmentID: attachment.ID}
+
+		if err = uc.thumbnailQueue.Enqueue(&job); err != nil {
+			log.Printf("err: enqueue thumbnail job: %s", err)
+		}
+	}
+
+	return attachment, nil
+}
+
+// Compared in 64 bits, so huge dimensions can't overflow
+func exceedsPixels(config image.Config, maxPixels int) bool {
+	return int64(config.Width)*int64(config.Height) > int64(maxPixels)
+}
+
+func NewUploadAttachment(
+	chatRepository domain.ChatRepository,
+	blobStore domain.BlobStore,
+	uidGenerator domain.UIDGenerator,
+	thumbnailQueue domain.ThumbnailQueue,
+	maxSize int64,
+	allowedTypes []string,
+	maxPixels int,
+) *uploadAttachment {
+	return &uploadAttachment{
+		chatRepository: chatRepository,
+		blobStore:      blobStore,
+		uidGenerator:   uidGenerator,
+		thumbnailQueue: thumbnailQueue,
+		maxSize:        maxSize,
+		allowedTypes:   allowedTypes,
+		maxPixels:      maxPixels,
+	}
+}
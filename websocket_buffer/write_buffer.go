@@ -28,7 +28,11 @@ func (b *WriteBuffer) Write(body any) {
 	b.buffer <- body
 }
 
-func (b *WriteBuffer) Setup(conn domain.WebsocketConnection) {
-	b.buffer = make(chan any)
-	b.conn = conn
+// Each connection needs its own, what is written
+// is only delivered to conn
+func NewWriteBuffer(conn domain.WebsocketConnection) *WriteBuffer {
+	return &WriteBuffer{
+		conn:   conn,
+		buffer: make(chan any),
+	}
 }